@@ -3,12 +3,20 @@ package animus
 import (
 	language "cloud.google.com/go/language/apiv2"
 	"context"
+	"errors"
+	"fmt"
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/infra"
-	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/lib"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/model"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/service"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/usecase"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/auth"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/pubsub"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/secrets"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/telemetry"
 	"github.com/uptrace/bun"
 	"google.golang.org/api/youtube/v3"
 	"log"
@@ -19,15 +27,15 @@ import (
 	"strings"
 )
 
+// collectLockKey guards the chat collection against overlapping runs
+const collectLockKey = "animus:collect-chats"
+
 var stampPat *regexp.Regexp
 
 // Global variables
 // Initialize once per function instance
-var ytApiKey = os.Getenv("YOUTUBE_API_KEY")
 var ytSvc *youtube.Service
 
-// DSN is the connection string for Supabase
-var dsn = os.Getenv("SUPABASE_DSN")
 var supaClient *bun.DB
 
 var nlaClient *language.Client
@@ -41,8 +49,31 @@ func init() {
 	stampPat = regexp.MustCompile(`:[^:]+:`)
 
 	// Custom log
-	handler := lib.NewCustomLogger()
-	slog.SetDefault(handler)
+	logger := logging.NewCustomLogger("fetch-chat-function")
+	slog.SetDefault(logger)
+
+	// Exporter failures must not stop the function, so fall back to the no-op providers
+	if _, err := telemetry.Setup(context.Background(), telemetry.ConfigFromEnv()); err != nil {
+		slog.Error("Failed to set up telemetry", slog.Group("Telemetry", "error", err))
+	}
+
+	// The API key and the DSN are read from the provider of SECRETS_PROVIDER, which falls back to the environment
+	sp, err := secrets.FromEnv()
+	if err != nil {
+		slog.Error("Failed to create secrets provider", slog.Group("Secrets", "error", err))
+		log.Fatalf("Failed to create secrets provider: %v", err)
+	}
+	ytApiKey, err := secrets.Lookup(context.Background(), sp, "YOUTUBE_API_KEY")
+	if err != nil {
+		slog.Error("Failed to read YOUTUBE_API_KEY", slog.Group("Secrets", "error", err))
+		log.Fatalf("Failed to read YOUTUBE_API_KEY: %v", err)
+	}
+	// DSN is the connection string for Supabase
+	dsn, err := secrets.Lookup(context.Background(), sp, "SUPABASE_DSN")
+	if err != nil {
+		slog.Error("Failed to read SUPABASE_DSN", slog.Group("Secrets", "error", err))
+		log.Fatalf("Failed to read SUPABASE_DSN: %v", err)
+	}
 
 	// Create YouTube Data API service
 	if ytApiKey == "" {
@@ -73,24 +104,122 @@ func init() {
 		log.Fatalf("Failed to create NaturalLanguageAPI client: %v", err)
 	}
 
+	// Authentication is enabled by AUTH_AUDIENCE
+	var verifier *auth.Verifier
+	if ac := auth.ConfigFromEnv(); ac.Enabled() {
+		verifier, err = auth.NewVerifier(ac)
+		if err != nil {
+			slog.Error("Failed to create token verifier", slog.Group("Auth", "error", err))
+			log.Fatalf("Failed to create token verifier: %v", err)
+		}
+	}
+
 	// Register the function to handle HTTP requests
-	functions.HTTP("Animus", animus)
+	// Unauthenticated requests are rejected before the target check, which panics to avoid retries by CloudScheduler
+	functions.HTTP("Animus", telemetry.WrapHTTP("Animus", logging.Middleware(auth.Middleware(verifier, animus))))
+	// Register the function to handle Pub/Sub messages
+	functions.CloudEvent("AnimusPubSub", pubsub.Handler(animusPubSub))
 }
 
 func animus(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/healthz":
+		healthz(w, r)
+		return
+	case "/readyz":
+		readyz(w, r)
+		return
+	}
+
 	ctx := r.Context()
 
 	// Cache common environment variables
 	// Because the function is supposed to run on CloudFunctions, it is necessary to read the environment variables here.
 	// If the environment variable is not set, the function will panic.
 	// (To prevent retries by CloudScheduler, the function should panic without returning error responses.)
+	targetChannels, err := loadTargetChannels()
+	if err != nil {
+		logging.FromContext(ctx).Error(err.Error())
+		panic(err.Error())
+	}
+
+	skipped, err := collect(ctx, targetChannels)
+	switch {
+	case errors.Is(err, usecase.ErrInvalidStaticTarget):
+		// a broken env panics like a missing one, to prevent retries by CloudScheduler
+		panic(err.Error())
+	case errors.Is(err, errLockUnavailable):
+		http.Error(w, "Failed to acquire collection lock", http.StatusServiceUnavailable)
+	case err != nil:
+		http.Error(w, "Failed to collect chats", http.StatusInternalServerError)
+	case skipped:
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("skipped: already running"))
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// collectMessage is the data of a Pub/Sub message to Animus
+type collectMessage struct {
+	Operation string `json:"operation"`
+}
+
+const operationCollectChats = "collect_chats"
+
+// animusPubSub collects chats for a Pub/Sub message.
+// A message that can never succeed is permanent, and every other failure is retried by Pub/Sub.
+func animusPubSub(ctx context.Context, m pubsub.Message) error {
+	var msg collectMessage
+	if err := m.Unmarshal(&msg); err != nil {
+		return err
+	}
+	if msg.Operation != "" && msg.Operation != operationCollectChats {
+		return pubsub.Permanent(fmt.Errorf("unknown operation %q", msg.Operation))
+	}
+
+	targetChannels, err := loadTargetChannels()
+	if err != nil {
+		return pubsub.Permanent(err)
+	}
+
+	_, err = collect(ctx, targetChannels)
+	if errors.Is(err, usecase.ErrInvalidStaticTarget) {
+		return pubsub.Permanent(err)
+	}
+	return err
+}
+
+func loadTargetChannels() ([]string, error) {
 	targetChannelIdStr := os.Getenv("TARGET_CHANNEL_ID")
 	if targetChannelIdStr == "" {
-		slog.Error("TARGET_CHANNEL_ID is not set")
-		panic("TARGET_CHANNEL_ID is not set")
+		return nil, errors.New("TARGET_CHANNEL_ID is not set")
 	}
 	// Split targetChannelIdStr by comma
-	targetChannels := strings.Split(targetChannelIdStr, ",")
+	return strings.Split(targetChannelIdStr, ","), nil
+}
+
+var errLockUnavailable = errors.New("failed to acquire collection lock")
+
+// collect fetches the new chats of the target videos and saves them.
+// skipped reports that another run holds the lock, which is not an error.
+func collect(ctx context.Context, targetChannels []string) (skipped bool, err error) {
+	// Guard against overlapping runs, which would insert the same chats twice
+	l, err := lock.NewPostgresLocker(supaClient.DB).TryLock(ctx, collectLockKey)
+	if errors.Is(err, lock.ErrHeld) {
+		logging.FromContext(ctx).Info("Chat collection skipped: already running")
+		return true, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to acquire collection lock", slog.Group("lock", "error", err))
+		return false, fmt.Errorf("%w: %w", errLockUnavailable, err)
+	}
+	defer func() {
+		// the request context may already be done, but the lock must still be released
+		if err := l.Unlock(context.WithoutCancel(ctx)); err != nil {
+			logging.FromContext(ctx).Error("Failed to release collection lock", slog.Group("lock", "error", err))
+		}
+	}()
 
 	ytRepo := infra.NewYouTubeRepository(ytSvc)
 	supaRepo := infra.NewSupabaseRepository(supaClient)
@@ -102,62 +231,56 @@ func animus(w http.ResponseWriter, r *http.Request) {
 	videoUsc := usecase.NewVideoUsecase(supaRepo)
 
 	// Get variable video info from Supabase
-	targetStatus := []string{"upcoming", "live"}
+	targetStatus := []status.Status{status.Upcoming, status.Live}
 	varVideos, err := videoUsc.GetVideoInfosByStatusFromSupabase(ctx, targetStatus)
 	if err != nil {
-		slog.Error("Failed to get variable video info", slog.Group("error", err))
-		http.Error(w, "Failed to get video info by status", http.StatusInternalServerError)
-		return
+		logging.FromContext(ctx).Error("Failed to get variable video info", "error", err)
+		return false, fmt.Errorf("failed to get video info by status: %w", err)
 	}
 
 	// Check the existence of the live status video
 	// If there is live status video, skip the function
-	if _, ok := varVideos["live"]; ok {
-		slog.Info("There is a live status video")
-		w.WriteHeader(http.StatusOK)
-		return
+	if _, ok := varVideos[status.Live]; ok {
+		logging.FromContext(ctx).Info("There is a live status video")
+		return false, nil
 	}
 
 	// Find the upcoming target video
 	var upcVideos []model.VideoInfo
-	if _, ok := varVideos["upcoming"]; ok {
-		upcVideos = varVideos["upcoming"]
+	if _, ok := varVideos[status.Upcoming]; ok {
+		upcVideos = varVideos[status.Upcoming]
 	} else {
-		slog.Info("No upcoming target video")
+		logging.FromContext(ctx).Info("No upcoming target video")
 	}
 
 	// Fetch chats from the static target video
 	stcChats, err := chatUsc.FetchChatsFromStaticTargetVideo(ctx)
 	if err != nil {
-		slog.Error("Failed to fetch chats from the static target video", slog.Group("staticTarget", "error", err))
-		http.Error(w, "Failed to fetch chats from the static target video", http.StatusInternalServerError)
-		return
+		logging.FromContext(ctx).Error("Failed to fetch chats from the static target video", slog.Group("staticTarget", "error", err))
+		return false, fmt.Errorf("failed to fetch chats from the static target video: %w", err)
 	}
 
 	// Fetch chats from the upcoming target video
 	upcChats, err := chatUsc.FetchChatsFromUpcomingTargetVideo(ctx, upcVideos)
 	if err != nil {
-		slog.Error("Failed to fetch chats from the upcoming target video", slog.Group("upcomingTarget", "error", err))
-		http.Error(w, "Failed to fetch chats from the upcoming target video", http.StatusInternalServerError)
-		return
+		logging.FromContext(ctx).Error("Failed to fetch chats from the upcoming target video", slog.Group("upcomingTarget", "error", err))
+		return false, fmt.Errorf("failed to fetch chats from the upcoming target video: %w", err)
 	}
 
 	newChats := append(stcChats, upcChats...)
 	if len(newChats) == 0 {
-		slog.Info("No new chats")
-		w.WriteHeader(http.StatusOK)
-		return
+		logging.FromContext(ctx).Info("No new chats")
+		return false, nil
 	}
 
 	// Save the new target chats
 	if err := chatUsc.SaveNewChats(ctx, newChats); err != nil {
-		slog.Error("Failed to save new target chats",
+		logging.FromContext(ctx).Error("Failed to save new target chats",
 			slog.Group("saveNewChats", "error", err),
 		)
-		http.Error(w, "Failed to save new target chats", http.StatusInternalServerError)
-		return
+		return false, fmt.Errorf("failed to save new target chats: %w", err)
 	}
 
-	w.WriteHeader(http.StatusOK)
-	slog.Info("Animus function executed successfully")
+	logging.FromContext(ctx).Info("Animus function executed successfully")
+	return false, nil
 }
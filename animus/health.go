@@ -0,0 +1,95 @@
+package animus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/model"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+const checkTimeout = 3 * time.Second
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+type check struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// healthz reports that the instance is alive. It never touches any dependency.
+func healthz(w http.ResponseWriter, _ *http.Request) {
+	writeReadiness(w, http.StatusOK, readinessResponse{Status: "ok", Checks: map[string]string{}})
+}
+
+// readyz reports 503 if the database is unreachable or the configuration read per request is broken.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	checks := []check{
+		{name: "config", check: checkConfig},
+		{name: "youtube", check: func(_ context.Context) error {
+			if ytSvc == nil {
+				return errors.New("YouTube service is not built")
+			}
+			return nil
+		}},
+		{name: "naturalLanguage", check: func(_ context.Context) error {
+			if nlaClient == nil {
+				return errors.New("NaturalLanguageAPI client is not built")
+			}
+			return nil
+		}},
+		{name: "database", check: func(ctx context.Context) error {
+			return supaClient.PingContext(ctx)
+		}},
+	}
+
+	resp := readinessResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+	code := http.StatusOK
+	for _, c := range checks {
+		if err := c.check(ctx); err != nil {
+			logging.FromContext(r.Context()).Warn("Readiness check failed", "check", c.name, "error", err)
+			resp.Checks[c.name] = err.Error()
+			resp.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[c.name] = "ok"
+	}
+
+	writeReadiness(w, code, resp)
+}
+
+// checkConfig validates the environment variables that animus reads on every request
+func checkConfig(_ context.Context) error {
+	if os.Getenv("TARGET_CHANNEL_ID") == "" {
+		return errors.New("TARGET_CHANNEL_ID is not set")
+	}
+
+	// STATIC_TARGET is only a fallback for the free chats stored by Opus
+	if stcEnv := os.Getenv("STATIC_TARGET"); stcEnv != "" {
+		var stc model.VideoInfo
+		if err := json.Unmarshal([]byte(stcEnv), &stc); err != nil {
+			return errors.New("STATIC_TARGET is not a valid JSON")
+		}
+	}
+
+	return nil
+}
+
+func writeReadiness(w http.ResponseWriter, code int, resp readinessResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("Failed to write response", "error", err)
+	}
+}
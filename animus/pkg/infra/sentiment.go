@@ -4,7 +4,11 @@ import (
 	language "cloud.google.com/go/language/apiv2"
 	"cloud.google.com/go/language/apiv2/languagepb"
 	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/telemetry"
+	"go.opentelemetry.io/otel/codes"
 	"log/slog"
+	"time"
 )
 
 type SentimentRepository struct {
@@ -24,6 +28,10 @@ func NewSentimentRepository(client *language.Client) *SentimentRepository {
 }
 
 func (r *SentimentRepository) AnalyzeSentiment(ctx context.Context, text string) (float32, float32, error) {
+	ctx, span := tracer.Start(ctx, "NaturalLanguage AnalyzeSentiment")
+	defer span.End()
+
+	start := time.Now()
 	sentiment, err := r.client.AnalyzeSentiment(ctx, &languagepb.AnalyzeSentimentRequest{
 		Document: &languagepb.Document{
 			Source: &languagepb.Document_Content{
@@ -32,8 +40,12 @@ func (r *SentimentRepository) AnalyzeSentiment(ctx context.Context, text string)
 			Type: languagepb.Document_PLAIN_TEXT,
 		},
 	})
+	apiMetrics.Record(ctx, "AnalyzeSentiment", start, err)
 	if err != nil {
-		slog.Error(
+		span.RecordError(err)
+		span.SetStatus(codes.Error, telemetry.ErrorReason(err))
+		logging.FromContext(ctx).ErrorContext(
+			ctx,
 			"Failed to analyze sentiment",
 			slog.Group("analyzeSentiment", "text", text,
 				slog.Group("NaturalLanguageAPI", "error", err),
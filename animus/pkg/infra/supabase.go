@@ -4,9 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/model"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
+	"github.com/uptrace/bun/extra/bunotel"
 	"log/slog"
 	"time"
 )
@@ -18,6 +22,7 @@ type SupabaseRepository struct {
 func NewSupabaseClient(dsn string) (*bun.DB, error) {
 	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
 	db := bun.NewDB(sqldb, pgdialect.New())
+	db.AddQueryHook(bunotel.NewQueryHook(bunotel.WithDBName("supabase")))
 	return db, nil
 }
 
@@ -27,13 +32,19 @@ func NewSupabaseRepository(db *bun.DB) *SupabaseRepository {
 	}
 }
 
-func (r *SupabaseRepository) GetVideoInfoByStatus(ctx context.Context, status []string) ([]model.VideoRecord, error) {
+func (r *SupabaseRepository) GetVideoInfoByStatus(ctx context.Context, statuses []status.Status) ([]model.VideoRecord, error) {
 	records := make([]model.VideoRecord, 0)
-	err := r.db.NewSelect().Model(&records).Where("status IN (?)", bun.In(status)).Column("status", "source_id", "chat_id").Scan(ctx)
+	// uploads and Shorts have no chat to collect, and free chats are collected as static targets
+	err := r.db.NewSelect().
+		Model(&records).
+		Where("status IN (?)", bun.In(statuses)).
+		Where("kind NOT IN (?)", bun.In([]kind.Kind{kind.Upload, kind.Short, kind.FreeChat})).
+		Column("status", "source_id", "chat_id").
+		Scan(ctx)
 	if err != nil {
-		slog.Error(
+		logging.FromContext(ctx).Error(
 			"Failed to get video records by status",
-			"status", status,
+			"status", statuses,
 			slog.Group("Supabase", "error", err),
 		)
 		return nil, err
@@ -51,7 +62,7 @@ func (r *SupabaseRepository) GetPublishedAtOfLastRecordedChatBySource(ctx contex
 		Limit(1).
 		Scan(ctx)
 	if err != nil {
-		slog.Error(
+		logging.FromContext(ctx).Error(
 			"Failed to get the last recorded chat",
 			"sourceId", sourceId,
 			slog.Group("Supabase", "error", err),
@@ -69,7 +80,7 @@ func (r *SupabaseRepository) GetPublishedAtOfLastRecordedChatBySource(ctx contex
 func (r *SupabaseRepository) InsertChatRecord(ctx context.Context, record []model.ChatRecord) error {
 	_, err := r.db.NewInsert().Model(&record).Exec(ctx)
 	if err != nil {
-		slog.Error(
+		logging.FromContext(ctx).Error(
 			"Failed to insert chat records",
 			slog.Group("saveChat", "record", record,
 				slog.Group("Supabase", "error", err),
@@ -87,7 +98,7 @@ func (r *SupabaseRepository) InsertFetchedHistory(ctx context.Context, sourceId
 		CreatedAt: time.Now(),
 	}).Exec(ctx)
 	if err != nil {
-		slog.Error(
+		logging.FromContext(ctx).Error(
 			"Failed to insert fetched history",
 			slog.Group("Supabase", "error", err),
 		)
@@ -100,15 +111,20 @@ func (r *SupabaseRepository) InsertFetchedHistory(ctx context.Context, sourceId
 func (r *SupabaseRepository) GetFetchedHistory(ctx context.Context, sourceIds []string) ([]model.History, error) {
 	histories := make([]model.History, 0)
 	// get the last fetched history of each sourceId using Rank
+	// the rank has to be computed in a subquery because WHERE cannot refer to a window function
+	ranked := r.db.NewSelect().
+		Model((*model.History)(nil)).
+		ColumnExpr("source_id, created_at, RANK() OVER (PARTITION BY source_id ORDER BY created_at DESC) AS rank").
+		Where("source_id IN (?)", bun.In(sourceIds))
 	err := r.db.NewSelect().
 		Model(&histories).
-		Where("source_id IN (?)", sourceIds).
-		ColumnExpr("source_id, created_at, RANK() OVER (PARTITION BY source_id ORDER BY created_at DESC) as rank").
+		ModelTableExpr("(?) AS fetch_chat_history", ranked).
+		Column("source_id", "created_at").
 		Where("rank = 1").
 		Order("created_at ASC").
 		Scan(ctx)
 	if err != nil {
-		slog.Error(
+		logging.FromContext(ctx).Error(
 			"Failed to get fetched history",
 			slog.Group("Supabase", "error", err),
 		)
@@ -118,24 +134,31 @@ func (r *SupabaseRepository) GetFetchedHistory(ctx context.Context, sourceIds []
 	return histories, nil
 }
 
-func (r *SupabaseRepository) UpdateStatusBySourceID(ctx context.Context, sourceId string, status string) error {
+func (r *SupabaseRepository) UpdateStatusBySourceID(ctx context.Context, sourceId string, st status.Status) error {
 	video := model.VideoRecord{
 		SourceID:  sourceId,
-		Status:    status,
+		Status:    st,
 		UpdatedAt: time.Now(),
 	}
 
+	columns := []string{"status", "updated_at"}
+	// a video is archived when its chat has ended
+	if st == status.Archived {
+		video.ChatStatus = status.ChatEnded
+		columns = append(columns, "chat_status")
+	}
+
 	_, err := r.db.NewUpdate().
 		Model(&video).
-		Column("status", "updated_at").
+		Column(columns...).
 		Where("source_id = ?", sourceId).
 		Exec(ctx)
 
 	if err != nil {
-		slog.Error(
+		logging.FromContext(ctx).Error(
 			"Failed to update status",
 			"sourceId", sourceId,
-			"status", status,
+			"status", st,
 			slog.Group("Supabase", "error", err),
 		)
 		return err
@@ -143,3 +166,17 @@ func (r *SupabaseRepository) UpdateStatusBySourceID(ctx context.Context, sourceI
 
 	return nil
 }
+
+// GetFreeChats returns the active free-chat room of each channel, which Opus keeps up to date.
+func (r *SupabaseRepository) GetFreeChats(ctx context.Context) ([]model.FreeChatRecord, error) {
+	records := make([]model.FreeChatRecord, 0)
+	if err := r.db.NewSelect().Model(&records).Order("channel_id").Scan(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to get free chats",
+			slog.Group("Supabase", "error", err),
+		)
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,21 @@
+package infra
+
+import (
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/telemetry"
+	"go.opentelemetry.io/otel"
+	"log"
+)
+
+const instrumentationName = "github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/infra"
+
+var tracer = otel.Tracer(instrumentationName)
+
+var apiMetrics *telemetry.APIMetrics
+
+func init() {
+	var err error
+	apiMetrics, err = telemetry.NewAPIMetrics(otel.Meter(instrumentationName))
+	if err != nil {
+		log.Fatalf("Failed to create API metrics: %v", err)
+	}
+}
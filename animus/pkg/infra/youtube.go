@@ -2,9 +2,15 @@ package infra
 
 import (
 	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 	"log/slog"
+	"time"
 )
 
 type YouTubeRepository struct {
@@ -20,6 +26,11 @@ func NewYouTubeRepository(svc *youtube.Service) *YouTubeRepository {
 }
 
 func (r *YouTubeRepository) FetchChatsByChatID(ctx context.Context, chatID string, maxResults int64) (*youtube.LiveChatMessageListResponse, bool, error) {
+	ctx, span := tracer.Start(ctx, "YouTube LiveChatMessages.List", trace.WithAttributes(
+		attribute.String("youtube.chat_id", chatID),
+	))
+	defer span.End()
+
 	call := r.ytSvc.LiveChatMessages.List(chatID, []string{"snippet"})
 
 	// If the length is set, set the maximum number of messages to be fetched
@@ -30,18 +41,25 @@ func (r *YouTubeRepository) FetchChatsByChatID(ctx context.Context, chatID strin
 	call = call.Context(ctx)
 
 	// Fetch the chats from the YouTube API
+	start := time.Now()
 	resp, err := call.Do()
+	apiMetrics.Record(ctx, "LiveChatMessages.List", start, err)
 	if err != nil {
 		// If video is archived and an error occurs, recover the error and notify archiving
 		if err.Error() == "googleapi: Error 403: The live chat is no longer live., liveChatEnded" {
 			return nil, true, nil
 		}
 		// Otherwise, log the error and return the error as common error
-		slog.Error(
+		span.RecordError(err)
+		span.SetStatus(codes.Error, telemetry.ErrorReason(err))
+		logging.FromContext(ctx).ErrorContext(
+			ctx,
 			"Failed to run LiveChatMessages.List",
 			slog.Group("fetchChat", "chatId", chatID, slog.Group("YouTubeAPI", "error", err)),
 		)
 		return nil, false, err
 	}
+	span.SetAttributes(attribute.Int("youtube.chat.items", len(resp.Items)))
+
 	return resp, false, nil
 }
@@ -1,8 +1,7 @@
 package model
 
 import (
-	"github.com/uptrace/bun"
-	"time"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/schema"
 )
 
 type YTChat struct {
@@ -12,11 +11,4 @@ type YTChat struct {
 	SourceID        string
 }
 
-type ChatRecord struct {
-	bun.BaseModel `bun:"table:chats"`
-
-	Message     string    `bun:",pk,type:varchar(255)"`
-	IsNegative  bool      `bun:",type:tinyint(1)"`
-	SourceID    string    `bun:",type:varchar(255)"`
-	PublishedAt time.Time `bun:",type:timestamptz"`
-}
+type ChatRecord = schema.Chat
@@ -1,13 +1,7 @@
 package model
 
 import (
-	"github.com/uptrace/bun"
-	"time"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/schema"
 )
 
-type History struct {
-	bun.BaseModel `bun:"table:fetch_chat_history"`
-
-	SourceID  string    `bun:",type:varchar(255)"`
-	CreatedAt time.Time `bun:",type:timestamptz"`
-}
+type History = schema.FetchChatHistory
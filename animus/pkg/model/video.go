@@ -1,8 +1,7 @@
 package model
 
 import (
-	"github.com/uptrace/bun"
-	"time"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/schema"
 )
 
 type VideoInfo struct {
@@ -10,11 +9,6 @@ type VideoInfo struct {
 	ChatID   string `json:"chatId"`
 }
 
-type VideoRecord struct {
-	bun.BaseModel `bun:"table:videos"`
+type VideoRecord = schema.Video
 
-	SourceID  string    `bun:",type:varchar(255)"`
-	Status    string    `bun:",type:varchar(255)"`
-	ChatID    string    `bun:",type:varchar(255)"`
-	UpdatedAt time.Time `bun:",type:timestamptz"`
-}
+type FreeChatRecord = schema.FreeChat
@@ -3,13 +3,15 @@ package repository
 import (
 	"context"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/model"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 )
 
 type Supabase interface {
-	GetVideoInfoByStatus(ctx context.Context, status []string) ([]model.VideoRecord, error)
+	GetVideoInfoByStatus(ctx context.Context, statuses []status.Status) ([]model.VideoRecord, error)
 	InsertChatRecord(ctx context.Context, record []model.ChatRecord) error
 	GetPublishedAtOfLastRecordedChatBySource(ctx context.Context, sourceId string) (int64, error)
 	InsertFetchedHistory(ctx context.Context, sourceId string) error
 	GetFetchedHistory(ctx context.Context, sourceIds []string) ([]model.History, error)
-	UpdateStatusBySourceID(ctx context.Context, sourceId string, status string) error
+	UpdateStatusBySourceID(ctx context.Context, sourceId string, st status.Status) error
+	GetFreeChats(ctx context.Context) ([]model.FreeChatRecord, error)
 }
@@ -7,6 +7,9 @@ import (
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/lib"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/model"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/repository"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
 	"golang.org/x/text/unicode/norm"
 	"log/slog"
 	"regexp"
@@ -23,14 +26,24 @@ type chatService struct {
 	supaRepo repository.Supabase
 	sntRepo  repository.Sentiment
 	stampPat *regexp.Regexp
+	saved    metric.Int64Counter
 }
 
 func NewChatService(ytRepo repository.YouTube, supaRepo repository.Supabase, sntRepo repository.Sentiment, stampPat *regexp.Regexp) Chat {
+	saved, err := otel.Meter("github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/service").Int64Counter(
+		"animus.chats.saved",
+		metric.WithDescription("Chats inserted into the chats table"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
 	return &chatService{
 		ytRepo:   ytRepo,
 		supaRepo: supaRepo,
 		sntRepo:  sntRepo,
 		stampPat: stampPat,
+		saved:    saved,
 	}
 }
 
@@ -38,7 +51,7 @@ func (s *chatService) FetchChatsByVideoInfo(ctx context.Context, videoInfo model
 	// Fetch chats from the static target video
 	resp, archived, err := s.ytRepo.FetchChatsByChatID(ctx, videoInfo.ChatID, l)
 	if err != nil {
-		slog.Error(
+		logging.FromContext(ctx).Error(
 			"Failed to fetch chats",
 			slog.Group("fetchChat", "chatId", videoInfo.ChatID, "sourceId", videoInfo.SourceID,
 				slog.Group("YouTubeAPI", "error", err),
@@ -47,7 +60,7 @@ func (s *chatService) FetchChatsByVideoInfo(ctx context.Context, videoInfo model
 		return nil, false, err
 	}
 	if archived {
-		slog.Info("The chat is archived",
+		logging.FromContext(ctx).Info("The chat is archived",
 			slog.Group("fetchChat", "chatId", videoInfo.ChatID, "sourceId", videoInfo.SourceID),
 		)
 		return nil, true, nil
@@ -63,7 +76,7 @@ func (s *chatService) FetchChatsByVideoInfo(ctx context.Context, videoInfo model
 	for _, item := range items {
 		pa, err := synchro.ParseISO[tz.AsiaTokyo](item.Snippet.PublishedAt)
 		if err != nil {
-			slog.Error(
+			logging.FromContext(ctx).Error(
 				"Failed to parse the publishedAt",
 				slog.Group("fetchChat", "chatId", videoInfo.ChatID, "sourceId", videoInfo.SourceID,
 					slog.Group("formatChat", "error", err, "target", item.Snippet.PublishedAt)),
@@ -87,7 +100,7 @@ func (s *chatService) SaveNewTargetChats(ctx context.Context, chats []model.YTCh
 	for _, chat := range chats {
 		isNegative, err := s.analyzeNegativityOfChatMessage(ctx, chat.Message)
 		if err != nil {
-			slog.Error("Failed to analyze negativity of chat message",
+			logging.FromContext(ctx).Error("Failed to analyze negativity of chat message",
 				slog.Group("saveChat", "sourceId", chat.SourceID, "message", chat.Message, "error", err),
 			)
 			continue
@@ -102,13 +115,14 @@ func (s *chatService) SaveNewTargetChats(ctx context.Context, chats []model.YTCh
 
 	// Save the chats to the database
 	if err := s.supaRepo.InsertChatRecord(ctx, chatRecords); err != nil {
-		slog.Error("Failed to insert chats",
+		logging.FromContext(ctx).Error("Failed to insert chats",
 			slog.Group("saveChat", "sourceId", chats[0].SourceID,
 				slog.Group("Supabase", "error", err),
 			),
 		)
 		return err
 	}
+	s.saved.Add(ctx, int64(len(chatRecords)))
 
 	return nil
 }
@@ -125,7 +139,7 @@ func (s *chatService) analyzeNegativityOfChatMessage(ctx context.Context, msg st
 
 	score, magnitude, err := s.sntRepo.AnalyzeSentiment(ctx, reMsg)
 	if err != nil {
-		slog.Error("Failed to analyze sentiment",
+		logging.FromContext(ctx).Error("Failed to analyze sentiment",
 			slog.Group("analyzeSentiment", "error", err),
 		)
 		return false, err
@@ -3,15 +3,21 @@ package usecase
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/model"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/repository"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/service"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 	"log/slog"
 	"os"
 	"slices"
 )
 
+// ErrInvalidStaticTarget is returned when STATIC_TARGET is malformed, which no retry can fix.
+var ErrInvalidStaticTarget = errors.New("invalid STATIC_TARGET")
+
 type Chat interface {
 	FetchChatsFromStaticTargetVideo(ctx context.Context) ([]model.YTChat, error)
 	FetchChatsFromUpcomingTargetVideo(ctx context.Context, upc []model.VideoInfo) ([]model.YTChat, error)
@@ -33,44 +39,81 @@ func NewChatUsecase(targetChannel []string, chatSvc service.Chat, supaRepo repos
 }
 
 func (u *chatUsecase) FetchChatsFromStaticTargetVideo(ctx context.Context) ([]model.YTChat, error) {
-	// load info of static target video from environment variables
-	stcEnv := os.Getenv("STATIC_TARGET")
-	var stc model.VideoInfo
-	if err := json.Unmarshal([]byte(stcEnv), &stc); err != nil {
-		slog.Error("Failed to unmarshal STATIC_TARGET", "error", err)
-		// If the environment variable is not set correctly, the function will panic.
-		// (To prevent retries by CloudScheduler, the function should panic without returning error responses.)
-		panic(fmt.Sprintf("Failed to unmarshal static target: %v", err))
-	}
-
-	// Fetch chats from the static target video
-	stcChats, _, err := u.chatSvc.FetchChatsByVideoInfo(ctx, stc, 0)
+	stcs, err := u.loadStaticTargets(ctx)
 	if err != nil {
-		slog.Error("Failed to fetch chats from the static target video", slog.Group("staticTarget", "error", err))
 		return nil, err
 	}
+	if len(stcs) == 0 {
+		logging.FromContext(ctx).Info("No static target video")
+		return nil, nil
+	}
 
-	// Filter chats by author channel
-	targetChats, _ := filterChatsByAuthorChannel(stcChats, u.targetChannel)
+	var newChats []model.YTChat
+	for _, stc := range stcs {
+		// Fetch chats from the static target video
+		stcChats, _, err := u.chatSvc.FetchChatsByVideoInfo(ctx, stc, 0)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to fetch chats from the static target video",
+				slog.Group("staticTarget", "sourceId", stc.SourceID, "error", err),
+			)
+			return nil, err
+		}
 
-	if len(targetChats) == 0 {
-		slog.Info("No chats from the static target video")
-		return nil, nil
+		// Filter chats by author channel
+		targetChats, _ := filterChatsByAuthorChannel(stcChats, u.targetChannel)
+
+		if len(targetChats) == 0 {
+			logging.FromContext(ctx).Info("No chats from the static target video",
+				slog.Group("staticTarget", "sourceId", stc.SourceID),
+			)
+			continue
+		}
+
+		// Filter chats by the publishedAt
+		chats, err := u.filterChatsByPublishedAt(ctx, targetChats, stc.SourceID)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to filter chats by the publishedAt",
+				slog.Group("staticTarget", "error", err),
+			)
+			return nil, err
+		}
+		newChats = append(newChats, chats...)
 	}
 
-	// Filter chats by the publishedAt
-	newChats, err := u.filterChatsByPublishedAt(ctx, targetChats, stc.SourceID)
+	// debug log
+	logging.FromContext(ctx).Debug("Fetched chats from the static target videos", "count", len(newChats))
+
+	return newChats, nil
+}
+
+// loadStaticTargets returns the free chats stored by Opus.
+// STATIC_TARGET is used instead while Opus has not found any.
+func (u *chatUsecase) loadStaticTargets(ctx context.Context) ([]model.VideoInfo, error) {
+	fcs, err := u.supaRepo.GetFreeChats(ctx)
 	if err != nil {
-		slog.Error("Failed to filter chats by the publishedAt",
-			slog.Group("staticTarget", "error", err),
-		)
+		logging.FromContext(ctx).Error("Failed to get free chats", slog.Group("staticTarget", "error", err))
 		return nil, err
 	}
+	if len(fcs) > 0 {
+		stcs := make([]model.VideoInfo, 0, len(fcs))
+		for _, fc := range fcs {
+			stcs = append(stcs, model.VideoInfo{SourceID: fc.SourceID, ChatID: fc.ChatID})
+		}
+		return stcs, nil
+	}
 
-	// debug log
-	slog.Debug("Fetched chats from the static target video", "count", len(newChats))
+	// load info of static target video from environment variables
+	stcEnv := os.Getenv("STATIC_TARGET")
+	if stcEnv == "" {
+		return nil, nil
+	}
+	var stc model.VideoInfo
+	if err := json.Unmarshal([]byte(stcEnv), &stc); err != nil {
+		logging.FromContext(ctx).Error("Failed to unmarshal STATIC_TARGET", "error", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStaticTarget, err)
+	}
 
-	return newChats, nil
+	return []model.VideoInfo{stc}, nil
 }
 
 func (u *chatUsecase) FetchChatsFromUpcomingTargetVideo(ctx context.Context, upc []model.VideoInfo) ([]model.YTChat, error) {
@@ -81,7 +124,7 @@ func (u *chatUsecase) FetchChatsFromUpcomingTargetVideo(ctx context.Context, upc
 	// the priority is based on fetched history
 	switch len(upc) {
 	case 0:
-		slog.Info("No upcoming target video")
+		logging.FromContext(ctx).Info("No upcoming target video")
 		return nil, nil
 	case 1:
 		video = upc[0]
@@ -89,7 +132,7 @@ func (u *chatUsecase) FetchChatsFromUpcomingTargetVideo(ctx context.Context, upc
 		// Get fetched history from the Supabase
 		top, err := u.calculatePriority(ctx, upc)
 		if err != nil {
-			slog.Error("Failed to calculate priority",
+			logging.FromContext(ctx).Error("Failed to calculate priority",
 				slog.Group("upcomingTarget", "error", err),
 			)
 			return nil, err
@@ -99,7 +142,7 @@ func (u *chatUsecase) FetchChatsFromUpcomingTargetVideo(ctx context.Context, upc
 
 	upcChats, archived, err := u.chatSvc.FetchChatsByVideoInfo(ctx, video, 0)
 	if err != nil {
-		slog.Error("Failed to fetch chats from the upcoming target video",
+		logging.FromContext(ctx).Error("Failed to fetch chats from the upcoming target video",
 			slog.Group("upcomingTarget", "error", err),
 		)
 		return nil, err
@@ -107,7 +150,7 @@ func (u *chatUsecase) FetchChatsFromUpcomingTargetVideo(ctx context.Context, upc
 
 	// Save the fetched history to the Supabase
 	if err := u.supaRepo.InsertFetchedHistory(ctx, video.SourceID); err != nil {
-		slog.Error("Failed to insert fetched history",
+		logging.FromContext(ctx).Error("Failed to insert fetched history",
 			slog.Group("upcomingTarget", "sourceId", video.SourceID, "error", err),
 		)
 		return nil, err
@@ -115,8 +158,8 @@ func (u *chatUsecase) FetchChatsFromUpcomingTargetVideo(ctx context.Context, upc
 
 	// If the chat is archived, update the status of the video to archived and return
 	if archived {
-		if err := u.supaRepo.UpdateStatusBySourceID(ctx, video.SourceID, "archived"); err != nil {
-			slog.Error("Failed to update the status of the video to archived",
+		if err := u.supaRepo.UpdateStatusBySourceID(ctx, video.SourceID, status.Archived); err != nil {
+			logging.FromContext(ctx).Error("Failed to update the status of the video to archived",
 				slog.Group("upcomingTarget", "sourceId", video.SourceID, "error", err),
 			)
 			return nil, err
@@ -129,7 +172,7 @@ func (u *chatUsecase) FetchChatsFromUpcomingTargetVideo(ctx context.Context, upc
 	targetChats, _ := filterChatsByAuthorChannel(upcChats, u.targetChannel)
 
 	if len(targetChats) == 0 {
-		slog.Info("No new chats from the upcoming target video",
+		logging.FromContext(ctx).Info("No new chats from the upcoming target video",
 			slog.Group("upcomingTarget", "sourceId", video.SourceID),
 		)
 		return nil, nil
@@ -138,7 +181,7 @@ func (u *chatUsecase) FetchChatsFromUpcomingTargetVideo(ctx context.Context, upc
 	// Filter chats by the publishedAt
 	newChats, err := u.filterChatsByPublishedAt(ctx, targetChats, video.SourceID)
 	if err != nil {
-		slog.Error("Failed to filter chats by the publishedAt",
+		logging.FromContext(ctx).Error("Failed to filter chats by the publishedAt",
 			slog.Group("upcomingTarget", "error", err),
 		)
 		return nil, err
@@ -164,7 +207,7 @@ func (u *chatUsecase) filterChatsByPublishedAt(ctx context.Context, chats []mode
 	// Fetch the last recorded chat's publishedAt from the Supabase
 	threshold, err := u.supaRepo.GetPublishedAtOfLastRecordedChatBySource(ctx, sourceId)
 	if err != nil {
-		slog.Error("Failed to get the last recorded chat",
+		logging.FromContext(ctx).Error("Failed to get the last recorded chat",
 			slog.Group("filterChat", "chats", chats, "sourceId", sourceId),
 		)
 		return nil, err
@@ -196,7 +239,7 @@ func (u *chatUsecase) calculatePriority(ctx context.Context, videos []model.Vide
 
 	histories, err := u.supaRepo.GetFetchedHistory(ctx, ids)
 	if err != nil {
-		slog.Error("Failed to get fetched history",
+		logging.FromContext(ctx).Error("Failed to get fetched history",
 			slog.Group("upcomingTarget", "error", err),
 		)
 		return model.VideoInfo{}, err
@@ -229,7 +272,7 @@ func (u *chatUsecase) calculatePriority(ctx context.Context, videos []model.Vide
 		}
 	}
 
-	slog.Error(
+	logging.FromContext(ctx).Error(
 		"Failed to calculate priority",
 		slog.Group("upcomingTarget",
 			"histories", histories,
@@ -243,7 +286,7 @@ func (u *chatUsecase) calculatePriority(ctx context.Context, videos []model.Vide
 func (u *chatUsecase) SaveNewChats(ctx context.Context, chats []model.YTChat) error {
 	// Save the new target chats
 	if err := u.chatSvc.SaveNewTargetChats(ctx, chats); err != nil {
-		slog.Error("Failed to save the new target chats",
+		logging.FromContext(ctx).Error("Failed to save the new target chats",
 			slog.Group("saveChat", "error", err),
 		)
 		return err
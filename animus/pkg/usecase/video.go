@@ -4,11 +4,12 @@ import (
 	"context"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/model"
 	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/repository"
-	"log/slog"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 )
 
 type Video interface {
-	GetVideoInfosByStatusFromSupabase(ctx context.Context, status []string) (map[string][]model.VideoInfo, error)
+	GetVideoInfosByStatusFromSupabase(ctx context.Context, statuses []status.Status) (map[status.Status][]model.VideoInfo, error)
 }
 
 type videoUsecase struct {
@@ -21,12 +22,12 @@ func NewVideoUsecase(supaRepo repository.Supabase) Video {
 	}
 }
 
-func (u *videoUsecase) GetVideoInfosByStatusFromSupabase(ctx context.Context, status []string) (map[string][]model.VideoInfo, error) {
-	rec, err := u.supaRepo.GetVideoInfoByStatus(ctx, status)
+func (u *videoUsecase) GetVideoInfosByStatusFromSupabase(ctx context.Context, statuses []status.Status) (map[status.Status][]model.VideoInfo, error) {
+	rec, err := u.supaRepo.GetVideoInfoByStatus(ctx, statuses)
 	if err != nil {
-		slog.Error("Failed to get video records",
-			"status", status,
-			slog.Group("error", err),
+		logging.FromContext(ctx).Error("Failed to get video records",
+			"status", statuses,
+			"error", err,
 		)
 		return nil, err
 	}
@@ -35,7 +36,7 @@ func (u *videoUsecase) GetVideoInfosByStatusFromSupabase(ctx context.Context, st
 		return nil, nil
 	}
 
-	videoInfos := make(map[string][]model.VideoInfo)
+	videoInfos := make(map[status.Status][]model.VideoInfo)
 	for _, r := range rec {
 		videoInfos[r.Status] = append(videoInfos[r.Status], model.VideoInfo{
 			SourceID: r.SourceID,
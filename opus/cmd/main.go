@@ -1,28 +1,189 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/cloudfunction"
-	"github.com/KasumiMercury/patotta-stone-functions-go/opus/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/rss"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/service"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/auth"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/pubsub"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/telemetry"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/GoogleCloudPlatform/functions-framework-go/funcframework"
 	// Blank-import the function package so the init() runs
 )
 
 var handler *cloudfunction.Handler
+var queryHandler *cloudfunction.QueryHandler
 
 func init() {
-	handler = cloudfunction.NewCloudFunctionHandler()
-
-	logger := logging.NewCustomLogger()
+	logger := logging.NewCustomLogger("opus")
 	slog.SetDefault(logger)
 
+	// Exporter failures must not stop the function, so fall back to the no-op providers
+	if _, err := telemetry.Setup(context.Background(), telemetry.ConfigFromEnv()); err != nil {
+		slog.Error("Failed to set up telemetry", slog.Group("Telemetry", "error", err))
+	}
+
+	// Dependencies that fail to initialize are reported by the readiness check
+	// instead of crashing the instance, so that /healthz and /readyz stay reachable.
+	cfg, cfgErr := config.NewConfig()
+	if cfgErr != nil {
+		slog.Error("Failed to load config", "error", cfgErr)
+		cfg = &config.Config{}
+	}
+
+	ytClt, ytErr := api.NewYouTubeClient(*cfg)
+	if ytErr != nil {
+		slog.Error("Failed to create YouTube client", slog.Group("YouTubeAPI", "error", ytErr))
+	}
+
+	// Creating the Postgres client does not connect to the database, so it only fails on a broken setup.
+	// REALTIME_DSN may also point to SQLite or memory for local runs.
+	// Without a config there is no DSN to open, and the database check reports the config error.
+	var rtd realtime.Backend = realtime.NewUnavailable(cfgErr)
+	if cfgErr == nil {
+		b, err := realtime.Open(context.Background(), cfg.Realtime.Dsn)
+		if err != nil {
+			slog.Error("Failed to create Realtime client", slog.Group("Realtime", "error", err))
+			b = realtime.NewUnavailable(fmt.Errorf("failed to create Realtime client: %w", err))
+		}
+		rtd = b
+	}
+
+	// The targets table is read only when neither TARGET_FILE nor TARGET_JSON is set.
+	// A table that cannot be read yet, e.g. before the migration, keeps the targets loaded so far.
+	if cfgErr == nil {
+		cfg.UseTargetStore(rtd)
+		if _, err := cfg.ReloadTargets(context.Background()); err != nil {
+			slog.Error("Failed to reload targets", slog.Group("Target", "error", err))
+		}
+		slog.Info("Loaded targets", slog.Group("Target", "source", cfg.TargetSource(), "channels", len(cfg.Targets())))
+	}
+
+	// Handles missing from the embedded cache cost a channels.list call each at every cold start, so cmd/resolve should keep it up to date.
+	// A handle that cannot be resolved is a broken config, which keeps the instance from syncing.
+	if cfgErr == nil && ytErr == nil {
+		if _, err := cfg.ResolveHandles(context.Background(), api.NewYouTubeVideo(ytClt)); err != nil {
+			slog.Error("Failed to resolve channel handles", "error", err)
+			cfgErr = fmt.Errorf("failed to resolve channel handles: %w", err)
+		}
+	}
+	if cfgErr == nil && cfg.Sources.ReloadInterval > 0 {
+		go watchTargets(cfg)
+	}
+
+	checks := []cloudfunction.Check{
+		{Name: "config", Check: func(_ context.Context) error { return validateConfig(cfg, cfgErr) }},
+		{Name: "youtube", Check: func(_ context.Context) error { return ytErr }},
+		{Name: "database", Check: rtd.Ping},
+	}
+	if cfg.Realtime.MigrateOnStart {
+		migErr := migrateOnStart(context.Background(), rtd)
+		if migErr != nil {
+			slog.Error("Failed to migrate on start", slog.Group("Migration", "error", migErr))
+		}
+		checks = append(checks, cloudfunction.Check{Name: "migration", Check: func(_ context.Context) error { return migErr }})
+	}
+	healthHandler := cloudfunction.NewHealthHandler(checks, rtd, cfg, cfg.Health.StaleThreshold)
+
+	var syncSvc cloudfunction.SyncService
+	var staleSvc cloudfunction.StaleChecker
+	var scheduleSvc cloudfunction.ScheduleRefresher
+	var reconcileSvc cloudfunction.Reconciler
+	var statsSvc cloudfunction.StatsSnapshotter
+	var channelSvc cloudfunction.ChannelSyncer
+	if cfgErr == nil && ytErr == nil {
+		syncSvc = service.NewSyncService(*cfg, rss.NewRssClient(rss.NewParser()), api.NewYouTubeVideo(ytClt), rtd, rtd, rtd, rtd)
+		staleSvc = service.NewStaleService(*cfg, api.NewYouTubeVideo(ytClt), rtd)
+		refreshSvc := service.NewRefreshService(*cfg, api.NewYouTubeVideo(ytClt), rtd)
+		scheduleSvc, reconcileSvc = refreshSvc, refreshSvc
+		statsSvc = service.NewStatsService(*cfg, api.NewYouTubeVideo(ytClt), rtd, rtd)
+		channelSvc = service.NewChannelService(*cfg, api.NewYouTubeVideo(ytClt), rtd)
+	}
+	handler = cloudfunction.NewCloudFunctionHandler(syncSvc, cfg, rtd.Locker(), rtd, healthHandler)
+	handler.Register(run.OperationScheduleRefresh, cloudfunction.ScheduleRefreshOperation(scheduleSvc))
+	handler.Register(run.OperationReconcile, cloudfunction.ReconcileOperation(reconcileSvc))
+	handler.Register(run.OperationStaleCheck, cloudfunction.StaleCheckOperation(staleSvc))
+	handler.Register(run.OperationStatsSnapshot, cloudfunction.StatsSnapshotOperation(statsSvc))
+	handler.Register(run.OperationChannelSync, cloudfunction.ChannelSyncOperation(channelSvc))
+
+	queryHandler = cloudfunction.NewQueryHandler(rtd, rtd, rtd, rtd)
+
+	// Authentication is enabled by AUTH_AUDIENCE. A broken setup must not leave the functions open, so it stops the instance.
+	var verifier *auth.Verifier
+	if ac := auth.ConfigFromEnv(); ac.Enabled() {
+		var err error
+		verifier, err = auth.NewVerifier(ac)
+		if err != nil {
+			slog.Error("Failed to create token verifier", slog.Group("Auth", "error", err))
+			log.Fatalf("Failed to create token verifier: %v", err)
+		}
+	}
+
 	// Register the function to handle HTTP requests
-	functions.HTTP("Opus", EntryPoint)
+	functions.HTTP("Opus", telemetry.WrapHTTP("Opus", logging.Middleware(auth.Middleware(verifier, EntryPoint))))
+	// Register the same operations for a Pub/Sub trigger, which retries failed messages
+	functions.CloudEvent("OpusPubSub", pubsub.Handler(PubSubEntryPoint))
+	// Register the read-only query API over the videos table
+	functions.HTTP("OpusQuery", telemetry.WrapHTTP("OpusQuery", logging.Middleware(auth.Middleware(verifier, QueryEntryPoint))))
+}
+
+func validateConfig(cfg *config.Config, err error) error {
+	if err != nil {
+		return err
+	}
+	if cfg.Api.ApiKey == "" {
+		return errors.New("API_KEY is not set")
+	}
+	if cfg.Realtime.Dsn == "" {
+		return errors.New("REALTIME_DSN is not set")
+	}
+	if len(cfg.Targets()) == 0 {
+		return errors.New("no target channel is configured")
+	}
+
+	return nil
+}
+
+// watchTargets reloads the targets while the instance is warm, so that a change needs no redeployment.
+// Broken targets are logged, and the instance keeps the last good ones.
+func watchTargets(cfg *config.Config) {
+	ticker := time.NewTicker(cfg.Sources.ReloadInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		changed, err := cfg.ReloadTargets(context.Background())
+		if err != nil {
+			slog.Error("Failed to reload targets", slog.Group("Target", "error", err))
+			continue
+		}
+		if changed {
+			slog.Info("Reloaded targets", slog.Group("Target", "source", cfg.TargetSource(), "channels", len(cfg.Targets())))
+		}
+	}
+}
+
+// migrateOnStart applies pending migrations. Instances starting together are serialized by the lock of golang-migrate.
+func migrateOnStart(ctx context.Context, rtd realtime.Backend) error {
+	if err := rtd.Migrate(ctx); err != nil {
+		return err
+	}
+	slog.Info("Migrated on start")
+
+	return nil
 }
 
 func main() {
@@ -41,3 +202,11 @@ func main() {
 func EntryPoint(w http.ResponseWriter, r *http.Request) {
 	handler.Handle(w, r)
 }
+
+func QueryEntryPoint(w http.ResponseWriter, r *http.Request) {
+	queryHandler.Handle(w, r)
+}
+
+func PubSubEntryPoint(ctx context.Context, m pubsub.Message) error {
+	return handler.HandleMessage(ctx, m)
+}
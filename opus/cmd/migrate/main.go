@@ -0,0 +1,105 @@
+// Command migrate applies the shared schema migrations to the database in REALTIME_DSN.
+//
+//	migrate up
+//	migrate down N
+//	migrate status
+//	migrate force VERSION
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/migration"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("REALTIME_DSN"), "database to migrate, defaults to REALTIME_DSN")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: migrate [-dsn DSN] up | down N | status | force VERSION")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(context.Background(), *dsn, flag.Args()); err != nil {
+		slog.Error("Failed to migrate", slog.Group("Migration", "error", err))
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, dsn string, args []string) (err error) {
+	if dsn == "" {
+		return errors.New("REALTIME_DSN is not set")
+	}
+	if len(args) == 0 {
+		flag.Usage()
+		return errors.New("no command is given")
+	}
+
+	db := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	r, err := migration.NewPostgresRunner(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, r.Close())
+	}()
+
+	switch cmd := args[0]; cmd {
+	case "up":
+		if err := r.Up(); err != nil {
+			return err
+		}
+	case "down":
+		n, err := intArg(args)
+		if err != nil {
+			return err
+		}
+		if err := r.Down(n); err != nil {
+			return err
+		}
+	case "force":
+		v, err := intArg(args)
+		if err != nil {
+			return err
+		}
+		if err := r.Force(v); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+
+	// every command ends by printing where the database is
+	s, err := r.Status()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+func intArg(args []string) (int, error) {
+	if len(args) != 2 {
+		return 0, fmt.Errorf("%s takes exactly one number", args[0])
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid number %q", args[0], args[1])
+	}
+
+	return n, nil
+}
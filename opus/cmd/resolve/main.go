@@ -0,0 +1,66 @@
+// Command resolve looks up the channel IDs of the handles in target.json, and writes them as the handle cache
+// that is embedded into Opus, so that instances do not resolve them at every cold start.
+//
+//	resolve [-out config/handles.json]
+//
+// API_KEY is read like in the function, so run it with LOCAL_ONLY=true to read .env.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api"
+	"log/slog"
+	"os"
+)
+
+func main() {
+	out := flag.String("out", "config/handles.json", "file to write the handle cache to")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: resolve [-out FILE]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(context.Background(), *out); err != nil {
+		slog.Error("Failed to resolve handles", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, out string) error {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		return err
+	}
+	clt, err := api.NewYouTubeClient(*cfg)
+	if err != nil {
+		return err
+	}
+
+	// the embedded cache was applied by NewConfig, so every handle is resolved again from scratch
+	target := config.Target{Channel: make([]config.Channel, 0)}
+	for _, h := range cfg.Handles() {
+		target.Channel = append(target.Channel, config.Channel{Display: h, Handle: h})
+	}
+	fresh := &config.Config{}
+	fresh.SetTarget(target)
+	cache, err := fresh.ResolveHandles(ctx, api.NewYouTubeVideo(clt))
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	slog.Info("Wrote the handle cache", "handles", len(cache), "out", out)
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// Command targets replaces the targets table of the database in REALTIME_DSN with a target file,
+// which Opus reads when neither TARGET_FILE nor TARGET_JSON is set.
+//
+//	targets [-dsn DSN] FILE
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"log/slog"
+	"os"
+)
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("REALTIME_DSN"), "database to write to, defaults to REALTIME_DSN")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: targets [-dsn DSN] FILE")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(context.Background(), *dsn, flag.Args()); err != nil {
+		slog.Error("Failed to import targets", slog.Group("Target", "error", err))
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, dsn string, args []string) error {
+	if dsn == "" {
+		return errors.New("REALTIME_DSN is not set")
+	}
+	if len(args) != 1 {
+		flag.Usage()
+		return errors.New("no target file is given")
+	}
+
+	raw, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	// a broken file is rejected here, instead of by every instance at its next reload
+	t, err := config.ParseTarget(raw, config.TargetFormat(args[0]))
+	if err != nil {
+		return err
+	}
+
+	rtd, err := realtime.Open(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	if err := rtd.SaveTargets(ctx, t.Channel); err != nil {
+		return err
+	}
+	slog.Info("Imported targets", "channels", len(t.Channel), "file", args[0])
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// channelIDPattern is the format of a channel ID, UC followed by 22 characters of URL-safe base64
+var channelIDPattern = regexp.MustCompile(`^UC[0-9A-Za-z_-]{22}$`)
+
+var youtubeHosts = []string{"youtube.com", "www.youtube.com", "m.youtube.com"}
+
+// HandleResolver looks up the channel ID of an @handle, which is done by channels.list?forHandle=.
+type HandleResolver interface {
+	ResolveHandle(ctx context.Context, handle string) (string, error)
+}
+
+func validChannelID(id string) bool {
+	return channelIDPattern.MatchString(id)
+}
+
+// normalize fills ChannelId or Handle of ch from whichever of channelId, handle and url is given.
+// An entry with a Handle and no ChannelId is left to be resolved.
+func (ch *Channel) normalize() error {
+	given := 0
+	for _, v := range []string{ch.ChannelId, ch.Handle, ch.URL} {
+		if v != "" {
+			given++
+		}
+	}
+	switch {
+	case given == 0:
+		return errors.New("one of channelId, handle and url is required")
+	case given > 1:
+		return errors.New("only one of channelId, handle and url may be given")
+	}
+
+	if ch.URL != "" {
+		id, handle, err := parseChannelURL(ch.URL)
+		if err != nil {
+			return err
+		}
+		ch.ChannelId, ch.Handle = id, handle
+	}
+	if ch.ChannelId != "" && !validChannelID(ch.ChannelId) {
+		return fmt.Errorf("invalid channel ID %q", ch.ChannelId)
+	}
+	if ch.Handle != "" {
+		h, err := normalizeHandle(ch.Handle)
+		if err != nil {
+			return err
+		}
+		ch.Handle = h
+	}
+
+	switch ch.Discovery {
+	case "", DiscoveryRSS, DiscoveryNone:
+	default:
+		return fmt.Errorf("unknown discovery %q", ch.Discovery)
+	}
+	if ch.Filters != nil {
+		if err := ch.Filters.compile(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseChannelURL reads a channel ID from youtube.com/channel/ID, or a handle from youtube.com/@handle.
+// The legacy /c/ and /user/ URLs cannot be resolved by the API, so they are rejected.
+func parseChannelURL(raw string) (id, handle string, err error) {
+	s := raw
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid url %q: %w", raw, err)
+	}
+	host := strings.ToLower(u.Hostname())
+	known := false
+	for _, h := range youtubeHosts {
+		known = known || host == h
+	}
+	if !known {
+		return "", "", fmt.Errorf("url %q is not a YouTube channel", raw)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case len(segments) >= 2 && segments[0] == "channel":
+		return segments[1], "", nil
+	case strings.HasPrefix(segments[0], "@"):
+		return "", segments[0], nil
+	default:
+		return "", "", fmt.Errorf("url %q is neither /channel/ID nor /@handle", raw)
+	}
+}
+
+// normalizeHandle adds the leading @ if it is missing, which is how the API prints handles.
+func normalizeHandle(h string) (string, error) {
+	name := strings.TrimPrefix(strings.TrimSpace(h), "@")
+	if name == "" || strings.ContainsAny(name, " /?#@") {
+		return "", fmt.Errorf("invalid handle %q", h)
+	}
+
+	return "@" + name, nil
+}
+
+// handleKey is the key of a handle in the cache. Handles are case-insensitive.
+func handleKey(h string) string {
+	return strings.ToLower(h)
+}
+
+// applyHandleCache fills the channel IDs of the handles found in cache.
+func (t *Target) applyHandleCache(cache map[string]string) {
+	for i := range t.Channel {
+		ch := &t.Channel[i]
+		if ch.ChannelId == "" && ch.Handle != "" {
+			ch.ChannelId = cache[handleKey(ch.Handle)]
+		}
+	}
+}
+
+// resolveHandles resolves the handles that have no channel ID yet, and returns them as a cache to save.
+// Every handle that cannot be resolved is reported in a single error, and leaves t unchanged.
+func (t *Target) resolveHandles(ctx context.Context, r HandleResolver) (map[string]string, error) {
+	resolved := make(map[string]string)
+	var errs []error
+	for _, ch := range t.Channel {
+		if ch.ChannelId != "" || ch.Handle == "" {
+			continue
+		}
+		id, err := r.ResolveHandle(ctx, ch.Handle)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("channel %q: failed to resolve %s: %w", ch.Display, ch.Handle, err))
+		case !validChannelID(id):
+			errs = append(errs, fmt.Errorf("channel %q: %s resolved to an invalid channel ID %q", ch.Display, ch.Handle, id))
+		default:
+			resolved[handleKey(ch.Handle)] = id
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	t.applyHandleCache(resolved)
+
+	return resolved, nil
+}
+
+// Handles returns the handles of the target channels, whether they are resolved or not.
+func (c *Config) Handles() []string {
+	hs := make([]string, 0)
+	for _, ch := range c.current().Channel {
+		if ch.Handle != "" {
+			hs = append(hs, ch.Handle)
+		}
+	}
+
+	return hs
+}
+
+// ResolveHandles resolves the handles that the cache did not, and returns them as a cache to save.
+// r is kept to resolve the handles of the targets reloaded later.
+func (c *Config) ResolveHandles(ctx context.Context, r HandleResolver) (map[string]string, error) {
+	t := c.current()
+	t.Channel = slices.Clone(t.Channel)
+	resolved, err := t.resolveHandles(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	c.SetTarget(t)
+	c.targets.mu.Lock()
+	c.targets.resolver = r
+	c.targets.mu.Unlock()
+	c.targets.addToCache(resolved)
+
+	return resolved, nil
+}
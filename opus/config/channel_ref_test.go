@@ -0,0 +1,130 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const (
+	mainID = "UCeLzT-7b2PBcunJplmWtoDg"
+	subID  = "UC1tBdWwNOsdMcO904N7eiWA"
+)
+
+func TestChannel_Normalize(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		channel Channel
+		want    Channel
+		wantErr bool
+	}{
+		"channel ID":             {channel: Channel{ChannelId: mainID}, want: Channel{ChannelId: mainID}},
+		"handle":                 {channel: Channel{Handle: "@main"}, want: Channel{Handle: "@main"}},
+		"handle without @":       {channel: Channel{Handle: "main"}, want: Channel{Handle: "@main"}},
+		"channel URL":            {channel: Channel{URL: "https://www.youtube.com/channel/" + mainID}, want: Channel{ChannelId: mainID, URL: "https://www.youtube.com/channel/" + mainID}},
+		"handle URL":             {channel: Channel{URL: "youtube.com/@main/videos"}, want: Channel{Handle: "@main", URL: "youtube.com/@main/videos"}},
+		"malformed channel ID":   {channel: Channel{ChannelId: "UCtypo"}, wantErr: true},
+		"malformed handle":       {channel: Channel{Handle: "@ma in"}, wantErr: true},
+		"legacy URL":             {channel: Channel{URL: "https://www.youtube.com/c/main"}, wantErr: true},
+		"other site":             {channel: Channel{URL: "https://example.com/@main"}, wantErr: true},
+		"URL of a malformed ID":  {channel: Channel{URL: "https://www.youtube.com/channel/UCtypo"}, wantErr: true},
+		"nothing given":          {channel: Channel{Display: "main"}, wantErr: true},
+		"more than one is given": {channel: Channel{ChannelId: mainID, Handle: "@main"}, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := tt.channel
+			err := got.normalize()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseTarget([]byte(`{"channel": [
+		{"display": "main", "channelId": "UCtypo"},
+		{"display": "sub", "handle": "@sub"},
+		{"display": "other", "url": "https://example.com/@other"}
+	]}`), "json")
+
+	// both broken entries are reported together
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), `channel 0 ("main")`), err.Error())
+		assert.True(t, strings.Contains(err.Error(), `channel 2 ("other")`), err.Error())
+		assert.False(t, strings.Contains(err.Error(), "sub"), err.Error())
+	}
+}
+
+// fakeResolver knows the handles in ids
+type fakeResolver struct {
+	ids      map[string]string
+	resolved []string
+}
+
+func (f *fakeResolver) ResolveHandle(_ context.Context, handle string) (string, error) {
+	f.resolved = append(f.resolved, handle)
+	id, ok := f.ids[handle]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return id, nil
+}
+
+func TestConfig_ResolveHandles(t *testing.T) {
+	t.Parallel()
+
+	load := func(t *testing.T, cache map[string]string) *Config {
+		t.Helper()
+		target, err := ParseTarget([]byte(`{"channel": [
+			{"display": "main", "url": "https://www.youtube.com/@Main"},
+			{"display": "sub", "handle": "@sub"},
+			{"display": "id", "channelId": "`+mainID+`"}
+		]}`), "json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		target.applyHandleCache(cache)
+		c := &Config{}
+		c.SetTarget(*target)
+		return c
+	}
+
+	t.Run("cached handles are not resolved again", func(t *testing.T) {
+		t.Parallel()
+		c := load(t, map[string]string{"@main": mainID})
+		r := &fakeResolver{ids: map[string]string{"@sub": subID}}
+
+		cache, err := c.ResolveHandles(context.Background(), r)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"@sub"}, r.resolved)
+		assert.Equal(t, map[string]string{"@sub": subID}, cache)
+		assert.Equal(t, []string{mainID, subID, mainID}, c.ChannelIDs())
+	})
+
+	t.Run("every unresolved handle is reported", func(t *testing.T) {
+		t.Parallel()
+		c := load(t, nil)
+		r := &fakeResolver{ids: map[string]string{"@Main": "not an ID"}}
+
+		_, err := c.ResolveHandles(context.Background(), r)
+		if assert.Error(t, err) {
+			assert.True(t, strings.Contains(err.Error(), `channel "main"`), err.Error())
+			assert.True(t, strings.Contains(err.Error(), `channel "sub"`), err.Error())
+		}
+		assert.Equal(t, []string{"", "", mainID}, c.ChannelIDs())
+	})
+}
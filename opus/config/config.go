@@ -1,74 +1,260 @@
 package config
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/secrets"
 	"github.com/joho/godotenv"
+	"io/fs"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 //go:embed target.json
 var target string
 
+// handles is the cache of resolved handles, written by cmd/resolve
+//
+//go:embed handles.json
+var handles string
+
+const (
+	defaultStaleThreshold    = 30 * time.Minute
+	defaultFreeChatHorizon   = 30 * 24 * time.Hour
+	defaultStaleGracePeriod  = 6 * time.Hour
+	defaultReconcileAhead    = 30 * time.Minute
+	defaultStatsLiveInterval = 5 * time.Minute
+	defaultStatsInterval     = time.Hour
+	defaultStatsWindow       = 7 * 24 * time.Hour
+	defaultChannelsRefresh   = 24 * time.Hour
+	defaultTargetReload      = time.Minute
+)
+
+var defaultFreeChatKeywords = []string{"フリーチャット", "フリーチャ", "free chat"}
+
 type Config struct {
-	Target Target
-	Api    Api
+	Api       Api
+	Realtime  Realtime
+	Health    Health
+	FreeChat  FreeChat
+	Stale     Stale
+	Reconcile Reconcile
+	Stats     Stats
+	Channels  Channels
+	Sources   TargetSources
+	// targets follow the reloads, read them with Targets and ChannelIDs
+	targets *targetSet
 }
 
 type Api struct {
 	ApiKey string
 }
 
-type Target struct {
-	Channel []Channel `json:"channel"`
+type Realtime struct {
+	Dsn string
+	// MigrateOnStart applies pending migrations when an instance starts
+	MigrateOnStart bool
+}
+
+type Health struct {
+	// StaleThreshold is the period after which a channel without successful sync is reported as stale
+	StaleThreshold time.Duration
+}
+
+// FreeChat holds the rules that classify an upcoming broadcast as a free-chat room
+type FreeChat struct {
+	// Horizon is how far ahead a room is scheduled, 0 disables the check
+	Horizon  time.Duration
+	Keywords []string
+}
+
+type Stale struct {
+	// GracePeriod is how long after its schedule an upcoming broadcast is checked again
+	GracePeriod time.Duration
+}
+
+type Reconcile struct {
+	// Ahead is how long before its schedule an upcoming broadcast is reconciled with the API
+	Ahead time.Duration
+}
+
+// Stats is how often the statistics of a video are sampled
+type Stats struct {
+	LiveInterval time.Duration
+	Interval     time.Duration
+	// Window is how long after it ends an archived video is still sampled
+	Window time.Duration
 }
 
-type Channel struct {
-	Display   string `json:"display"`
-	ChannelId string `json:"channelId"`
+// TargetSources are where the targets are loaded from, before the targets table and the embedded target.json
+type TargetSources struct {
+	// File is a JSON or YAML file, told apart by its extension
+	File string
+	// JSON is the targets themselves
+	JSON string
+	// ReloadInterval is how often a warm instance checks the sources for a change, 0 disables it
+	ReloadInterval time.Duration
 }
 
+type Channels struct {
+	// RefreshInterval is how long the metadata of a channel is kept before it is fetched again
+	RefreshInterval time.Duration
+}
+
+// NewConfig loads the env, the secrets from the provider of SECRETS_PROVIDER,
+// and the targets from the first of TARGET_FILE, TARGET_JSON and the embedded target.json.
+// The targets table joins the chain once a store is attached by UseTargetStore.
 func NewConfig() (*Config, error) {
-	c := &Config{}
-	if err := c.loadTarget(target); err != nil {
-		return nil, fmt.Errorf("failed to load target: %w", err)
+	if err := loadDotEnv(); err != nil {
+		return nil, fmt.Errorf("failed to load .env: %w", err)
 	}
+	sp, err := secrets.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secrets provider: %w", err)
+	}
+
+	c := &Config{}
 	if err := c.loadEnv(); err != nil {
 		return nil, fmt.Errorf("failed to load env: %w", err)
 	}
+	if err := c.loadSecrets(context.Background(), sp); err != nil {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	cache := make(map[string]string)
+	if err := json.Unmarshal([]byte(handles), &cache); err != nil {
+		return nil, fmt.Errorf("failed to load handle cache: %w", err)
+	}
+	c.targets = &targetSet{cache: cache}
+	if _, err := c.ReloadTargets(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load target: %w", err)
+	}
 
 	return c, nil
 }
 
-func (c *Config) loadEnv() error {
-	err := godotenv.Load()
-	if err != nil {
+// loadDotEnv loads .env for a local run, where it is optional. A deployed function reads only its env and secrets.
+func loadDotEnv() error {
+	if os.Getenv("LOCAL_ONLY") != "true" {
+		return nil
+	}
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
 
-	c.Api.ApiKey = os.Getenv("API_KEY")
-
 	return nil
 }
 
-func (c *Config) loadTarget(j string) error {
-	// load target channels from target.json
-	t := &Target{}
-	if err := json.Unmarshal([]byte(j), t); err != nil {
+// loadSecrets reads the API key and the DSN. Missing ones are left empty, for the readiness check to report.
+func (c *Config) loadSecrets(ctx context.Context, sp secrets.Provider) error {
+	var err error
+	if c.Api.ApiKey, err = secrets.Lookup(ctx, sp, "API_KEY"); err != nil {
+		return err
+	}
+	if c.Realtime.Dsn, err = secrets.Lookup(ctx, sp, "REALTIME_DSN"); err != nil {
 		return err
 	}
-
-	c.Target = *t
 
 	return nil
 }
 
-func (c *Config) ChannelIDs() []string {
-	cids := make([]string, 0, len(c.Target.Channel))
-	for _, c := range c.Target.Channel {
-		cids = append(cids, c.ChannelId)
+func (c *Config) loadEnv() error {
+	if m := os.Getenv("MIGRATE_ON_START"); m != "" {
+		b, err := strconv.ParseBool(m)
+		if err != nil {
+			return fmt.Errorf("invalid MIGRATE_ON_START: %w", err)
+		}
+		c.Realtime.MigrateOnStart = b
+	}
+
+	c.Health.StaleThreshold = defaultStaleThreshold
+	if st := os.Getenv("SYNC_STALE_THRESHOLD"); st != "" {
+		d, err := time.ParseDuration(st)
+		if err != nil {
+			return fmt.Errorf("invalid SYNC_STALE_THRESHOLD: %w", err)
+		}
+		c.Health.StaleThreshold = d
+	}
+
+	c.FreeChat.Horizon = defaultFreeChatHorizon
+	if h := os.Getenv("FREE_CHAT_HORIZON"); h != "" {
+		d, err := time.ParseDuration(h)
+		if err != nil {
+			return fmt.Errorf("invalid FREE_CHAT_HORIZON: %w", err)
+		}
+		c.FreeChat.Horizon = d
+	}
+	c.FreeChat.Keywords = defaultFreeChatKeywords
+	if k, ok := os.LookupEnv("FREE_CHAT_KEYWORDS"); ok {
+		c.FreeChat.Keywords = splitList(k)
+	}
+
+	c.Stale.GracePeriod = defaultStaleGracePeriod
+	if g := os.Getenv("STALE_UPCOMING_GRACE_PERIOD"); g != "" {
+		d, err := time.ParseDuration(g)
+		if err != nil {
+			return fmt.Errorf("invalid STALE_UPCOMING_GRACE_PERIOD: %w", err)
+		}
+		c.Stale.GracePeriod = d
+	}
+
+	c.Reconcile.Ahead = defaultReconcileAhead
+	if a := os.Getenv("RECONCILE_AHEAD"); a != "" {
+		d, err := time.ParseDuration(a)
+		if err != nil {
+			return fmt.Errorf("invalid RECONCILE_AHEAD: %w", err)
+		}
+		c.Reconcile.Ahead = d
+	}
+
+	c.Stats = Stats{LiveInterval: defaultStatsLiveInterval, Interval: defaultStatsInterval, Window: defaultStatsWindow}
+	for key, d := range map[string]*time.Duration{
+		"STATS_LIVE_INTERVAL": &c.Stats.LiveInterval,
+		"STATS_INTERVAL":      &c.Stats.Interval,
+		"STATS_WINDOW":        &c.Stats.Window,
+	} {
+		if v := os.Getenv(key); v != "" {
+			p, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*d = p
+		}
+	}
+
+	c.Sources.File = os.Getenv("TARGET_FILE")
+	c.Sources.JSON = os.Getenv("TARGET_JSON")
+	c.Sources.ReloadInterval = defaultTargetReload
+	if r := os.Getenv("TARGET_RELOAD_INTERVAL"); r != "" {
+		d, err := time.ParseDuration(r)
+		if err != nil {
+			return fmt.Errorf("invalid TARGET_RELOAD_INTERVAL: %w", err)
+		}
+		c.Sources.ReloadInterval = d
 	}
 
-	return cids
+	c.Channels.RefreshInterval = defaultChannelsRefresh
+	if r := os.Getenv("CHANNEL_REFRESH_INTERVAL"); r != "" {
+		d, err := time.ParseDuration(r)
+		if err != nil {
+			return fmt.Errorf("invalid CHANNEL_REFRESH_INTERVAL: %w", err)
+		}
+		c.Channels.RefreshInterval = d
+	}
+
+	return nil
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
 }
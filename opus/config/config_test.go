@@ -0,0 +1,72 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/secrets"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeSecrets map[string]string
+
+func (f fakeSecrets) Secret(_ context.Context, name string) (string, error) {
+	v, ok := f[name]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return v, nil
+}
+
+type failingSecrets struct{}
+
+func (failingSecrets) Secret(_ context.Context, _ string) (string, error) {
+	return "", errors.New("permission denied")
+}
+
+func TestConfig_LoadSecrets(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		provider secrets.Provider
+		want     Config
+		wantErr  bool
+	}{
+		"both": {
+			provider: fakeSecrets{"API_KEY": "key", "REALTIME_DSN": "postgres://db"},
+			want:     Config{Api: Api{ApiKey: "key"}, Realtime: Realtime{Dsn: "postgres://db"}},
+		},
+		"missing are left empty": {
+			provider: fakeSecrets{"API_KEY": "key"},
+			want:     Config{Api: Api{ApiKey: "key"}},
+		},
+		"broken provider": {
+			provider: failingSecrets{},
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c := Config{}
+			err := c.loadSecrets(context.Background(), tt.provider)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, c)
+		})
+	}
+}
+
+func TestLoadDotEnv_Optional(t *testing.T) {
+	// the working directory of the test has no .env
+	t.Setenv("LOCAL_ONLY", "true")
+	assert.NoError(t, loadDotEnv())
+
+	t.Setenv("LOCAL_ONLY", "")
+	assert.NoError(t, loadDotEnv())
+}
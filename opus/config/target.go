@@ -0,0 +1,413 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The sources of the targets, in order of precedence
+const (
+	TargetSourceFile     = "file"
+	TargetSourceEnv      = "env"
+	TargetSourceDB       = "db"
+	TargetSourceEmbedded = "embedded"
+)
+
+// TargetStore reads the targets table. An empty table falls through to the embedded target.json.
+type TargetStore interface {
+	LoadTargets(ctx context.Context) ([]Channel, error)
+}
+
+type Target struct {
+	Channel []Channel `json:"channel" yaml:"channel"`
+}
+
+// Discovery is how the new videos of a channel are found.
+type Discovery string
+
+const (
+	// DiscoveryRSS polls the RSS feed of the channel, which is the default
+	DiscoveryRSS Discovery = "rss"
+	// DiscoveryNone keeps the channel refreshed but never looks for its videos
+	DiscoveryNone Discovery = "none"
+)
+
+// Channel is a target channel, given by exactly one of its ID, its @handle or its URL.
+// A handle, or a URL of one, is resolved to ChannelId when the config is loaded.
+type Channel struct {
+	Display   string `json:"display" yaml:"display"`
+	ChannelId string `json:"channelId,omitempty" yaml:"channelId,omitempty"`
+	Handle    string `json:"handle,omitempty" yaml:"handle,omitempty"`
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+	// Enabled is true when omitted. A disabled channel stays in the config, but is neither synced nor refreshed.
+	Enabled   *bool     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Discovery Discovery `json:"discovery,omitempty" yaml:"discovery,omitempty"`
+	Filters   *Filters  `json:"filters,omitempty" yaml:"filters,omitempty"`
+}
+
+func (ch Channel) IsEnabled() bool {
+	return ch.Enabled == nil || *ch.Enabled
+}
+
+func (ch Channel) DiscoveredByRSS() bool {
+	return ch.Discovery == "" || ch.Discovery == DiscoveryRSS
+}
+
+// Filters drop videos of a channel. IgnoreSourceIDs are dropped before their details are fetched,
+// and the other rules are evaluated on the details.
+type Filters struct {
+	// IncludeTitle keeps only the videos whose title matches one of the patterns
+	IncludeTitle []string `json:"includeTitle,omitempty" yaml:"includeTitle,omitempty"`
+	// ExcludeTitle drops the videos whose title matches one of the patterns
+	ExcludeTitle []string `json:"excludeTitle,omitempty" yaml:"excludeTitle,omitempty"`
+	// IncludeKind keeps only the videos of one of the kinds, such as live and premiere
+	IncludeKind []kind.Kind `json:"includeKind,omitempty" yaml:"includeKind,omitempty"`
+	// ExcludeKind drops the videos of one of the kinds, such as short
+	ExcludeKind []kind.Kind `json:"excludeKind,omitempty" yaml:"excludeKind,omitempty"`
+	// MinDuration drops the videos shorter than it, such as 10m.
+	// Broadcasts that are upcoming or live have no duration yet, and are kept.
+	MinDuration string `json:"minDuration,omitempty" yaml:"minDuration,omitempty"`
+	// IgnoreSourceIDs drops the videos of the IDs
+	IgnoreSourceIDs []string `json:"ignoreSourceIds,omitempty" yaml:"ignoreSourceIds,omitempty"`
+
+	include     []*regexp.Regexp
+	exclude     []*regexp.Regexp
+	minDuration time.Duration
+}
+
+// Candidate is what the filters know of a video once its details are fetched.
+type Candidate struct {
+	SourceID string
+	Title    string
+	Kind     kind.Kind
+	// Duration is 0 when it is not known
+	Duration time.Duration
+}
+
+func (f *Filters) compile() error {
+	var errs []error
+	compile := func(patterns []string) []*regexp.Regexp {
+		res := make([]*regexp.Regexp, 0, len(patterns))
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid title pattern %q: %w", p, err))
+				continue
+			}
+			res = append(res, re)
+		}
+		return res
+	}
+	f.include = compile(f.IncludeTitle)
+	f.exclude = compile(f.ExcludeTitle)
+
+	for _, k := range slices.Concat(f.IncludeKind, f.ExcludeKind) {
+		if _, err := kind.Parse(string(k)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if f.MinDuration != "" {
+		d, err := time.ParseDuration(f.MinDuration)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid minDuration: %w", err))
+		}
+		f.minDuration = d
+	}
+
+	return errors.Join(errs...)
+}
+
+// Ignores reports whether sourceID is in the ignore list. A nil Filters ignores nothing.
+func (f *Filters) Ignores(sourceID string) bool {
+	return f != nil && slices.Contains(f.IgnoreSourceIDs, sourceID)
+}
+
+// Keep reports whether the video passes every rule, and the rule that dropped it if it does not.
+// A nil Filters keeps every video.
+func (f *Filters) Keep(c Candidate) (bool, string) {
+	if f == nil {
+		return true, ""
+	}
+	if f.Ignores(c.SourceID) {
+		return false, "ignoreSourceIds"
+	}
+	if keep, rule := f.KeepTitle(c.Title); !keep {
+		return false, rule
+	}
+	if slices.Contains(f.ExcludeKind, c.Kind) {
+		return false, "excludeKind " + string(c.Kind)
+	}
+	if len(f.IncludeKind) > 0 && !slices.Contains(f.IncludeKind, c.Kind) {
+		return false, "includeKind"
+	}
+	if f.minDuration > 0 && c.Duration > 0 && c.Duration < f.minDuration {
+		return false, "minDuration " + f.MinDuration
+	}
+
+	return true, ""
+}
+
+// Fingerprint identifies the rules, so that the videos they dropped are evaluated again once they change.
+func (f *Filters) Fingerprint() string {
+	if f == nil {
+		return ""
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeepTitle reports whether a video titled title passes the filters, and the rule that dropped it if it does not.
+// A nil Filters keeps every video.
+func (f *Filters) KeepTitle(title string) (bool, string) {
+	if f == nil {
+		return true, ""
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(title) {
+			return false, "excludeTitle " + re.String()
+		}
+	}
+	if len(f.include) == 0 {
+		return true, ""
+	}
+	for _, re := range f.include {
+		if re.MatchString(title) {
+			return true, ""
+		}
+	}
+
+	return false, "includeTitle"
+}
+
+// ParseTarget reads targets written in format, which is json or yaml, and checks every entry.
+func ParseTarget(raw []byte, format string) (*Target, error) {
+	t := &Target{}
+	var err error
+	switch format {
+	case "yaml":
+		err = yaml.Unmarshal(raw, t)
+	case "json":
+		err = json.Unmarshal(raw, t)
+	default:
+		return nil, fmt.Errorf("unknown target format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// every broken entry is reported at once, instead of one per deployment
+	var errs []error
+	for i := range t.Channel {
+		if err := t.Channel[i].normalize(); err != nil {
+			errs = append(errs, fmt.Errorf("channel %d (%q): %w", i, t.Channel[i].Display, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return t, nil
+}
+
+// TargetFormat tells the format of a target file from its extension.
+func TargetFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return "json"
+	}
+}
+
+// targetSet is the current targets, which every copy of a Config shares so that a reload reaches the services.
+type targetSet struct {
+	mu     sync.RWMutex
+	target Target
+	source string
+	// raw is what target was parsed from, to tell whether a source changed
+	raw      string
+	store    TargetStore
+	resolver HandleResolver
+	cache    map[string]string
+}
+
+// read returns the targets of the first source in the chain that has them.
+func (ts *targetSet) read(ctx context.Context, src TargetSources) (raw []byte, format, source string, err error) {
+	if src.File != "" {
+		b, err := os.ReadFile(src.File)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return b, TargetFormat(src.File), TargetSourceFile, nil
+	}
+	if src.JSON != "" {
+		return []byte(src.JSON), "json", TargetSourceEnv, nil
+	}
+
+	ts.mu.RLock()
+	store := ts.store
+	ts.mu.RUnlock()
+	if store != nil {
+		chs, err := store.LoadTargets(ctx)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to load the targets table: %w", err)
+		}
+		if len(chs) > 0 {
+			b, err := json.Marshal(Target{Channel: chs})
+			if err != nil {
+				return nil, "", "", err
+			}
+			return b, "json", TargetSourceDB, nil
+		}
+	}
+
+	return []byte(target), "json", TargetSourceEmbedded, nil
+}
+
+func (c *Config) current() Target {
+	if c.targets == nil {
+		return Target{}
+	}
+	c.targets.mu.RLock()
+	defer c.targets.mu.RUnlock()
+
+	return c.targets.target
+}
+
+// SetTarget replaces the targets, for the tools and the tests that do not load them from the chain.
+func (c *Config) SetTarget(t Target) {
+	if c.targets == nil {
+		c.targets = &targetSet{}
+	}
+	c.targets.mu.Lock()
+	defer c.targets.mu.Unlock()
+	c.targets.target = t
+}
+
+// Targets returns the enabled target channels.
+func (c *Config) Targets() []Channel {
+	chs := make([]Channel, 0)
+	for _, ch := range c.current().Channel {
+		if ch.IsEnabled() {
+			chs = append(chs, ch)
+		}
+	}
+
+	return chs
+}
+
+// ChannelIDs returns the IDs of the enabled channels whose videos are found by RSS.
+func (c *Config) ChannelIDs() []string {
+	cids := make([]string, 0)
+	for _, ch := range c.Targets() {
+		if ch.DiscoveredByRSS() {
+			cids = append(cids, ch.ChannelId)
+		}
+	}
+
+	return cids
+}
+
+// TargetChannel returns the enabled target channel of id.
+func (c *Config) TargetChannel(id string) (Channel, bool) {
+	chs := c.Targets()
+	i := slices.IndexFunc(chs, func(ch Channel) bool { return ch.ChannelId == id })
+	if i < 0 {
+		return Channel{}, false
+	}
+
+	return chs[i], true
+}
+
+// TargetSource returns the source the current targets were loaded from.
+func (c *Config) TargetSource() string {
+	if c.targets == nil {
+		return ""
+	}
+	c.targets.mu.RLock()
+	defer c.targets.mu.RUnlock()
+
+	return c.targets.source
+}
+
+// UseTargetStore adds the targets table to the chain. It takes effect at the next reload.
+func (c *Config) UseTargetStore(s TargetStore) {
+	if c.targets == nil {
+		c.targets = &targetSet{}
+	}
+	c.targets.mu.Lock()
+	defer c.targets.mu.Unlock()
+	c.targets.store = s
+}
+
+// ReloadTargets loads the targets from the chain again, and reports whether they changed.
+// Broken targets are reported as an error, and the current ones are kept.
+func (c *Config) ReloadTargets(ctx context.Context) (bool, error) {
+	if c.targets == nil {
+		c.targets = &targetSet{}
+	}
+	ts := c.targets
+
+	raw, format, source, err := ts.read(ctx, c.Sources)
+	if err != nil {
+		return false, err
+	}
+	ts.mu.RLock()
+	same := source == ts.source && string(raw) == ts.raw
+	resolver := ts.resolver
+	ts.mu.RUnlock()
+	if same {
+		return false, nil
+	}
+
+	t, err := ParseTarget(raw, format)
+	if err != nil {
+		return false, fmt.Errorf("%s target: %w", source, err)
+	}
+	// the cache grows while the handles of another reload are resolved
+	ts.mu.RLock()
+	t.applyHandleCache(ts.cache)
+	ts.mu.RUnlock()
+	if resolver != nil {
+		resolved, err := t.resolveHandles(ctx, resolver)
+		if err != nil {
+			return false, fmt.Errorf("%s target: %w", source, err)
+		}
+		ts.addToCache(resolved)
+	}
+
+	ts.mu.Lock()
+	ts.target, ts.source, ts.raw = *t, source, string(raw)
+	ts.mu.Unlock()
+
+	return true, nil
+}
+
+func (ts *targetSet) addToCache(resolved map[string]string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.cache == nil {
+		ts.cache = make(map[string]string)
+	}
+	for h, id := range resolved {
+		ts.cache[h] = id
+	}
+}
@@ -0,0 +1,274 @@
+package config
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTargetStore is the targets table
+type fakeTargetStore struct {
+	channels []Channel
+}
+
+func (f *fakeTargetStore) LoadTargets(_ context.Context) ([]Channel, error) {
+	return f.channels, nil
+}
+
+func TestConfig_ReloadTargets(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "target.yaml")
+	if err := os.WriteFile(yamlFile, []byte("channel:\n  - display: file\n    channelId: "+subID+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := &fakeTargetStore{channels: []Channel{{Display: "db", ChannelId: mainID}}}
+
+	tests := map[string]struct {
+		sources    TargetSources
+		store      TargetStore
+		wantSource string
+		wantIDs    []string
+	}{
+		"file takes precedence": {
+			sources:    TargetSources{File: yamlFile, JSON: `{"channel": [{"display": "env", "channelId": "` + mainID + `"}]}`},
+			store:      store,
+			wantSource: TargetSourceFile,
+			wantIDs:    []string{subID},
+		},
+		"env before the table": {
+			sources:    TargetSources{JSON: `{"channel": [{"display": "env", "channelId": "` + subID + `"}]}`},
+			store:      store,
+			wantSource: TargetSourceEnv,
+			wantIDs:    []string{subID},
+		},
+		"table before the embedded default": {
+			store:      store,
+			wantSource: TargetSourceDB,
+			wantIDs:    []string{mainID},
+		},
+		"empty table falls through": {
+			store:      &fakeTargetStore{},
+			wantSource: TargetSourceEmbedded,
+			wantIDs:    []string{mainID, subID},
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c := &Config{Sources: tt.sources}
+			c.UseTargetStore(tt.store)
+
+			changed, err := c.ReloadTargets(ctx)
+			assert.NoError(t, err)
+			assert.True(t, changed)
+			assert.Equal(t, tt.wantSource, c.TargetSource())
+			assert.Equal(t, tt.wantIDs, c.ChannelIDs())
+		})
+	}
+}
+
+func TestConfig_ReloadTargetsReachesCopies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "target.json")
+	write := func(body string) {
+		if err := os.WriteFile(file, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"channel": [{"display": "main", "channelId": "` + mainID + `"}]}`)
+
+	c := &Config{Sources: TargetSources{File: file}}
+	_, err := c.ReloadTargets(ctx)
+	assert.NoError(t, err)
+	// a service keeps a copy of the config
+	copied := *c
+
+	changed, err := c.ReloadTargets(ctx)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	write(`{"channel": [
+		{"display": "main", "channelId": "` + mainID + `", "enabled": false},
+		{"display": "sub", "channelId": "` + subID + `", "discovery": "none"}
+	]}`)
+	changed, err = c.ReloadTargets(ctx)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{}, copied.ChannelIDs())
+	if chs := copied.Targets(); assert.Len(t, chs, 1) {
+		assert.Equal(t, "sub", chs[0].Display)
+	}
+
+	// broken targets keep the current ones
+	write(`{"channel": [{"display": "main", "channelId": "UCtypo"}]}`)
+	_, err = c.ReloadTargets(ctx)
+	assert.Error(t, err)
+	assert.Len(t, copied.Targets(), 1)
+}
+
+func TestConfig_ReloadTargets_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "target.json")
+	bodies := []string{
+		`{"channel": [{"display": "main", "channelId": "` + mainID + `"}]}`,
+		`{"channel": [{"display": "main", "channelId": "` + mainID + `"}, {"display": "sub", "channelId": "` + subID + `"}]}`,
+	}
+	if err := os.WriteFile(file, []byte(bodies[0]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{Sources: TargetSources{File: file}}
+	if _, err := c.ReloadTargets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	copied := *c
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 50; i++ {
+			if err := os.WriteFile(file, []byte(bodies[i%2]), 0o644); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := c.ReloadTargets(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	// a service reads whole targets while they are reloaded
+	for i := 0; i < 50; i++ {
+		n := len(copied.ChannelIDs())
+		assert.True(t, n == 1 || n == 2, n)
+		assert.Equal(t, TargetSourceFile, copied.TargetSource())
+	}
+	wg.Wait()
+}
+
+func TestFilters_KeepTitle(t *testing.T) {
+	t.Parallel()
+
+	target, err := ParseTarget([]byte(`
+channel:
+  - display: sub
+    channelId: `+subID+`
+    filters:
+      includeTitle: ["(?i)stream", "配信"]
+      excludeTitle: ["#shorts"]
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := target.Channel[0].Filters
+
+	tests := map[string]struct {
+		title    string
+		want     bool
+		wantRule string
+	}{
+		"included":          {title: "Morning Stream", want: true},
+		"included in kanji": {title: "雑談配信", want: true},
+		"not included":      {title: "Clip", want: false, wantRule: "includeTitle"},
+		"excluded":          {title: "stream highlight #shorts", want: false, wantRule: "excludeTitle #shorts"},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, rule := f.KeepTitle(tt.title)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantRule, rule)
+		})
+	}
+
+	var none *Filters
+	got, _ := none.KeepTitle("anything")
+	assert.True(t, got)
+
+	_, err = ParseTarget([]byte(`{"channel": [{"display": "x", "channelId": "`+subID+`", "filters": {"excludeTitle": ["("]}, "discovery": "api"}]}`), "json")
+	assert.Error(t, err)
+}
+
+func TestFilters_Keep(t *testing.T) {
+	t.Parallel()
+
+	target, err := ParseTarget([]byte(`
+channel:
+  - display: sub
+    channelId: `+subID+`
+    filters:
+      excludeTitle: ["切り抜き"]
+      includeKind: [live, premiere, upload]
+      excludeKind: [upload]
+      minDuration: 10m
+      ignoreSourceIds: [ignoredID]
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := target.Channel[0].Filters
+
+	tests := map[string]struct {
+		candidate Candidate
+		want      bool
+		wantRule  string
+	}{
+		"stream":               {candidate: Candidate{SourceID: "id", Title: "Stream", Kind: kind.Live, Duration: time.Hour}, want: true},
+		"upcoming stream":      {candidate: Candidate{SourceID: "id", Title: "Stream", Kind: kind.Live}, want: true},
+		"ignored":              {candidate: Candidate{SourceID: "ignoredID", Title: "Stream", Kind: kind.Live}, wantRule: "ignoreSourceIds"},
+		"excluded title":       {candidate: Candidate{SourceID: "id", Title: "切り抜き", Kind: kind.Live}, wantRule: "excludeTitle 切り抜き"},
+		"excluded kind":        {candidate: Candidate{SourceID: "id", Title: "Clip", Kind: kind.Upload}, wantRule: "excludeKind upload"},
+		"not included kind":    {candidate: Candidate{SourceID: "id", Title: "Clip", Kind: kind.Short}, wantRule: "includeKind"},
+		"shorter than minimum": {candidate: Candidate{SourceID: "id", Title: "Premiere", Kind: kind.Premiere, Duration: 5 * time.Minute}, wantRule: "minDuration 10m"},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, rule := f.Keep(tt.candidate)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantRule, rule)
+		})
+	}
+
+	var none *Filters
+	got, _ := none.Keep(Candidate{SourceID: "ignoredID", Kind: kind.Short})
+	assert.True(t, got)
+	assert.False(t, none.Ignores("ignoredID"))
+	assert.True(t, f.Ignores("ignoredID"))
+
+	for _, broken := range []string{`{"includeKind": ["clip"]}`, `{"minDuration": "ten minutes"}`} {
+		_, err = ParseTarget([]byte(`{"channel": [{"display": "x", "channelId": "`+subID+`", "filters": `+broken+`}]}`), "json")
+		assert.Error(t, err, broken)
+	}
+}
+
+func TestFilters_Fingerprint(t *testing.T) {
+	t.Parallel()
+
+	a := &Filters{ExcludeKind: []kind.Kind{kind.Short}}
+	b := &Filters{ExcludeKind: []kind.Kind{kind.Short}}
+	c := &Filters{ExcludeKind: []kind.Kind{kind.Short}, MinDuration: "10m"}
+	var none *Filters
+
+	assert.Equal(t, a.Fingerprint(), b.Fingerprint())
+	assert.NotEqual(t, a.Fingerprint(), c.Fingerprint())
+	assert.Empty(t, none.Fingerprint())
+}
@@ -6,6 +6,10 @@ import (
 )
 
 type ApiRepository interface {
+	// FetchVideoDetailsByVideoIDs returns every extracted video, together with an *ExtractionError
+	// listing the videos the API returned but that could not be extracted if there are any.
 	FetchVideoDetailsByVideoIDs(ctx context.Context, videoIDs []string) ([]dto.DetailResponse, error)
 	FetchScheduledAtByVideoIDs(ctx context.Context, videoIDs []string) ([]dto.ScheduleResponse, error)
+	FetchStatisticsByVideoIDs(ctx context.Context, videoIDs []string) ([]dto.StatsResponse, error)
+	FetchChannelsByIDs(ctx context.Context, channelIDs []string) ([]dto.ChannelResponse, error)
 }
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"google.golang.org/api/youtube/v3"
+)
+
+func (c *YouTubeVideo) FetchChannelsByIDs(ctx context.Context, channelIDs []string) ([]dto.ChannelResponse, error) {
+	if len(channelIDs) == 0 {
+		return []dto.ChannelResponse{}, nil
+	}
+
+	chs := make([]dto.ChannelResponse, 0, len(channelIDs))
+
+	// channels.list accepts as many IDs per call as videos.list does
+	for _, ids := range chunkVideoIDs(channelIDs) {
+		resp, err := c.clt.ChannelList(ctx, []string{PartSnippet, PartStatistics, PartContentDetails}, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range resp.Items {
+			ch, err := extractChannel(i)
+			if err != nil {
+				logging.FromContext(ctx).Error(
+					"failed to extract channel",
+					"channelID", i.Id,
+					"error", err,
+				)
+
+				// skip the item, because the error is not fatal
+				continue
+			}
+			chs = append(chs, *ch)
+		}
+	}
+
+	return chs, nil
+}
+
+// ResolveHandle returns the ID of the channel whose handle is handle.
+func (c *YouTubeVideo) ResolveHandle(ctx context.Context, handle string) (string, error) {
+	resp, err := c.clt.ChannelListByHandle(ctx, []string{PartID}, handle)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Items) == 0 {
+		return "", fmt.Errorf("no channel has the handle %s", handle)
+	}
+
+	return resp.Items[0].Id, nil
+}
+
+func extractChannel(i *youtube.Channel) (*dto.ChannelResponse, error) {
+	if i.Snippet == nil {
+		return nil, fmt.Errorf("snippet is not found for channelID: %s", i.Id)
+	}
+
+	ch := &dto.ChannelResponse{
+		Id:        i.Id,
+		Title:     i.Snippet.Title,
+		Handle:    i.Snippet.CustomUrl,
+		AvatarURL: avatarURL(i.Snippet.Thumbnails),
+	}
+	if s := i.Statistics; s != nil && !s.HiddenSubscriberCount {
+		v := int64(s.SubscriberCount)
+		ch.SubscriberCount = &v
+	}
+	if d := i.ContentDetails; d != nil && d.RelatedPlaylists != nil {
+		ch.UploadsPlaylistID = d.RelatedPlaylists.Uploads
+	}
+
+	return ch, nil
+}
+
+// avatarURL picks the largest thumbnail a channel has
+func avatarURL(t *youtube.ThumbnailDetails) string {
+	if t == nil {
+		return ""
+	}
+	for _, th := range []*youtube.Thumbnail{t.High, t.Medium, t.Default} {
+		if th != nil && th.Url != "" {
+			return th.Url
+		}
+	}
+
+	return ""
+}
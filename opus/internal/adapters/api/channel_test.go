@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	mocks "github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/youtube/mock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/api/youtube/v3"
+	"testing"
+)
+
+func TestYouTubeVideo_FetchChannelsByIDs(t *testing.T) {
+	t.Parallel()
+
+	subscribers := int64(250000)
+
+	tests := map[string]struct {
+		channelIDs []string
+		mockSetup  func(*mocks.MockClient)
+		want       []dto.ChannelResponse
+		wantErr    bool
+	}{
+		"channel with every part": {
+			channelIDs: []string{"channelID"},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().
+					ChannelList(gomock.Any(), gomock.Eq([]string{"snippet", "statistics", "contentDetails"}), gomock.Eq([]string{"channelID"})).
+					Return(&youtube.ChannelListResponse{
+						Items: []*youtube.Channel{
+							{
+								Id: "channelID",
+								Snippet: &youtube.ChannelSnippet{
+									Title:     "title",
+									CustomUrl: "@handle",
+									Thumbnails: &youtube.ThumbnailDetails{
+										Default: &youtube.Thumbnail{Url: "https://example.com/default.jpg"},
+										High:    &youtube.Thumbnail{Url: "https://example.com/high.jpg"},
+									},
+								},
+								Statistics: &youtube.ChannelStatistics{SubscriberCount: 250000},
+								ContentDetails: &youtube.ChannelContentDetails{
+									RelatedPlaylists: &youtube.ChannelContentDetailsRelatedPlaylists{Uploads: "UUchannelID"},
+								},
+							},
+						},
+					}, nil)
+			},
+			want: []dto.ChannelResponse{
+				{
+					Id:                "channelID",
+					Title:             "title",
+					Handle:            "@handle",
+					AvatarURL:         "https://example.com/high.jpg",
+					SubscriberCount:   &subscribers,
+					UploadsPlaylistID: "UUchannelID",
+				},
+			},
+		},
+		"hidden subscriber count and no thumbnail": {
+			channelIDs: []string{"channelID"},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().
+					ChannelList(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(&youtube.ChannelListResponse{
+						Items: []*youtube.Channel{
+							{
+								Id:         "channelID",
+								Snippet:    &youtube.ChannelSnippet{Title: "title"},
+								Statistics: &youtube.ChannelStatistics{SubscriberCount: 100, HiddenSubscriberCount: true},
+							},
+						},
+					}, nil)
+			},
+			want: []dto.ChannelResponse{
+				{Id: "channelID", Title: "title"},
+			},
+		},
+		"channel without snippet is skipped": {
+			channelIDs: []string{"channelID"},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().
+					ChannelList(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(&youtube.ChannelListResponse{Items: []*youtube.Channel{{Id: "channelID"}}}, nil)
+			},
+			want: []dto.ChannelResponse{},
+		},
+		"no channel IDs": {
+			channelIDs: []string{},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().ChannelList(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			want: []dto.ChannelResponse{},
+		},
+		"api call failed": {
+			channelIDs: []string{"channelID"},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().ChannelList(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClient := mocks.NewMockClient(ctrl)
+			tt.mockSetup(mockClient)
+
+			c := &YouTubeVideo{
+				clt: mockClient,
+			}
+
+			// Act
+			got, err := c.FetchChannelsByIDs(context.Background(), tt.channelIDs)
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("unexpected response: %v", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestYouTubeVideo_ResolveHandle(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		mockSetup func(*mocks.MockClient)
+		want      string
+		wantErr   bool
+	}{
+		"found": {
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().
+					ChannelListByHandle(gomock.Any(), gomock.Eq([]string{"id"}), gomock.Eq("@handle")).
+					Return(&youtube.ChannelListResponse{Items: []*youtube.Channel{{Id: "channelID"}}}, nil)
+			},
+			want: "channelID",
+		},
+		"not found": {
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().
+					ChannelListByHandle(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(&youtube.ChannelListResponse{}, nil)
+			},
+			wantErr: true,
+		},
+		"api call failed": {
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().ChannelListByHandle(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClient := mocks.NewMockClient(ctrl)
+			tt.mockSetup(mockClient)
+
+			c := &YouTubeVideo{
+				clt: mockClient,
+			}
+
+			// Act
+			got, err := c.ResolveHandle(context.Background(), "@handle")
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -3,12 +3,29 @@ package api
 import (
 	"context"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
+	"time"
 )
 
+const instrumentationName = "github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api"
+
+// videosListQuotaCost is the quota units a videos.list call consumes regardless of its parts
+const videosListQuotaCost = 1
+
+// channelsListQuotaCost is the quota units a channels.list call consumes regardless of its parts
+const channelsListQuotaCost = 1
+
 type Client struct {
-	svc *youtube.Service
+	svc     *youtube.Service
+	tracer  trace.Tracer
+	metrics *telemetry.APIMetrics
 }
 
 func NewYouTubeClient(c config.Config) (*Client, error) {
@@ -17,12 +34,79 @@ func NewYouTubeClient(c config.Config) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{svc: svc}, nil
+	m, err := telemetry.NewAPIMetrics(otel.Meter(instrumentationName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{svc: svc, tracer: otel.Tracer(instrumentationName), metrics: m}, nil
 }
 
 func (y *Client) VideoList(ctx context.Context, part []string, id []string) (*youtube.VideoListResponse, error) {
+	ctx, span := y.tracer.Start(ctx, "YouTube Videos.List", trace.WithAttributes(
+		attribute.StringSlice("youtube.part", part),
+		attribute.Int("youtube.id.count", len(id)),
+	))
+	defer span.End()
+
 	call := y.svc.Videos.List(part).Id(id...)
 	call = call.Context(ctx)
 
-	return call.Do()
+	start := time.Now()
+	resp, err := call.Do()
+	y.metrics.Record(ctx, "Videos.List", start, err)
+	run.FromContext(ctx).AddAPICall(videosListQuotaCost)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, telemetry.ErrorReason(err))
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (y *Client) ChannelList(ctx context.Context, part []string, id []string) (*youtube.ChannelListResponse, error) {
+	ctx, span := y.tracer.Start(ctx, "YouTube Channels.List", trace.WithAttributes(
+		attribute.StringSlice("youtube.part", part),
+		attribute.Int("youtube.id.count", len(id)),
+	))
+	defer span.End()
+
+	call := y.svc.Channels.List(part).Id(id...)
+	call = call.Context(ctx)
+
+	start := time.Now()
+	resp, err := call.Do()
+	y.metrics.Record(ctx, "Channels.List", start, err)
+	run.FromContext(ctx).AddAPICall(channelsListQuotaCost)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, telemetry.ErrorReason(err))
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (y *Client) ChannelListByHandle(ctx context.Context, part []string, handle string) (*youtube.ChannelListResponse, error) {
+	ctx, span := y.tracer.Start(ctx, "YouTube Channels.List", trace.WithAttributes(
+		attribute.StringSlice("youtube.part", part),
+		attribute.String("youtube.handle", handle),
+	))
+	defer span.End()
+
+	call := y.svc.Channels.List(part).ForHandle(handle)
+	call = call.Context(ctx)
+
+	start := time.Now()
+	resp, err := call.Do()
+	y.metrics.Record(ctx, "Channels.List", start, err)
+	run.FromContext(ctx).AddAPICall(channelsListQuotaCost)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, telemetry.ErrorReason(err))
+		return nil, err
+	}
+
+	return resp, nil
 }
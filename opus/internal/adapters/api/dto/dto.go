@@ -3,7 +3,9 @@ package dto
 import (
 	"github.com/Code-Hex/synchro"
 	"github.com/Code-Hex/synchro/tz"
-	"github.com/KasumiMercury/patotta-stone-functions-go/opus/status"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"time"
 )
 
 type DetailResponse struct {
@@ -12,12 +14,39 @@ type DetailResponse struct {
 	Title       string
 	Description string
 	Status      status.Status
+	Kind        kind.Kind
 	PublishedAt synchro.Time[tz.AsiaTokyo]
 	ScheduledAt synchro.Time[tz.AsiaTokyo]
 	ChatId      string
+	ChatStatus  status.Chat
+	// ActualStartAt and ActualEndAt are zero until the broadcast starts and ends
+	ActualStartAt synchro.Time[tz.AsiaTokyo]
+	ActualEndAt   synchro.Time[tz.AsiaTokyo]
+	// Duration is 0 until the broadcast ends, and when it is not known
+	Duration time.Duration
 }
 
 type ScheduleResponse struct {
 	Id          string
 	ScheduledAt synchro.Time[tz.AsiaTokyo]
 }
+
+type StatsResponse struct {
+	Id           string
+	ViewCount    int64
+	LikeCount    int64
+	CommentCount int64
+	// ConcurrentViewers is nil unless the video is live
+	ConcurrentViewers *int64
+}
+
+type ChannelResponse struct {
+	Id     string
+	Title  string
+	Handle string
+	// AvatarURL is empty if the channel has no thumbnail
+	AvatarURL string
+	// SubscriberCount is nil if the channel hides it
+	SubscriberCount   *int64
+	UploadsPlaylistID string
+}
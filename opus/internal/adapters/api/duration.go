@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDuration parses an ISO 8601 duration as returned in contentDetails.duration, such as PT1H2M3S, P1DT2H and P0D.
+// Years and months are rejected because their length is not fixed, and YouTube does not use them.
+func parseDuration(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(s, "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("invalid duration: %q", s)
+	}
+
+	date, clock, hasTime := strings.Cut(rest, "T")
+	if hasTime && clock == "" {
+		return 0, fmt.Errorf("invalid duration: %q", s)
+	}
+
+	d, err := sumDuration(date, map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour})
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %q: %w", s, err)
+	}
+	c, err := sumDuration(clock, map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second})
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %q: %w", s, err)
+	}
+
+	return d + c, nil
+}
+
+// sumDuration adds up the designated numbers in s, such as 1H2M, by the units of their designators
+func sumDuration(s string, units map[byte]time.Duration) (time.Duration, error) {
+	var total time.Duration
+	seen := make(map[byte]bool, len(units))
+
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			return 0, fmt.Errorf("number expected in %q", s)
+		}
+		unit, ok := units[s[i]]
+		if !ok || seen[s[i]] {
+			return 0, fmt.Errorf("unexpected designator %q", s[i])
+		}
+		seen[s[i]] = true
+
+		n, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		total += time.Duration(n) * unit
+		s = s[i+1:]
+	}
+
+	return total, nil
+}
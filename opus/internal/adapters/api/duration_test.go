@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		"live stream":       {in: "P0D", want: 0},
+		"seconds":           {in: "PT59S", want: 59 * time.Second},
+		"minutes":           {in: "PT3M", want: 3 * time.Minute},
+		"hours":             {in: "PT1H2M3S", want: time.Hour + 2*time.Minute + 3*time.Second},
+		"days":              {in: "P1DT2H", want: 26 * time.Hour},
+		"weeks":             {in: "P1W", want: 7 * 24 * time.Hour},
+		"empty":             {in: "", wantErr: true},
+		"no designator":     {in: "P", wantErr: true},
+		"time without unit": {in: "PT", wantErr: true},
+		"months":            {in: "P1M", wantErr: true},
+		"years":             {in: "P1Y", wantErr: true},
+		"missing number":    {in: "PTH", wantErr: true},
+		"duplicated unit":   {in: "PT1M2M", wantErr: true},
+		"trailing number":   {in: "PT12", wantErr: true},
+		"fraction":          {in: "PT1.5S", wantErr: true},
+		"not a duration":    {in: "1:02:03", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseDuration(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
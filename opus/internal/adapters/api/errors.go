@@ -0,0 +1,26 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExtractionError reports items the API returned but that could not be extracted.
+// The fetch that returns it still returns every extracted item, so a caller can tell them from the items the API did not return.
+type ExtractionError struct {
+	SourceIDs []string
+	Errs      []error
+}
+
+func (e *ExtractionError) Error() string {
+	return fmt.Sprintf("failed to extract %d items: %s", len(e.SourceIDs), strings.Join(e.SourceIDs, ", "))
+}
+
+func (e *ExtractionError) Unwrap() []error {
+	return e.Errs
+}
+
+func (e *ExtractionError) add(sourceID string, err error) {
+	e.SourceIDs = append(e.SourceIDs, sourceID)
+	e.Errs = append(e.Errs, err)
+}
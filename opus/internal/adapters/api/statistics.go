@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"google.golang.org/api/youtube/v3"
+)
+
+func (c *YouTubeVideo) FetchStatisticsByVideoIDs(ctx context.Context, videoIDs []string) ([]dto.StatsResponse, error) {
+	if len(videoIDs) == 0 {
+		return []dto.StatsResponse{}, nil
+	}
+
+	sts := make([]dto.StatsResponse, 0, len(videoIDs))
+
+	for _, ids := range chunkVideoIDs(videoIDs) {
+		resp, err := c.clt.VideoList(ctx, []string{PartStatistics, PartLiveStreamingDetails}, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range resp.Items {
+			st, err := extractStatistics(i)
+			if err != nil {
+				logging.FromContext(ctx).Error(
+					"failed to extract statistics",
+					"sourceID", i.Id,
+					"error", err,
+				)
+
+				// skip the item, because the error is not fatal
+				continue
+			}
+			sts = append(sts, *st)
+		}
+	}
+
+	return sts, nil
+}
+
+func extractStatistics(i *youtube.Video) (*dto.StatsResponse, error) {
+	if i.Statistics == nil {
+		return nil, fmt.Errorf("statistics is not found for sourceID: %s", i.Id)
+	}
+
+	st := &dto.StatsResponse{
+		Id:           i.Id,
+		ViewCount:    int64(i.Statistics.ViewCount),
+		LikeCount:    int64(i.Statistics.LikeCount),
+		CommentCount: int64(i.Statistics.CommentCount),
+	}
+	// concurrentViewers is only returned while the broadcast is on air
+	if d := i.LiveStreamingDetails; d != nil && d.ActualStartTime != "" && d.ActualEndTime == "" {
+		v := int64(d.ConcurrentViewers)
+		st.ConcurrentViewers = &v
+	}
+
+	return st, nil
+}
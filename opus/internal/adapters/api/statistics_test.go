@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	mocks "github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/youtube/mock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/api/youtube/v3"
+	"testing"
+)
+
+func TestYouTubeVideo_FetchStatisticsByVideoIDs(t *testing.T) {
+	t.Parallel()
+
+	viewers := int64(1200)
+
+	tests := map[string]struct {
+		videoIDs  []string
+		mockSetup func(*mocks.MockClient)
+		want      []dto.StatsResponse
+		wantErr   bool
+	}{
+		"live video": {
+			videoIDs: []string{"videoID"},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().
+					VideoList(gomock.Any(), gomock.Eq([]string{"statistics", "liveStreamingDetails"}), gomock.Eq([]string{"videoID"})).
+					Return(&youtube.VideoListResponse{
+						Items: []*youtube.Video{
+							{
+								Id:         "videoID",
+								Statistics: &youtube.VideoStatistics{ViewCount: 3000, LikeCount: 200, CommentCount: 10},
+								LiveStreamingDetails: &youtube.VideoLiveStreamingDetails{
+									ActualStartTime:   "2024-01-01T00:00:00Z",
+									ConcurrentViewers: 1200,
+								},
+							},
+						},
+					}, nil)
+			},
+			want: []dto.StatsResponse{
+				{Id: "videoID", ViewCount: 3000, LikeCount: 200, CommentCount: 10, ConcurrentViewers: &viewers},
+			},
+		},
+		"archived and uploaded videos": {
+			videoIDs: []string{"archived", "upload"},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().
+					VideoList(gomock.Any(), gomock.Eq([]string{"statistics", "liveStreamingDetails"}), gomock.Eq([]string{"archived", "upload"})).
+					Return(&youtube.VideoListResponse{
+						Items: []*youtube.Video{
+							{
+								Id:         "archived",
+								Statistics: &youtube.VideoStatistics{ViewCount: 5000},
+								LiveStreamingDetails: &youtube.VideoLiveStreamingDetails{
+									ActualStartTime: "2024-01-01T00:00:00Z",
+									ActualEndTime:   "2024-01-01T02:00:00Z",
+								},
+							},
+							{
+								Id:         "upload",
+								Statistics: &youtube.VideoStatistics{ViewCount: 100, LikeCount: 5, CommentCount: 1},
+							},
+						},
+					}, nil)
+			},
+			want: []dto.StatsResponse{
+				{Id: "archived", ViewCount: 5000},
+				{Id: "upload", ViewCount: 100, LikeCount: 5, CommentCount: 1},
+			},
+		},
+		"video without statistics is skipped": {
+			videoIDs: []string{"videoID"},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().
+					VideoList(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(&youtube.VideoListResponse{Items: []*youtube.Video{{Id: "videoID"}}}, nil)
+			},
+			want: []dto.StatsResponse{},
+		},
+		"no video IDs": {
+			videoIDs: []string{},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().VideoList(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			want: []dto.StatsResponse{},
+		},
+		"api call failed": {
+			videoIDs: []string{"videoID"},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().VideoList(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClient := mocks.NewMockClient(ctrl)
+			tt.mockSetup(mockClient)
+
+			c := &YouTubeVideo{
+				clt: mockClient,
+			}
+
+			// Act
+			got, err := c.FetchStatisticsByVideoIDs(context.Background(), tt.videoIDs)
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("unexpected response: %v", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
@@ -7,15 +7,20 @@ import (
 	"github.com/Code-Hex/synchro/tz"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
 	repo "github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/youtube"
-	"github.com/KasumiMercury/patotta-stone-functions-go/opus/status"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 	"google.golang.org/api/youtube/v3"
-	"log/slog"
+	"strings"
+	"time"
 )
 
 const (
+	PartID                   = "id"
 	PartSnippet              = "snippet"
 	PartContentDetails       = "contentDetails"
 	PartLiveStreamingDetails = "liveStreamingDetails"
+	PartStatistics           = "statistics"
 )
 
 const MaxVideoIDs = 50
@@ -33,16 +38,10 @@ func (c *YouTubeVideo) FetchVideoDetailsByVideoIDs(ctx context.Context, videoIDs
 		return []dto.DetailResponse{}, nil
 	}
 
-	idsSlice := make([][]string, 0, len(videoIDs)/MaxVideoIDs+1)
-	for i := 0; i < len(videoIDs); i += MaxVideoIDs {
-		end := i + MaxVideoIDs
-		if end > len(videoIDs) {
-			end = len(videoIDs)
-		}
-		idsSlice = append(idsSlice, videoIDs[i:end])
-	}
+	idsSlice := chunkVideoIDs(videoIDs)
 
 	vds := make([]dto.DetailResponse, 0, len(videoIDs))
+	failed := &ExtractionError{}
 
 	for _, ids := range idsSlice {
 		resp, err := c.clt.VideoList(ctx, []string{PartSnippet, PartContentDetails, PartLiveStreamingDetails}, ids)
@@ -53,24 +52,40 @@ func (c *YouTubeVideo) FetchVideoDetailsByVideoIDs(ctx context.Context, videoIDs
 		for _, i := range resp.Items {
 			vd, err := extractVideoItem(i)
 			if err != nil {
-				slog.Error(
+				logging.FromContext(ctx).Error(
 					"failed to extract video item",
 					"sourceID", i.Id,
 					"error", err,
 				)
 
-				// if any error occurs, skip the item
-				// and continue to the next item
-				// because the error is not fatal
+				// the item is skipped, but reported apart from the videos the API did not return
+				failed.add(i.Id, err)
 				continue
 			}
 			vds = append(vds, *vd)
 		}
 	}
 
+	if len(failed.SourceIDs) > 0 {
+		return vds, failed
+	}
 	return vds, nil
 }
 
+// chunkVideoIDs splits videoIDs into the chunks a videos.list call accepts.
+func chunkVideoIDs(videoIDs []string) [][]string {
+	idsSlice := make([][]string, 0, len(videoIDs)/MaxVideoIDs+1)
+	for i := 0; i < len(videoIDs); i += MaxVideoIDs {
+		end := i + MaxVideoIDs
+		if end > len(videoIDs) {
+			end = len(videoIDs)
+		}
+		idsSlice = append(idsSlice, videoIDs[i:end])
+	}
+
+	return idsSlice
+}
+
 func extractVideoItem(i *youtube.Video) (*dto.DetailResponse, error) {
 	if i.Snippet == nil {
 		return nil, fmt.Errorf("snippet is not found for sourceID: %s", i.Id)
@@ -86,26 +101,39 @@ func extractVideoItem(i *youtube.Video) (*dto.DetailResponse, error) {
 		return nil, err
 	}
 
+	as, ae, err := extractActualTimes(i.LiveStreamingDetails)
+	if err != nil {
+		return nil, err
+	}
+	d, _ := videoDuration(i.ContentDetails)
+
 	return &dto.DetailResponse{
-		Id:          i.Id,
-		ChannelId:   i.Snippet.ChannelId,
-		Title:       i.Snippet.Title,
-		Description: i.Snippet.Description,
-		Status:      sts,
-		PublishedAt: pa,
-		ScheduledAt: sa,
-		ChatId:      cID,
+		Id:            i.Id,
+		ChannelId:     i.Snippet.ChannelId,
+		Title:         i.Snippet.Title,
+		Description:   i.Snippet.Description,
+		Status:        sts,
+		Kind:          classifyKind(*i, sts),
+		PublishedAt:   pa,
+		ScheduledAt:   sa,
+		ChatId:        cID,
+		ChatStatus:    extractChatStatus(sts, cID, i.LiveStreamingDetails),
+		ActualStartAt: as,
+		ActualEndAt:   ae,
+		Duration:      d,
 	}, nil
 }
 
 func extractVideoStatus(i youtube.Video) (status.Status, string, synchro.Time[tz.AsiaTokyo], error) {
 	switch i.Snippet.LiveBroadcastContent {
 	case "live":
+		// the chat ID is kept during the broadcast, because Animus collects the chat while the video is live
+		cID := extractChatID(i.LiveStreamingDetails)
 		sa, err := extractScheduledAt(i.LiveStreamingDetails)
 		if err != nil {
 			return status.Live, "", synchro.Time[tz.AsiaTokyo]{}, fmt.Errorf("failed to extract ScheduledAt for live video: %w", err)
 		}
-		return status.Live, "", sa, nil
+		return status.Live, cID, sa, nil
 	case "upcoming":
 		cID := extractChatID(i.LiveStreamingDetails)
 		sa, err := extractScheduledAt(i.LiveStreamingDetails)
@@ -134,19 +162,127 @@ func extractChatID(details *youtube.VideoLiveStreamingDetails) string {
 	return details.ActiveLiveChatId
 }
 
+// extractChatStatus tells the availability of the chat. An upcoming or live broadcast without an active chat has it disabled,
+// and a broadcast that has ended has it ended. A video that is not a broadcast has no chat to tell about.
+func extractChatStatus(sts status.Status, chatID string, details *youtube.VideoLiveStreamingDetails) status.Chat {
+	if details == nil {
+		return status.ChatUnknown
+	}
+
+	switch {
+	case sts == status.Upcoming || sts == status.Live:
+		if chatID == "" {
+			return status.ChatDisabled
+		}
+		return status.ChatEnabled
+	case details.ActualEndTime != "":
+		return status.ChatEnded
+	default:
+		return status.ChatUnknown
+	}
+}
+
+// extractActualTimes returns the times the broadcast actually started and ended, which are zero until then.
+func extractActualTimes(details *youtube.VideoLiveStreamingDetails) (synchro.Time[tz.AsiaTokyo], synchro.Time[tz.AsiaTokyo], error) {
+	if details == nil {
+		return synchro.Time[tz.AsiaTokyo]{}, synchro.Time[tz.AsiaTokyo]{}, nil
+	}
+
+	var as, ae synchro.Time[tz.AsiaTokyo]
+	var err error
+	if details.ActualStartTime != "" {
+		as, err = synchro.ParseISO[tz.AsiaTokyo](details.ActualStartTime)
+		if err != nil {
+			return as, ae, fmt.Errorf("failed to parse actualStartTime: %s, %w", details.ActualStartTime, err)
+		}
+	}
+	if details.ActualEndTime != "" {
+		ae, err = synchro.ParseISO[tz.AsiaTokyo](details.ActualEndTime)
+		if err != nil {
+			return as, ae, fmt.Errorf("failed to parse actualEndTime: %s, %w", details.ActualEndTime, err)
+		}
+	}
+
+	return as, ae, nil
+}
+
+const (
+	// shortMaxDuration is the longest a Short can be
+	shortMaxDuration = 3 * time.Minute
+	// shortSureDuration is short enough to be a Short without the #shorts tag
+	shortSureDuration = time.Minute
+)
+
+// membersOnlyMarkers are what the channels put in the title of a members-only video, which the API does not tell
+var membersOnlyMarkers = []string{"メン限", "メンバー限定", "members only", "members-only", "membership only"}
+
+// classifyKind tells what the video is. The API does not expose the kind, so it is a heuristic:
+// a video with liveStreamingDetails is a broadcast, and a premiere has the duration of its video before it ends,
+// while a live stream has none. After a broadcast ends both have a duration, so an archived broadcast is a live stream.
+// An upload of up to a minute, or up to three minutes and tagged #shorts, is a Short.
+func classifyKind(i youtube.Video, sts status.Status) kind.Kind {
+	if isMembersOnly(i.Snippet) {
+		return kind.MembersOnly
+	}
+
+	d, known := videoDuration(i.ContentDetails)
+	if i.LiveStreamingDetails != nil {
+		if sts != status.Archived && known && d > 0 {
+			return kind.Premiere
+		}
+		return kind.Live
+	}
+
+	switch {
+	case !known:
+		return kind.Upload
+	case d <= shortSureDuration:
+		return kind.Short
+	case d <= shortMaxDuration && hasShortsTag(i.Snippet):
+		return kind.Short
+	default:
+		return kind.Upload
+	}
+}
+
+// videoDuration returns the duration of the video. An unparsable duration is treated as unknown, not as an error,
+// because the kind is a best effort and must not drop the video.
+func videoDuration(cd *youtube.VideoContentDetails) (time.Duration, bool) {
+	if cd == nil || cd.Duration == "" {
+		return 0, false
+	}
+	d, err := parseDuration(cd.Duration)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+func isMembersOnly(s *youtube.VideoSnippet) bool {
+	title := strings.ToLower(s.Title)
+	for _, m := range membersOnlyMarkers {
+		if strings.Contains(title, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasShortsTag(s *youtube.VideoSnippet) bool {
+	for _, t := range s.Tags {
+		if strings.EqualFold(strings.TrimPrefix(t, "#"), "shorts") {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(s.Title+" "+s.Description), "#shorts")
+}
+
 func (c *YouTubeVideo) FetchScheduledAtByVideoIDs(ctx context.Context, videoIDs []string) ([]dto.ScheduleResponse, error) {
 	if len(videoIDs) == 0 {
 		return []dto.ScheduleResponse{}, nil
 	}
 
-	idsSlice := make([][]string, 0, len(videoIDs)/MaxVideoIDs+1)
-	for i := 0; i < len(videoIDs); i += MaxVideoIDs {
-		end := i + MaxVideoIDs
-		if end > len(videoIDs) {
-			end = len(videoIDs)
-		}
-		idsSlice = append(idsSlice, videoIDs[i:end])
-	}
+	idsSlice := chunkVideoIDs(videoIDs)
 
 	vss := make([]dto.ScheduleResponse, 0, len(videoIDs))
 
@@ -159,7 +295,7 @@ func (c *YouTubeVideo) FetchScheduledAtByVideoIDs(ctx context.Context, videoIDs
 		for _, i := range resp.Items {
 			sa, err := extractScheduledAt(i.LiveStreamingDetails)
 			if err != nil {
-				slog.Error(
+				logging.FromContext(ctx).Error(
 					"failed to extract scheduledStartTime",
 					"sourceID", i.Id,
 					"error", err,
@@ -2,11 +2,13 @@ package api
 
 import (
 	"context"
+	"errors"
 	"github.com/Code-Hex/synchro"
 	"github.com/Code-Hex/synchro/tz"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
 	mocks "github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/youtube/mock"
-	"github.com/KasumiMercury/patotta-stone-functions-go/opus/status"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
@@ -23,9 +25,10 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 	}
 
 	tests := map[string]struct {
-		args      args
-		mockSetup func(*mocks.MockClient)
-		want      []dto.DetailResponse
+		args       args
+		mockSetup  func(*mocks.MockClient)
+		want       []dto.DetailResponse
+		wantFailed []string
 	}{
 		"success_single_live_video": {
 			args: args{videoIDs: []string{"videoID"}},
@@ -52,6 +55,8 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 								},
 								LiveStreamingDetails: &youtube.VideoLiveStreamingDetails{
 									ScheduledStartTime: "2024-01-01T00:00:00Z",
+									ActualStartTime:    "2024-01-01T00:02:00Z",
+									ActiveLiveChatId:   "chatID",
 								},
 							},
 						},
@@ -59,13 +64,62 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 			},
 			want: []dto.DetailResponse{
 				{
-					Id:        "videoID",
-					ChannelId: "channelID",
-					Status:    status.Live,
+					Id:         "videoID",
+					ChannelId:  "channelID",
+					Status:     status.Live,
+					Kind:       kind.Live,
+					ChatId:     "chatID",
+					ChatStatus: status.ChatEnabled,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+					ActualStartAt: synchro.In[tz.AsiaTokyo](
+						time.Date(2024, 1, 1, 0, 2, 0, 0, time.UTC)),
+				},
+			},
+		},
+		"success_single_ended_live_video": {
+			args: args{videoIDs: []string{"videoID"}},
+			mockSetup: func(m *mocks.MockClient) {
+				m.EXPECT().
+					VideoList(gomock.Any(), gomock.Any(), gomock.Eq([]string{"videoID"})).
+					Times(1).
+					Return(&youtube.VideoListResponse{
+						Items: []*youtube.Video{
+							{
+								Id: "videoID",
+								Snippet: &youtube.VideoSnippet{
+									PublishedAt:          "2024-01-01T00:00:00Z",
+									LiveBroadcastContent: "none",
+									ChannelId:            "channelID",
+								},
+								LiveStreamingDetails: &youtube.VideoLiveStreamingDetails{
+									ScheduledStartTime: "2024-01-01T00:00:00Z",
+									ActualStartTime:    "2024-01-01T00:02:00Z",
+									ActualEndTime:      "2024-01-01T02:00:00Z",
+								},
+								ContentDetails: &youtube.VideoContentDetails{Duration: "PT1H58M"},
+							},
+						},
+					}, nil)
+			},
+			want: []dto.DetailResponse{
+				{
+					Id:         "videoID",
+					ChannelId:  "channelID",
+					Status:     status.Archived,
+					Kind:       kind.Live,
+					ChatStatus: status.ChatEnded,
+					PublishedAt: synchro.In[tz.AsiaTokyo](
+						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+					ScheduledAt: synchro.In[tz.AsiaTokyo](
+						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+					ActualStartAt: synchro.In[tz.AsiaTokyo](
+						time.Date(2024, 1, 1, 0, 2, 0, 0, time.UTC)),
+					ActualEndAt: synchro.In[tz.AsiaTokyo](
+						time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)),
+					Duration: time.Hour + 58*time.Minute,
 				},
 			},
 		},
@@ -101,9 +155,11 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 			},
 			want: []dto.DetailResponse{
 				{
-					Id:        "videoID",
-					ChannelId: "channelID",
-					Status:    status.Upcoming,
+					Id:         "videoID",
+					ChannelId:  "channelID",
+					Status:     status.Upcoming,
+					Kind:       kind.Live,
+					ChatStatus: status.ChatDisabled,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -146,6 +202,7 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					Id:        "videoID",
 					ChannelId: "channelID",
 					Status:    status.Archived,
+					Kind:      kind.Live,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -188,6 +245,7 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					Id:        "videoID",
 					ChannelId: "channelID",
 					Status:    status.Archived,
+					Kind:      kind.Live,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -238,18 +296,22 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 			},
 			want: []dto.DetailResponse{
 				{
-					Id:        "videoID1",
-					ChannelId: "channelID",
-					Status:    status.Live,
+					Id:         "videoID1",
+					ChannelId:  "channelID",
+					Status:     status.Live,
+					Kind:       kind.Live,
+					ChatStatus: status.ChatDisabled,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
 				},
 				{
-					Id:        "videoID2",
-					ChannelId: "channelID",
-					Status:    status.Upcoming,
+					Id:         "videoID2",
+					ChannelId:  "channelID",
+					Status:     status.Upcoming,
+					Kind:       kind.Live,
+					ChatStatus: status.ChatDisabled,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -300,9 +362,11 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 			},
 			want: []dto.DetailResponse{
 				{
-					Id:        "videoID1",
-					ChannelId: "channelID",
-					Status:    status.Live,
+					Id:         "videoID1",
+					ChannelId:  "channelID",
+					Status:     status.Live,
+					Kind:       kind.Live,
+					ChatStatus: status.ChatDisabled,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -312,6 +376,7 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					Id:        "videoID2",
 					ChannelId: "channelID",
 					Status:    status.Archived,
+					Kind:      kind.Live,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -362,9 +427,11 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 			},
 			want: []dto.DetailResponse{
 				{
-					Id:        "videoID1",
-					ChannelId: "channelID",
-					Status:    status.Live,
+					Id:         "videoID1",
+					ChannelId:  "channelID",
+					Status:     status.Live,
+					Kind:       kind.Live,
+					ChatStatus: status.ChatDisabled,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -374,6 +441,7 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					Id:        "videoID2",
 					ChannelId: "channelID",
 					Status:    status.Archived,
+					Kind:      kind.Live,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -424,9 +492,11 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 			},
 			want: []dto.DetailResponse{
 				{
-					Id:        "videoID1",
-					ChannelId: "channelID",
-					Status:    status.Upcoming,
+					Id:         "videoID1",
+					ChannelId:  "channelID",
+					Status:     status.Upcoming,
+					Kind:       kind.Live,
+					ChatStatus: status.ChatDisabled,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -436,6 +506,7 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					Id:        "videoID2",
 					ChannelId: "channelID",
 					Status:    status.Archived,
+					Kind:      kind.Live,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -486,9 +557,11 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 			},
 			want: []dto.DetailResponse{
 				{
-					Id:        "videoID1",
-					ChannelId: "channelID",
-					Status:    status.Upcoming,
+					Id:         "videoID1",
+					ChannelId:  "channelID",
+					Status:     status.Upcoming,
+					Kind:       kind.Live,
+					ChatStatus: status.ChatDisabled,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -498,6 +571,7 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					Id:        "videoID2",
 					ChannelId: "channelID",
 					Status:    status.Archived,
+					Kind:      kind.Live,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -551,6 +625,7 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					Id:        "videoID1",
 					ChannelId: "channelID",
 					Status:    status.Archived,
+					Kind:      kind.Live,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -560,6 +635,7 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					Id:        "videoID2",
 					ChannelId: "channelID",
 					Status:    status.Archived,
+					Kind:      kind.Live,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -599,9 +675,11 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 			},
 			want: []dto.DetailResponse{
 				{
-					Id:        "videoID1",
-					ChannelId: "channelID",
-					Status:    status.Live,
+					Id:         "videoID1",
+					ChannelId:  "channelID",
+					Status:     status.Live,
+					Kind:       kind.Live,
+					ChatStatus: status.ChatDisabled,
 					PublishedAt: synchro.In[tz.AsiaTokyo](
 						time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -656,6 +734,7 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 						Id:        "videoID",
 						ChannelId: "channelID",
 						Status:    status.Archived,
+						Kind:      kind.Live,
 						PublishedAt: synchro.In[tz.AsiaTokyo](
 							time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 						ScheduledAt: synchro.In[tz.AsiaTokyo](
@@ -680,7 +759,8 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					},
 				}, nil)
 			},
-			want: make([]dto.DetailResponse, 0),
+			want:       make([]dto.DetailResponse, 0),
+			wantFailed: []string{"videoID"},
 		},
 		"abnormally_failed_to_parse_published_at": {
 			args: args{videoIDs: []string{"videoID"}},
@@ -700,7 +780,8 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					},
 				}, nil)
 			},
-			want: make([]dto.DetailResponse, 0),
+			want:       make([]dto.DetailResponse, 0),
+			wantFailed: []string{"videoID"},
 		},
 		"abnormally_failed_to_match_video_status": {
 			args: args{videoIDs: []string{"videoID"}},
@@ -722,7 +803,8 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					},
 				}, nil)
 			},
-			want: make([]dto.DetailResponse, 0),
+			want:       make([]dto.DetailResponse, 0),
+			wantFailed: []string{"videoID"},
 		},
 		"abnormally_failed_to_parse_scheduled_at": {
 			args: args{videoIDs: []string{"videoID"}},
@@ -747,7 +829,8 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					},
 				}, nil)
 			},
-			want: make([]dto.DetailResponse, 0),
+			want:       make([]dto.DetailResponse, 0),
+			wantFailed: []string{"videoID"},
 		},
 		"abnormally_LiveBroadcastContent_not_found": {
 			args: args{videoIDs: []string{"videoID"}},
@@ -767,7 +850,8 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 					},
 				}, nil)
 			},
-			want: make([]dto.DetailResponse, 0),
+			want:       make([]dto.DetailResponse, 0),
+			wantFailed: []string{"videoID"},
 		},
 	}
 
@@ -790,8 +874,14 @@ func TestYouTubeVideo_FetchVideoDetailsByVideoIDsSuccessfully(t *testing.T) {
 			// Act
 			got, err := c.FetchVideoDetailsByVideoIDs(context.Background(), tt.args.videoIDs)
 			// Assert
-			if err != nil {
+			var failed *ExtractionError
+			switch {
+			case tt.wantFailed == nil && err != nil:
 				t.Errorf("unexpected error: %v", err)
+			case tt.wantFailed != nil && !errors.As(err, &failed):
+				t.Errorf("want *ExtractionError, got: %v", err)
+			case tt.wantFailed != nil:
+				assert.Equal(t, tt.wantFailed, failed.SourceIDs)
 			}
 			if !cmp.Equal(tt.want, got) {
 				t.Errorf("unexpected response: %v", cmp.Diff(tt.want, got))
@@ -1045,3 +1135,111 @@ func TestYouTubeVideo_FetchScheduledAtByVideoIDsError(t *testing.T) {
 		})
 	}
 }
+
+func TestClassifyKind(t *testing.T) {
+	t.Parallel()
+
+	live := &youtube.VideoLiveStreamingDetails{ScheduledStartTime: "2024-01-01T00:00:00Z"}
+
+	tests := map[string]struct {
+		snippet  youtube.VideoSnippet
+		duration string
+		details  *youtube.VideoLiveStreamingDetails
+		status   status.Status
+		want     kind.Kind
+	}{
+		"upcoming live stream": {
+			duration: "P0D",
+			details:  live,
+			status:   status.Upcoming,
+			want:     kind.Live,
+		},
+		"upcoming premiere": {
+			duration: "PT12M30S",
+			details:  live,
+			status:   status.Upcoming,
+			want:     kind.Premiere,
+		},
+		"premiere on air": {
+			duration: "PT12M30S",
+			details:  live,
+			status:   status.Live,
+			want:     kind.Premiere,
+		},
+		"archived broadcast": {
+			duration: "PT1H2M",
+			details:  live,
+			status:   status.Archived,
+			want:     kind.Live,
+		},
+		"members-only stream": {
+			snippet:  youtube.VideoSnippet{Title: "【メン限】雑談"},
+			duration: "P0D",
+			details:  live,
+			status:   status.Upcoming,
+			want:     kind.MembersOnly,
+		},
+		"members-only in English": {
+			snippet: youtube.VideoSnippet{Title: "Karaoke (Members Only)"},
+			details: live,
+			status:  status.Archived,
+			want:    kind.MembersOnly,
+		},
+		"upload": {
+			duration: "PT10M",
+			status:   status.Archived,
+			want:     kind.Upload,
+		},
+		"short": {
+			duration: "PT45S",
+			status:   status.Archived,
+			want:     kind.Short,
+		},
+		"tagged short up to three minutes": {
+			snippet:  youtube.VideoSnippet{Tags: []string{"#Shorts"}},
+			duration: "PT2M30S",
+			status:   status.Archived,
+			want:     kind.Short,
+		},
+		"short tag in the title": {
+			snippet:  youtube.VideoSnippet{Title: "cover #shorts"},
+			duration: "PT3M",
+			status:   status.Archived,
+			want:     kind.Short,
+		},
+		"untagged upload of two minutes": {
+			duration: "PT2M",
+			status:   status.Archived,
+			want:     kind.Upload,
+		},
+		"tagged upload over three minutes": {
+			snippet:  youtube.VideoSnippet{Tags: []string{"shorts"}},
+			duration: "PT3M1S",
+			status:   status.Archived,
+			want:     kind.Upload,
+		},
+		"upload without duration": {
+			status: status.Archived,
+			want:   kind.Upload,
+		},
+		"upload with malformed duration": {
+			duration: "10:00",
+			status:   status.Archived,
+			want:     kind.Upload,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			snippet := tt.snippet
+			v := youtube.Video{Snippet: &snippet, LiveStreamingDetails: tt.details}
+			if tt.duration != "" {
+				v.ContentDetails = &youtube.VideoContentDetails{Duration: tt.duration}
+			}
+
+			assert.Equal(t, tt.want, classifyKind(v, tt.status))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package cloudfunction
+
+import (
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type channelResponse struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Handle    string `json:"handle"`
+	AvatarURL string `json:"avatarUrl"`
+	// SubscriberCount is null if the channel hides it
+	SubscriberCount   *int64 `json:"subscriberCount"`
+	UploadsPlaylistID string `json:"uploadsPlaylistId"`
+	// UpdatedAt is null until the channel is refreshed for the first time
+	UpdatedAt *string `json:"updatedAt"`
+}
+
+type channelListResponse struct {
+	Channels []channelResponse `json:"channels"`
+}
+
+// listChannels returns the metadata of every channel that videos reference.
+func (h *QueryHandler) listChannels(w http.ResponseWriter, r *http.Request) {
+	channels, err := h.channels.GetChannels(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error(
+			"Failed to get channels",
+			slog.Group("Query", "error", err),
+		)
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get channels")
+		return
+	}
+
+	resp := channelListResponse{Channels: make([]channelResponse, 0, len(channels))}
+	for _, c := range channels {
+		cr := channelResponse{
+			ID:                c.ID,
+			Title:             c.Title,
+			Handle:            c.Handle,
+			AvatarURL:         c.AvatarURL,
+			SubscriberCount:   c.SubscriberCount,
+			UploadsPlaylistID: c.UploadsPlaylistID,
+		}
+		if c.UpdatedAt != nil {
+			u := c.UpdatedAt.UTC().Format(time.RFC3339)
+			cr.UpdatedAt = &u
+		}
+		resp.Channels = append(resp.Channels, cr)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,36 @@
+package cloudfunction
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/channel"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryHandler_ListChannels(t *testing.T) {
+	t.Parallel()
+
+	repo := realtime.NewMemory()
+	subscribers := int64(1000)
+	c, err := channel.NewChannel("main", "title", "@main", "https://example.com/main.jpg", &subscribers, "UUmain", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpsertChannels(context.Background(), []channel.Channel{*c}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewQueryHandler(&fakeQueryRepository{}, &fakeSyncRunRepository{}, nil, repo)
+
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodGet, "/channels", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"channels":[{
+		"id":"main","title":"title","handle":"@main","avatarUrl":"https://example.com/main.jpg",
+		"subscriberCount":1000,"uploadsPlaylistId":"UUmain","updatedAt":"2024-07-01T00:00:00Z"
+	}]}`, rec.Body.String())
+}
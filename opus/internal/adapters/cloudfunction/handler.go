@@ -1,20 +1,241 @@
 package cloudfunction
 
-import "net/http"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/service"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/pubsub"
+	"google.golang.org/api/googleapi"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type SyncService interface {
+	SyncVideosWithRSS(ctx context.Context, opts service.SyncOptions) error
+}
+
+// syncLockKey guards the sync against overlapping runs caused by scheduler retries
+const syncLockKey = "opus:sync"
+
+// TargetProvider is the target channels, which may be reloaded while the instance is warm.
+type TargetProvider interface {
+	Targets() []config.Channel
+	ChannelIDs() []string
+}
 
 type Handler struct {
+	ops     map[run.Operation]Operation
+	targets TargetProvider
+	locker  lock.Locker
+	runs    realtime.SyncRunRepository
+	health  *HealthHandler
+	now     func() time.Time
+}
+
+// NewCloudFunctionHandler registers the RSS sync, which runs when a request names no operation.
+// targets are the target channels that the channels parameter of an operation may choose from.
+func NewCloudFunctionHandler(s SyncService, targets TargetProvider, l lock.Locker, runs realtime.SyncRunRepository, hh *HealthHandler) *Handler {
+	h := &Handler{
+		ops:     make(map[run.Operation]Operation),
+		targets: targets,
+		locker:  l,
+		runs:    runs,
+		health:  hh,
+		now:     time.Now,
+	}
+	h.Register(run.OperationRSSSync, RSSSyncOperation(s))
+
+	return h
 }
 
-func NewCloudFunctionHandler() *Handler {
-	return &Handler{}
+// Register makes the operation reachable at /{name} and by {"operation": name} in the body.
+func (h *Handler) Register(name run.Operation, op Operation) {
+	h.ops[name] = op
+}
+
+type runReport struct {
+	Operation run.Operation `json:"operation"`
+	Status    string        `json:"status"`
+	Reason    string        `json:"reason,omitempty"`
+	RunID     int64         `json:"runId,omitempty"`
+	DryRun    bool          `json:"dryRun,omitempty"`
 }
 
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement
+	switch r.URL.Path {
+	case "/healthz":
+		h.health.Healthz(w, r)
+	case "/readyz":
+		h.health.Readyz(w, r)
+	case "/status":
+		h.health.Status(w, r)
+	default:
+		h.operate(w, r)
+	}
+}
+
+var (
+	errUnknownOperation = errors.New("unknown operation")
+	errNotReady         = errors.New("operation is not ready")
+	errLockUnavailable  = errors.New("failed to acquire operation lock")
+	errOperationFailed  = errors.New("operation failed")
+)
+
+func (h *Handler) operate(w http.ResponseWriter, r *http.Request) {
+	name, params, err := parseOperation(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+
+	report, err := h.execute(r.Context(), name, params, triggerOf(r))
+	switch {
+	case errors.Is(err, errUnknownOperation):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, errInvalidParameter):
+		writeError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+	case errors.Is(err, errNotReady), errors.Is(err, errLockUnavailable):
+		writeError(w, http.StatusServiceUnavailable, "unavailable", err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "internal", fmt.Sprintf("failed to run operation %q", name))
+	default:
+		writeJSON(w, http.StatusOK, report)
+	}
+}
 
-	// TODO: RSS service
+// HandleMessage runs the operation named by a Pub/Sub message, whose data is the same JSON as the HTTP body.
+// A message the handler can never run is permanent, and every other failure is retried by Pub/Sub.
+func (h *Handler) HandleMessage(ctx context.Context, m pubsub.Message) error {
+	var req operationRequest
+	if err := m.Unmarshal(&req); err != nil {
+		return err
+	}
+	if req.Operation == "" {
+		req.Operation = run.OperationRSSSync
+	}
 
-	// TODO: Update Video Info
+	_, err := h.execute(ctx, req.Operation, req.Params, run.TriggerPubSub)
+	if errors.Is(err, errUnknownOperation) || errors.Is(err, errInvalidParameter) || isClientError(err) {
+		return pubsub.Permanent(err)
+	}
+
+	return err
+}
+
+// isClientError reports whether err is an API error that a retry of the same request cannot fix.
+// A rate limit is a 4xx, but it passes.
+func isClientError(err error) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return false
+	}
+
+	return gerr.Code >= 400 && gerr.Code < 500 && gerr.Code != http.StatusTooManyRequests
+}
+
+// execute validates and runs the operation, and records it in the ledger.
+// A run skipped because another one holds the lock is not an error.
+func (h *Handler) execute(ctx context.Context, name run.Operation, params Params, trigger run.Trigger) (runReport, error) {
+	op, ok := h.ops[name]
+	if !ok {
+		return runReport{}, fmt.Errorf("%w %q", errUnknownOperation, name)
+	}
+	var channels []string
+	if h.targets != nil {
+		channels = h.targets.ChannelIDs()
+	}
+	if err := op.validate(params, channels); err != nil {
+		return runReport{}, err
+	}
+	// the operation is not ready when the instance failed to initialize its dependencies
+	if op.Run == nil {
+		logging.FromContext(ctx).Error("Operation is not ready", "operation", name)
+		return runReport{}, fmt.Errorf("%w: %s", errNotReady, name)
+	}
+
+	// the ledger must be written even if the request context is already done
+	ledgerCtx := context.WithoutCancel(ctx)
+	rec := run.NewRecorder(name, trigger, h.now())
+	report := runReport{Operation: name, DryRun: params.DryRun}
+
+	l, err := h.locker.TryLock(ctx, op.LockKey)
+	if errors.Is(err, lock.ErrHeld) {
+		logging.FromContext(ctx).Info("Operation skipped: already running", "operation", name)
+		rec.Finish(run.OutcomeSkipped, h.now())
+		report.Status, report.Reason = "skipped", "already running"
+		report.RunID = h.saveRun(ledgerCtx, 0, rec)
+		return report, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to acquire operation lock",
+			"operation", name,
+			slog.Group("Sync", "error", err),
+		)
+		return runReport{}, errLockUnavailable
+	}
+	defer func() {
+		if err := l.Unlock(ledgerCtx); err != nil {
+			logging.FromContext(ctx).Error(
+				"Failed to release operation lock",
+				"operation", name,
+				slog.Group("Sync", "error", err),
+			)
+		}
+	}()
+
+	id := h.saveRun(ledgerCtx, 0, rec)
+
+	if err := op.Run(run.WithRecorder(ctx, rec), params); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to run operation",
+			"operation", name,
+			slog.Group("Sync", "error", err),
+		)
+		rec.AddError(err)
+		rec.Finish(run.OutcomeFailed, h.now())
+		h.saveRun(ledgerCtx, id, rec)
+		return runReport{}, fmt.Errorf("%w: %w", errOperationFailed, err)
+	}
+
+	rec.Finish(run.OutcomeSucceeded, h.now())
+	report.Status = "ok"
+	report.RunID = h.saveRun(ledgerCtx, id, rec)
+	return report, nil
+}
+
+// saveRun writes the run to the ledger and returns its ID.
+// The ledger is best effort, so a failure is only logged and never fails the operation itself.
+// A run whose first write failed is inserted again on the next write.
+func (h *Handler) saveRun(ctx context.Context, id int64, rec *run.Recorder) int64 {
+	var err error
+	if id == 0 {
+		id, err = h.runs.StartRun(ctx, rec.Stats())
+	} else {
+		err = h.runs.FinishRun(ctx, id, rec.Stats())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn(
+			"Failed to record sync run",
+			slog.Group("Sync", "error", err),
+		)
+	}
+
+	return id
+}
 
-	w.WriteHeader(http.StatusOK)
+// triggerOf tells a scheduled invocation from a manual one by the user agent Cloud Scheduler sends
+func triggerOf(r *http.Request) run.Trigger {
+	if strings.HasPrefix(r.UserAgent(), "Google-Cloud-Scheduler") {
+		return run.TriggerScheduler
+	}
+	return run.TriggerManual
 }
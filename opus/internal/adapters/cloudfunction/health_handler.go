@@ -0,0 +1,133 @@
+package cloudfunction
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const checkTimeout = 3 * time.Second
+
+// Check is a single readiness condition such as a DB ping.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	checks     []Check
+	stRepo     realtime.SyncStateRepository
+	targets    TargetProvider
+	staleAfter time.Duration
+	now        func() time.Time
+}
+
+func NewHealthHandler(checks []Check, st realtime.SyncStateRepository, targets TargetProvider, staleAfter time.Duration) *HealthHandler {
+	return &HealthHandler{
+		checks:     checks,
+		stRepo:     st,
+		targets:    targets,
+		staleAfter: staleAfter,
+		now:        time.Now,
+	}
+}
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+type statusResponse struct {
+	Stale          bool                    `json:"stale"`
+	StaleThreshold string                  `json:"staleThreshold"`
+	Channels       []channelStatusResponse `json:"channels"`
+}
+
+type channelStatusResponse struct {
+	ChannelID    string  `json:"channelId"`
+	Display      string  `json:"display"`
+	LastSyncedAt *string `json:"lastSyncedAt"`
+	Stale        bool    `json:"stale"`
+}
+
+// Healthz reports that the instance is alive. It never touches any dependency.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, readinessResponse{Status: "ok", Checks: map[string]string{}})
+}
+
+// Readyz runs every check and reports 503 if any of them fails.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	resp := readinessResponse{Status: "ok", Checks: make(map[string]string, len(h.checks))}
+	code := http.StatusOK
+	for _, c := range h.checks {
+		if err := c.Check(ctx); err != nil {
+			logging.FromContext(r.Context()).Warn(
+				"Readiness check failed",
+				"check", c.Name,
+				"error", err,
+			)
+			resp.Checks[c.Name] = err.Error()
+			resp.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[c.Name] = "ok"
+	}
+
+	writeJSON(w, code, resp)
+}
+
+// Status reports the last successful sync time of each target channel
+// and flags the channels that have not been synced within the threshold.
+func (h *HealthHandler) Status(w http.ResponseWriter, r *http.Request) {
+	states, err := h.stRepo.GetSyncStates(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error(
+			"Failed to get sync states",
+			slog.Group("Status", "error", err),
+		)
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get sync states")
+		return
+	}
+
+	lastSynced := make(map[string]time.Time, len(states))
+	for _, s := range states {
+		lastSynced[s.ChannelID] = s.LastSyncedAt
+	}
+
+	// only the channels found by RSS are synced, so the others are never stale
+	channels := make([]config.Channel, 0)
+	if h.targets != nil {
+		for _, c := range h.targets.Targets() {
+			if c.DiscoveredByRSS() {
+				channels = append(channels, c)
+			}
+		}
+	}
+
+	now := h.now()
+	resp := statusResponse{
+		StaleThreshold: h.staleAfter.String(),
+		Channels:       make([]channelStatusResponse, 0, len(channels)),
+	}
+	for _, c := range channels {
+		cs := channelStatusResponse{ChannelID: c.ChannelId, Display: c.Display, Stale: true}
+		if t, ok := lastSynced[c.ChannelId]; ok {
+			cs.LastSyncedAt = formatNillableTime(&t)
+			cs.Stale = now.Sub(t) > h.staleAfter
+		}
+		if cs.Stale {
+			resp.Stale = true
+		}
+		resp.Channels = append(resp.Channels, cs)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
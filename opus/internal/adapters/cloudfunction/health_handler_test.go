@@ -0,0 +1,319 @@
+package cloudfunction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/service"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeSyncStateRepository struct {
+	states []*realtime.SyncState
+	err    error
+}
+
+func (f *fakeSyncStateRepository) MarkChannelsSynced(_ context.Context, _ []string, _ time.Time) error {
+	return f.err
+}
+
+func (f *fakeSyncStateRepository) SetRSSWatermarks(_ context.Context, _ map[string]time.Time) error {
+	return f.err
+}
+
+func (f *fakeSyncStateRepository) GetSyncStates(_ context.Context) ([]*realtime.SyncState, error) {
+	return f.states, f.err
+}
+
+type fakeSyncService struct {
+	err    error
+	called bool
+}
+
+func (f *fakeSyncService) SyncVideosWithRSS(_ context.Context, _ service.SyncOptions) error {
+	f.called = true
+	return f.err
+}
+
+// testTargets is a config targeting the channels of ids.
+func testTargets(ids ...string) *config.Config {
+	chs := make([]config.Channel, 0, len(ids))
+	for _, id := range ids {
+		chs = append(chs, config.Channel{ChannelId: id})
+	}
+	c := &config.Config{}
+	c.SetTarget(config.Target{Channel: chs})
+	return c
+}
+
+func okCheck(_ context.Context) error {
+	return nil
+}
+
+func TestHealthHandler_Readyz(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		checks   []Check
+		wantCode int
+		want     readinessResponse
+	}{
+		"all checks pass": {
+			checks: []Check{
+				{Name: "config", Check: okCheck},
+				{Name: "database", Check: okCheck},
+			},
+			wantCode: http.StatusOK,
+			want: readinessResponse{
+				Status: "ok",
+				Checks: map[string]string{"config": "ok", "database": "ok"},
+			},
+		},
+		"database is unreachable": {
+			checks: []Check{
+				{Name: "config", Check: okCheck},
+				{Name: "database", Check: func(_ context.Context) error { return errors.New("connection refused") }},
+			},
+			wantCode: http.StatusServiceUnavailable,
+			want: readinessResponse{
+				Status: "unavailable",
+				Checks: map[string]string{"config": "ok", "database": "connection refused"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			h := NewHealthHandler(tt.checks, &fakeSyncStateRepository{}, nil, time.Hour)
+
+			rec := httptest.NewRecorder()
+			h.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			var got readinessResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Readyz() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHealthHandler_Status(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	channels := []config.Channel{
+		{Display: "main", ChannelId: "main_channel"},
+		{Display: "sub", ChannelId: "sub_channel"},
+	}
+	recent := "2024-01-01T11:50:00Z"
+	old := "2024-01-01T10:00:00Z"
+
+	tests := map[string]struct {
+		states []*realtime.SyncState
+		want   statusResponse
+	}{
+		"all channels are fresh": {
+			states: []*realtime.SyncState{
+				{ChannelID: "main_channel", LastSyncedAt: time.Date(2024, 1, 1, 11, 50, 0, 0, time.UTC)},
+				{ChannelID: "sub_channel", LastSyncedAt: time.Date(2024, 1, 1, 11, 50, 0, 0, time.UTC)},
+			},
+			want: statusResponse{
+				Stale:          false,
+				StaleThreshold: "30m0s",
+				Channels: []channelStatusResponse{
+					{ChannelID: "main_channel", Display: "main", LastSyncedAt: &recent, Stale: false},
+					{ChannelID: "sub_channel", Display: "sub", LastSyncedAt: &recent, Stale: false},
+				},
+			},
+		},
+		"one channel is stale": {
+			states: []*realtime.SyncState{
+				{ChannelID: "main_channel", LastSyncedAt: time.Date(2024, 1, 1, 11, 50, 0, 0, time.UTC)},
+				{ChannelID: "sub_channel", LastSyncedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)},
+			},
+			want: statusResponse{
+				Stale:          true,
+				StaleThreshold: "30m0s",
+				Channels: []channelStatusResponse{
+					{ChannelID: "main_channel", Display: "main", LastSyncedAt: &recent, Stale: false},
+					{ChannelID: "sub_channel", Display: "sub", LastSyncedAt: &old, Stale: true},
+				},
+			},
+		},
+		"never synced": {
+			states: nil,
+			want: statusResponse{
+				Stale:          true,
+				StaleThreshold: "30m0s",
+				Channels: []channelStatusResponse{
+					{ChannelID: "main_channel", Display: "main", LastSyncedAt: nil, Stale: true},
+					{ChannelID: "sub_channel", Display: "sub", LastSyncedAt: nil, Stale: true},
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c := &config.Config{}
+			c.SetTarget(config.Target{Channel: channels})
+			h := NewHealthHandler(nil, &fakeSyncStateRepository{states: tt.states}, c, 30*time.Minute)
+			h.now = func() time.Time { return now }
+
+			rec := httptest.NewRecorder()
+			h.Status(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var got statusResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Status() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleRoutes(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		path       string
+		syncErr    error
+		wantCode   int
+		wantSynced bool
+	}{
+		"healthz": {
+			path:     "/healthz",
+			wantCode: http.StatusOK,
+		},
+		"readyz": {
+			path:     "/readyz",
+			wantCode: http.StatusServiceUnavailable,
+		},
+		"sync": {
+			path:       "/",
+			wantCode:   http.StatusOK,
+			wantSynced: true,
+		},
+		"sync failure": {
+			path:       "/",
+			syncErr:    errors.New("quota exceeded"),
+			wantCode:   http.StatusInternalServerError,
+			wantSynced: true,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			svc := &fakeSyncService{err: tt.syncErr}
+			checks := []Check{{Name: "database", Check: func(_ context.Context) error { return errors.New("down") }}}
+			h := NewCloudFunctionHandler(svc, nil, lock.NewMemoryLocker(), &fakeSyncRunRepository{}, NewHealthHandler(checks, &fakeSyncStateRepository{}, nil, time.Hour))
+
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantSynced, svc.called)
+		})
+	}
+}
+
+func TestHandler_HandleWithoutSyncService(t *testing.T) {
+	t.Parallel()
+
+	h := NewCloudFunctionHandler(nil, nil, lock.NewMemoryLocker(), &fakeSyncRunRepository{}, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestHandler_HandleWithoutDatabase(t *testing.T) {
+	t.Parallel()
+
+	// the handlers are wired as main does when the config fails to load
+	rtd := realtime.NewUnavailable(errors.New("API_KEY is not set"))
+	hh := NewHealthHandler([]Check{{Name: "database", Check: rtd.Ping}}, rtd, &config.Config{}, time.Hour)
+	h := NewCloudFunctionHandler(nil, &config.Config{}, rtd.Locker(), rtd, hh)
+	q := NewQueryHandler(rtd, rtd, rtd, rtd)
+
+	tests := map[string]struct {
+		handle   func(w http.ResponseWriter, r *http.Request)
+		path     string
+		wantCode int
+	}{
+		"readyz reports the database": {handle: h.Handle, path: "/readyz", wantCode: http.StatusServiceUnavailable},
+		"healthz stays reachable":     {handle: h.Handle, path: "/healthz", wantCode: http.StatusOK},
+		"status":                      {handle: h.Handle, path: "/status", wantCode: http.StatusInternalServerError},
+		"sync":                        {handle: h.Handle, path: "/", wantCode: http.StatusServiceUnavailable},
+		"query":                       {handle: q.Handle, path: "/", wantCode: http.StatusInternalServerError},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			tt.handle(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
+
+func TestHandler_HandleWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	locker := lock.NewMemoryLocker()
+	svc := &fakeSyncService{}
+	h := NewCloudFunctionHandler(svc, nil, locker, &fakeSyncRunRepository{}, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+
+	held, err := locker.TryLock(ctx, syncLockKey)
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, svc.called)
+	var got runReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, runReport{Operation: run.OperationRSSSync, Status: "skipped", Reason: "already running", RunID: 1}, got)
+
+	// the next run proceeds once the lock is released, and releases the lock itself
+	assert.NoError(t, held.Unlock(ctx))
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		h.Handle(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var report runReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, runReport{Operation: run.OperationRSSSync, Status: "ok", RunID: int64(i + 2)}, report)
+	}
+	assert.True(t, svc.called)
+}
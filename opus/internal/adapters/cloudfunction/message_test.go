@@ -0,0 +1,114 @@
+package cloudfunction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/pubsub"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandler_HandleMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		data          string
+		service       SyncService
+		held          bool
+		wantErr       bool
+		wantPermanent bool
+		wantOutcome   run.Outcome
+	}{
+		"empty message runs the RSS sync": {
+			service:     &fakeSyncService{},
+			wantOutcome: run.OutcomeSucceeded,
+		},
+		"operation with parameters": {
+			data:        `{"operation": "rss_sync", "channels": ["sub_channel"], "limit": 3}`,
+			service:     &fakeSyncService{},
+			wantOutcome: run.OutcomeSucceeded,
+		},
+		"already running": {
+			service:     &fakeSyncService{},
+			held:        true,
+			wantOutcome: run.OutcomeSkipped,
+		},
+		"unknown operation is permanent": {
+			data:          `{"operation": "delete_everything"}`,
+			service:       &fakeSyncService{},
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		"invalid parameter is permanent": {
+			data:          `{"limit": 100}`,
+			service:       &fakeSyncService{},
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		"malformed payload is permanent": {
+			data:          `{"operation": `,
+			service:       &fakeSyncService{},
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		"not ready is retried": {
+			wantErr: true,
+		},
+		"failed run is retried": {
+			service:     &fakeSyncService{err: errors.New("quota exceeded")},
+			wantErr:     true,
+			wantOutcome: run.OutcomeFailed,
+		},
+		"rejected request is permanent": {
+			service:       &fakeSyncService{err: fmt.Errorf("failed to fetch: %w", &googleapi.Error{Code: http.StatusForbidden})},
+			wantErr:       true,
+			wantPermanent: true,
+			wantOutcome:   run.OutcomeFailed,
+		},
+		"rate limit is retried": {
+			service:     &fakeSyncService{err: &googleapi.Error{Code: http.StatusTooManyRequests}},
+			wantErr:     true,
+			wantOutcome: run.OutcomeFailed,
+		},
+		"server error is retried": {
+			service:     &fakeSyncService{err: &googleapi.Error{Code: http.StatusServiceUnavailable}},
+			wantErr:     true,
+			wantOutcome: run.OutcomeFailed,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			runs := &fakeSyncRunRepository{}
+			locker := lock.NewMemoryLocker()
+			if tt.held {
+				if _, err := locker.TryLock(ctx, syncLockKey); err != nil {
+					t.Fatal(err)
+				}
+			}
+			h := NewCloudFunctionHandler(tt.service, testTargets("main_channel", "sub_channel"), locker, runs, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+
+			err := h.HandleMessage(ctx, pubsub.Message{ID: "message-1", Data: []byte(tt.data)})
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantPermanent, pubsub.IsPermanent(err))
+
+			if tt.wantOutcome == "" {
+				assert.Empty(t, runs.runs)
+				return
+			}
+			if assert.Len(t, runs.runs, 1) {
+				assert.Equal(t, tt.wantOutcome, runs.runs[1].Outcome)
+				assert.Equal(t, run.TriggerPubSub, runs.runs[1].Trigger)
+			}
+		})
+	}
+}
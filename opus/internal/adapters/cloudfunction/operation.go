@@ -0,0 +1,235 @@
+package cloudfunction
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/service"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const maxOperationBody = 1 << 20
+
+// Params are the parameters of an operation request.
+// Which of them an operation takes is declared by its Operation, and the rest are rejected.
+type Params struct {
+	Channels []string `json:"channels,omitempty"`
+	DryRun   bool     `json:"dryRun,omitempty"`
+	Limit    int      `json:"limit,omitempty"`
+}
+
+// Operation is a job the entry point runs, such as the RSS sync.
+type Operation struct {
+	// Run executes the job. The statistics of the run are collected through run.FromContext.
+	// An operation without Run is registered but not ready, and is answered with 503.
+	Run func(ctx context.Context, p Params) error
+	// LockKey guards the job against overlapping runs
+	LockKey string
+	// TakesChannels accepts a subset of the target channels
+	TakesChannels bool
+	TakesDryRun   bool
+	// MaxLimit is the largest limit the job accepts, and 0 if it takes no limit
+	MaxLimit int
+}
+
+// rssSyncMaxLimit caps the RSS items per channel, which is 15 in the YouTube feed
+const rssSyncMaxLimit = 15
+
+// RSSSyncOperation runs the RSS sync. s may be nil when the service failed to initialize.
+func RSSSyncOperation(s SyncService) Operation {
+	op := Operation{
+		LockKey:       syncLockKey,
+		TakesChannels: true,
+		TakesDryRun:   true,
+		MaxLimit:      rssSyncMaxLimit,
+	}
+	if s != nil {
+		op.Run = func(ctx context.Context, p Params) error {
+			return s.SyncVideosWithRSS(ctx, service.SyncOptions{ChannelIDs: p.Channels, DryRun: p.DryRun, Limit: p.Limit})
+		}
+	}
+
+	return op
+}
+
+// StaleChecker finds upcoming videos that never went live and resolves them through the API.
+type StaleChecker interface {
+	DetectStaleUpcoming(ctx context.Context, opts service.StaleOptions) (*service.StaleReport, error)
+}
+
+// StaleCheckOperation runs the stale upcoming detection, whose report is logged.
+// It writes the videos like the RSS sync, so they share the lock. s may be nil when the service failed to initialize.
+func StaleCheckOperation(s StaleChecker) Operation {
+	op := Operation{
+		LockKey:       syncLockKey,
+		TakesChannels: true,
+		TakesDryRun:   true,
+	}
+	if s != nil {
+		op.Run = func(ctx context.Context, p Params) error {
+			_, err := s.DetectStaleUpcoming(ctx, service.StaleOptions{ChannelIDs: p.Channels, DryRun: p.DryRun})
+			return err
+		}
+	}
+
+	return op
+}
+
+// ScheduleRefresher follows the upcoming videos that are rescheduled.
+type ScheduleRefresher interface {
+	RefreshSchedules(ctx context.Context, opts service.RefreshOptions) error
+}
+
+// ScheduleRefreshOperation refreshes the schedules of the upcoming videos.
+// It writes the videos like the RSS sync, so they share the lock. s may be nil when the service failed to initialize.
+func ScheduleRefreshOperation(s ScheduleRefresher) Operation {
+	op := Operation{
+		LockKey:       syncLockKey,
+		TakesChannels: true,
+		TakesDryRun:   true,
+	}
+	if s != nil {
+		op.Run = func(ctx context.Context, p Params) error {
+			return s.RefreshSchedules(ctx, service.RefreshOptions{ChannelIDs: p.Channels, DryRun: p.DryRun})
+		}
+	}
+
+	return op
+}
+
+// Reconciler follows the broadcasts around their streams.
+type Reconciler interface {
+	Reconcile(ctx context.Context, opts service.RefreshOptions) error
+}
+
+// ReconcileOperation reconciles the live and imminent broadcasts with the API.
+// It writes the videos like the RSS sync, so they share the lock. s may be nil when the service failed to initialize.
+func ReconcileOperation(s Reconciler) Operation {
+	op := Operation{
+		LockKey:       syncLockKey,
+		TakesChannels: true,
+		TakesDryRun:   true,
+	}
+	if s != nil {
+		op.Run = func(ctx context.Context, p Params) error {
+			return s.Reconcile(ctx, service.RefreshOptions{ChannelIDs: p.Channels, DryRun: p.DryRun})
+		}
+	}
+
+	return op
+}
+
+// StatsSnapshotter samples the statistics of the tracked videos.
+type StatsSnapshotter interface {
+	SnapshotStats(ctx context.Context, opts service.StatsOptions) error
+}
+
+// statsLockKey guards the stats sampling, which only writes the snapshots and may run alongside the sync
+const statsLockKey = "opus:stats"
+
+// StatsSnapshotOperation samples the statistics. s may be nil when the service failed to initialize.
+func StatsSnapshotOperation(s StatsSnapshotter) Operation {
+	op := Operation{
+		LockKey:       statsLockKey,
+		TakesChannels: true,
+	}
+	if s != nil {
+		op.Run = func(ctx context.Context, p Params) error {
+			return s.SnapshotStats(ctx, service.StatsOptions{ChannelIDs: p.Channels})
+		}
+	}
+
+	return op
+}
+
+// ChannelSyncer refreshes the metadata of the target channels.
+type ChannelSyncer interface {
+	SyncChannels(ctx context.Context, opts service.ChannelOptions) error
+}
+
+// channelLockKey guards the channel refresh, which only writes the channels and may run alongside the sync
+const channelLockKey = "opus:channels"
+
+// ChannelSyncOperation refreshes the channels that are due. s may be nil when the service failed to initialize.
+func ChannelSyncOperation(s ChannelSyncer) Operation {
+	op := Operation{
+		LockKey:       channelLockKey,
+		TakesChannels: true,
+	}
+	if s != nil {
+		op.Run = func(ctx context.Context, p Params) error {
+			return s.SyncChannels(ctx, service.ChannelOptions{ChannelIDs: p.Channels})
+		}
+	}
+
+	return op
+}
+
+type operationRequest struct {
+	Operation run.Operation `json:"operation"`
+	Params
+}
+
+// parseOperation reads the operation from the path, or from the body of a request to the root.
+// A request to the root without an operation runs the RSS sync, which is what the scheduler has always called.
+func parseOperation(r *http.Request) (run.Operation, Params, error) {
+	name := run.Operation(strings.Trim(r.URL.Path, "/"))
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxOperationBody))
+	if err != nil {
+		return "", Params{}, fmt.Errorf("%w: failed to read the body", errInvalidParameter)
+	}
+
+	var req operationRequest
+	if len(bytes.TrimSpace(body)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			return "", Params{}, fmt.Errorf("%w: malformed body: %v", errInvalidParameter, err)
+		}
+	}
+
+	switch {
+	case name != "" && req.Operation != "" && name != req.Operation:
+		return "", Params{}, fmt.Errorf("%w: operation %q in the body does not match the path", errInvalidParameter, req.Operation)
+	case name == "":
+		name = req.Operation
+	}
+	if name == "" {
+		name = run.OperationRSSSync
+	}
+
+	return name, req.Params, nil
+}
+
+// validate checks the parameters against what op takes. channels are the configured target channels.
+func (op Operation) validate(p Params, channels []string) error {
+	if len(p.Channels) > 0 {
+		if !op.TakesChannels {
+			return fmt.Errorf("%w: the operation does not take channels", errInvalidParameter)
+		}
+		for _, c := range p.Channels {
+			if !slices.Contains(channels, c) {
+				return fmt.Errorf("%w: %q is not a target channel", errInvalidParameter, c)
+			}
+		}
+	}
+	if p.DryRun && !op.TakesDryRun {
+		return fmt.Errorf("%w: the operation does not take dryRun", errInvalidParameter)
+	}
+	if p.Limit != 0 {
+		if op.MaxLimit == 0 {
+			return fmt.Errorf("%w: the operation does not take limit", errInvalidParameter)
+		}
+		if p.Limit < 0 || p.Limit > op.MaxLimit {
+			return fmt.Errorf("%w: limit must be between 1 and %d", errInvalidParameter, op.MaxLimit)
+		}
+	}
+
+	return nil
+}
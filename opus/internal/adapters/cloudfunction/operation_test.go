@@ -0,0 +1,296 @@
+package cloudfunction
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/service"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type optionsSyncService struct {
+	got    service.SyncOptions
+	called bool
+}
+
+func (s *optionsSyncService) SyncVideosWithRSS(_ context.Context, opts service.SyncOptions) error {
+	s.called = true
+	s.got = opts
+	return nil
+}
+
+func TestHandler_HandleOperations(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		path        string
+		body        string
+		wantCode    int
+		wantErr     string
+		wantOp      run.Operation
+		wantOptions *service.SyncOptions
+		wantRefresh bool
+	}{
+		"root without body runs the RSS sync": {
+			path:        "/",
+			wantCode:    http.StatusOK,
+			wantOp:      run.OperationRSSSync,
+			wantOptions: &service.SyncOptions{},
+		},
+		"operation in path": {
+			path:        "/rss_sync",
+			body:        `{"channels": ["sub_channel"], "dryRun": true, "limit": 5}`,
+			wantCode:    http.StatusOK,
+			wantOp:      run.OperationRSSSync,
+			wantOptions: &service.SyncOptions{ChannelIDs: []string{"sub_channel"}, DryRun: true, Limit: 5},
+		},
+		"operation in body": {
+			path:        "/",
+			body:        `{"operation": "schedule_refresh", "limit": 10}`,
+			wantCode:    http.StatusOK,
+			wantOp:      run.OperationScheduleRefresh,
+			wantRefresh: true,
+		},
+		"unknown operation": {
+			path:     "/delete_everything",
+			wantCode: http.StatusNotFound,
+			wantErr:  "not_found",
+		},
+		"unknown operation in body": {
+			path:     "/",
+			body:     `{"operation": "reconcile"}`,
+			wantCode: http.StatusNotFound,
+			wantErr:  "not_found",
+		},
+		"path and body disagree": {
+			path:     "/rss_sync",
+			body:     `{"operation": "schedule_refresh"}`,
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"malformed body": {
+			path:     "/",
+			body:     `{"operation": `,
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"unknown parameter": {
+			path:     "/rss_sync",
+			body:     `{"force": true}`,
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"channel outside the targets": {
+			path:     "/rss_sync",
+			body:     `{"channels": ["main_channel", "other_channel"]}`,
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"limit out of range": {
+			path:     "/rss_sync",
+			body:     `{"limit": 100}`,
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"parameter the operation does not take": {
+			path:     "/schedule_refresh",
+			body:     `{"dryRun": true}`,
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			svc := &optionsSyncService{}
+			h := NewCloudFunctionHandler(svc, testTargets("main_channel", "sub_channel"), lock.NewMemoryLocker(), &fakeSyncRunRepository{}, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+			refreshed := false
+			h.Register(run.OperationScheduleRefresh, Operation{
+				Run: func(_ context.Context, _ Params) error {
+					refreshed = true
+					return nil
+				},
+				LockKey:  "opus:schedule_refresh",
+				MaxLimit: 50,
+			})
+
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantRefresh, refreshed)
+			assert.Equal(t, tt.wantOptions != nil, svc.called)
+			if tt.wantOptions != nil {
+				if diff := cmp.Diff(*tt.wantOptions, svc.got); diff != "" {
+					t.Errorf("sync options mismatch (-want +got):\n%s", diff)
+				}
+			}
+
+			if tt.wantErr != "" {
+				var got errorResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+				assert.Equal(t, tt.wantErr, got.Error.Code)
+				assert.NotEmpty(t, got.Error.Message)
+				return
+			}
+			var got runReport
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			assert.Equal(t, tt.wantOp, got.Operation)
+			assert.Equal(t, "ok", got.Status)
+		})
+	}
+}
+
+func TestHandler_HandleOperationNotReady(t *testing.T) {
+	t.Parallel()
+
+	h := NewCloudFunctionHandler(nil, nil, lock.NewMemoryLocker(), &fakeSyncRunRepository{}, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+
+	// an invalid request is still answered as such while the operation is not ready
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodPost, "/rss_sync", strings.NewReader(`{"limit": -1}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodPost, "/rss_sync", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+type fakeStaleChecker struct {
+	got    service.StaleOptions
+	called bool
+}
+
+func (s *fakeStaleChecker) DetectStaleUpcoming(_ context.Context, opts service.StaleOptions) (*service.StaleReport, error) {
+	s.called = true
+	s.got = opts
+	return &service.StaleReport{DryRun: opts.DryRun}, nil
+}
+
+func TestStaleCheckOperation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body        string
+		wantCode    int
+		wantOptions *service.StaleOptions
+	}{
+		"all channels": {
+			wantCode:    http.StatusOK,
+			wantOptions: &service.StaleOptions{},
+		},
+		"dry run of a channel": {
+			body:        `{"channels": ["sub_channel"], "dryRun": true}`,
+			wantCode:    http.StatusOK,
+			wantOptions: &service.StaleOptions{ChannelIDs: []string{"sub_channel"}, DryRun: true},
+		},
+		"limit is not taken": {
+			body:     `{"limit": 5}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			svc := &fakeStaleChecker{}
+			h := NewCloudFunctionHandler(nil, testTargets("main_channel", "sub_channel"), lock.NewMemoryLocker(), &fakeSyncRunRepository{}, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+			h.Register(run.OperationStaleCheck, StaleCheckOperation(svc))
+
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest(http.MethodPost, "/stale_check", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantOptions != nil, svc.called)
+			if tt.wantOptions != nil {
+				if diff := cmp.Diff(*tt.wantOptions, svc.got); diff != "" {
+					t.Errorf("stale options mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+
+	// the operation is not ready without the service
+	h := NewCloudFunctionHandler(nil, nil, lock.NewMemoryLocker(), &fakeSyncRunRepository{}, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+	h.Register(run.OperationStaleCheck, StaleCheckOperation(nil))
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodPost, "/stale_check", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+type fakeRefresher struct {
+	got    map[string]service.RefreshOptions
+	called bool
+}
+
+func (s *fakeRefresher) RefreshSchedules(_ context.Context, opts service.RefreshOptions) error {
+	s.called = true
+	s.got[string(run.OperationScheduleRefresh)] = opts
+	return nil
+}
+
+func (s *fakeRefresher) Reconcile(_ context.Context, opts service.RefreshOptions) error {
+	s.called = true
+	s.got[string(run.OperationReconcile)] = opts
+	return nil
+}
+
+func TestRefreshOperations(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		path        string
+		body        string
+		wantCode    int
+		wantOptions *service.RefreshOptions
+	}{
+		"schedule refresh of every channel": {
+			path:        "/schedule_refresh",
+			wantCode:    http.StatusOK,
+			wantOptions: &service.RefreshOptions{},
+		},
+		"dry run of a reconcile in a channel": {
+			path:        "/reconcile",
+			body:        `{"channels": ["sub_channel"], "dryRun": true}`,
+			wantCode:    http.StatusOK,
+			wantOptions: &service.RefreshOptions{ChannelIDs: []string{"sub_channel"}, DryRun: true},
+		},
+		"limit is not taken": {
+			path:     "/reconcile",
+			body:     `{"limit": 5}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			svc := &fakeRefresher{got: make(map[string]service.RefreshOptions)}
+			h := NewCloudFunctionHandler(nil, testTargets("main_channel", "sub_channel"), lock.NewMemoryLocker(), &fakeSyncRunRepository{}, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+			h.Register(run.OperationScheduleRefresh, ScheduleRefreshOperation(svc))
+			h.Register(run.OperationReconcile, ReconcileOperation(svc))
+
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantOptions != nil, svc.called)
+			if tt.wantOptions != nil {
+				if diff := cmp.Diff(*tt.wantOptions, svc.got[strings.Trim(tt.path, "/")]); diff != "" {
+					t.Errorf("refresh options mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
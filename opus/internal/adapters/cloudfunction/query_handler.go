@@ -0,0 +1,307 @@
+package cloudfunction
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultQueryLimit = 50
+	maxQueryLimit     = 200
+)
+
+type QueryHandler struct {
+	repo     realtime.RealtimeQueryRepository
+	runs     realtime.SyncRunRepository
+	stats    realtime.StatsRepository
+	channels realtime.ChannelRepository
+}
+
+func NewQueryHandler(repo realtime.RealtimeQueryRepository, runs realtime.SyncRunRepository, stats realtime.StatsRepository, channels realtime.ChannelRepository) *QueryHandler {
+	return &QueryHandler{repo: repo, runs: runs, stats: stats, channels: channels}
+}
+
+type videoResponse struct {
+	SourceID    string        `json:"sourceId"`
+	ChannelID   string        `json:"channelId"`
+	Title       string        `json:"title"`
+	Status      status.Status `json:"status"`
+	Kind        kind.Kind     `json:"kind,omitempty"`
+	ChatID      string        `json:"chatId"`
+	ChatStatus  status.Chat   `json:"chatStatus,omitempty"`
+	PublishedAt *string       `json:"publishedAt"`
+	ScheduledAt *string       `json:"scheduledAt"`
+	// ActualStartAt and ActualEndAt are null until the broadcast starts and ends
+	ActualStartAt *string `json:"actualStartAt"`
+	ActualEndAt   *string `json:"actualEndAt"`
+	UpdatedAt     string  `json:"updatedAt"`
+}
+
+type listResponse struct {
+	Videos     []videoResponse `json:"videos"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type cursorPayload struct {
+	UpdatedAt time.Time `json:"u"`
+	SourceID  string    `json:"s"`
+}
+
+var errInvalidParameter = errors.New("invalid parameter")
+
+func (h *QueryHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", fmt.Sprintf("method %s is not allowed", r.Method))
+		return
+	}
+
+	if r.URL.Path == "/runs" {
+		h.listRuns(w, r)
+		return
+	}
+	if id, ok := strings.CutPrefix(r.URL.Path, "/runs/"); ok {
+		h.getRun(w, r, id)
+		return
+	}
+	if r.URL.Path == "/channels" {
+		h.listChannels(w, r)
+		return
+	}
+	if id, ok := strings.CutPrefix(r.URL.Path, "/stats/"); ok {
+		h.getStatsSeries(w, r, id)
+		return
+	}
+
+	h.listVideos(w, r)
+}
+
+func (h *QueryHandler) listVideos(w http.ResponseWriter, r *http.Request) {
+	q, err := parseVideoQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+
+	// fetch one extra record to know whether the next page exists
+	limit := q.Limit
+	q.Limit = limit + 1
+
+	records, err := h.repo.QueryRecords(r.Context(), q)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(
+			"Failed to query videos",
+			slog.Group("Query", "error", err),
+		)
+		writeError(w, http.StatusInternalServerError, "internal", "failed to query videos")
+		return
+	}
+
+	resp := listResponse{Videos: make([]videoResponse, 0, len(records))}
+	if len(records) > limit {
+		records = records[:limit]
+		last := records[len(records)-1]
+		resp.NextCursor = encodeCursor(realtime.Cursor{UpdatedAt: last.UpdatedAt, SourceID: last.SourceID})
+	}
+	for _, rec := range records {
+		resp.Videos = append(resp.Videos, toVideoResponse(rec))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func parseVideoQuery(v url.Values) (realtime.VideoQuery, error) {
+	q := realtime.VideoQuery{Limit: defaultQueryLimit}
+
+	for _, s := range splitValues(v["status"]) {
+		st, err := parseStatus(s)
+		if err != nil {
+			return q, err
+		}
+		q.Statuses = append(q.Statuses, st)
+	}
+
+	var err error
+	if q.Kinds, err = parseKinds(v, "kind"); err != nil {
+		return q, err
+	}
+	if q.ExcludeKinds, err = parseKinds(v, "excludeKind"); err != nil {
+		return q, err
+	}
+
+	q.ChannelIDs = splitValues(v["channel"])
+
+	if q.PublishedFrom, err = parseTimeParam(v, "publishedFrom"); err != nil {
+		return q, err
+	}
+	if q.PublishedTo, err = parseTimeParam(v, "publishedTo"); err != nil {
+		return q, err
+	}
+	if q.ScheduledFrom, err = parseTimeParam(v, "scheduledFrom"); err != nil {
+		return q, err
+	}
+	if q.ScheduledTo, err = parseTimeParam(v, "scheduledTo"); err != nil {
+		return q, err
+	}
+	if q.Since, err = parseTimeParam(v, "since"); err != nil {
+		return q, err
+	}
+
+	if q.PublishedFrom != nil && q.PublishedTo != nil && !q.PublishedFrom.Before(*q.PublishedTo) {
+		return q, fmt.Errorf("%w: publishedFrom must be before publishedTo", errInvalidParameter)
+	}
+	if q.ScheduledFrom != nil && q.ScheduledTo != nil && !q.ScheduledFrom.Before(*q.ScheduledTo) {
+		return q, fmt.Errorf("%w: scheduledFrom must be before scheduledTo", errInvalidParameter)
+	}
+
+	if c := v.Get("cursor"); c != "" {
+		cur, err := decodeCursor(c)
+		if err != nil {
+			return q, err
+		}
+		q.After = &cur
+	}
+
+	if l := v.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxQueryLimit {
+			return q, fmt.Errorf("%w: limit must be an integer between 1 and %d", errInvalidParameter, maxQueryLimit)
+		}
+		q.Limit = n
+	}
+
+	return q, nil
+}
+
+// splitValues accepts both repeated parameters and comma separated values
+func splitValues(values []string) []string {
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				res = append(res, s)
+			}
+		}
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+func parseTimeParam(v url.Values, key string) (*time.Time, error) {
+	s := v.Get(key)
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be RFC 3339 time", errInvalidParameter, key)
+	}
+
+	return &t, nil
+}
+
+func parseStatus(s string) (status.Status, error) {
+	st, err := status.Parse(s)
+	if err != nil {
+		return status.Undefined, fmt.Errorf("%w: unknown status %q", errInvalidParameter, s)
+	}
+
+	return st, nil
+}
+
+func parseKinds(v url.Values, key string) ([]kind.Kind, error) {
+	var kinds []kind.Kind
+	for _, s := range splitValues(v[key]) {
+		k, err := kind.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown %s %q", errInvalidParameter, key, s)
+		}
+		kinds = append(kinds, k)
+	}
+
+	return kinds, nil
+}
+
+func encodeCursor(c realtime.Cursor) string {
+	b, _ := json.Marshal(cursorPayload{UpdatedAt: c.UpdatedAt, SourceID: c.SourceID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (realtime.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return realtime.Cursor{}, fmt.Errorf("%w: malformed cursor", errInvalidParameter)
+	}
+
+	var p cursorPayload
+	if err := json.Unmarshal(b, &p); err != nil || p.SourceID == "" {
+		return realtime.Cursor{}, fmt.Errorf("%w: malformed cursor", errInvalidParameter)
+	}
+
+	return realtime.Cursor{UpdatedAt: p.UpdatedAt, SourceID: p.SourceID}, nil
+}
+
+func toVideoResponse(rec *realtime.Record) videoResponse {
+	return videoResponse{
+		SourceID:      rec.SourceID,
+		ChannelID:     rec.ChannelID,
+		Title:         rec.Title,
+		Status:        rec.Status,
+		Kind:          rec.Kind,
+		ChatID:        rec.ChatID,
+		ChatStatus:    rec.ChatStatus,
+		PublishedAt:   formatNillableTime(rec.PublishedAt),
+		ScheduledAt:   formatNillableTime(rec.ScheduledAt),
+		ActualStartAt: formatNillableTime(rec.ActualStartAt),
+		ActualEndAt:   formatNillableTime(rec.ActualEndAt),
+		UpdatedAt:     rec.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func formatNillableTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.UTC().Format(time.RFC3339)
+	return &s
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error(
+			"Failed to write response",
+			slog.Group("Query", "error", err),
+		)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, errCode, msg string) {
+	writeJSON(w, code, errorResponse{Error: errorBody{Code: errCode, Message: msg}})
+}
@@ -0,0 +1,311 @@
+package cloudfunction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeQueryRepository struct {
+	records []*realtime.Record
+	err     error
+	got     realtime.VideoQuery
+}
+
+func (f *fakeQueryRepository) QueryRecords(_ context.Context, q realtime.VideoQuery) ([]*realtime.Record, error) {
+	f.got = q
+	if f.err != nil {
+		return nil, f.err
+	}
+	if q.Limit > 0 && len(f.records) > q.Limit {
+		return f.records[:q.Limit], nil
+	}
+	return f.records, nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestQueryHandler_HandleSuccessfully(t *testing.T) {
+	t.Parallel()
+
+	records := []*realtime.Record{
+		{
+			SourceID:    "source1",
+			ChannelID:   "channel1",
+			Title:       "title1",
+			Status:      status.Upcoming,
+			ChatID:      "chat1",
+			PublishedAt: timePtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+			ScheduledAt: timePtr(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+			UpdatedAt:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			SourceID:  "source2",
+			ChannelID: "channel1",
+			Title:     "title2",
+			Status:    status.Archived,
+			UpdatedAt: time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC),
+		},
+	}
+
+	tests := map[string]struct {
+		target     string
+		wantQuery  realtime.VideoQuery
+		wantLen    int
+		wantCursor bool
+	}{
+		"default": {
+			target:    "/",
+			wantQuery: realtime.VideoQuery{Limit: defaultQueryLimit + 1},
+			wantLen:   2,
+		},
+		"filters": {
+			target: "/?status=Upcoming,live&channel=channel1&channel=channel2&scheduledFrom=2024-01-01T00:00:00Z&scheduledTo=2024-01-03T00:00:00Z&since=2024-01-01T00:00:00%2B09:00",
+			wantQuery: realtime.VideoQuery{
+				Statuses:      []status.Status{status.Upcoming, status.Live},
+				ChannelIDs:    []string{"channel1", "channel2"},
+				ScheduledFrom: timePtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				ScheduledTo:   timePtr(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)),
+				Since:         timePtr(time.Date(2023, 12, 31, 15, 0, 0, 0, time.UTC)),
+				Limit:         defaultQueryLimit + 1,
+			},
+			wantLen: 2,
+		},
+		"kinds": {
+			target: "/?kind=premiere,live&excludeKind=upload&excludeKind=short",
+			wantQuery: realtime.VideoQuery{
+				Kinds:        []kind.Kind{kind.Premiere, kind.Live},
+				ExcludeKinds: []kind.Kind{kind.Upload, kind.Short},
+				Limit:        defaultQueryLimit + 1,
+			},
+			wantLen: 2,
+		},
+		"paginated": {
+			target:     "/?limit=1",
+			wantQuery:  realtime.VideoQuery{Limit: 2},
+			wantLen:    1,
+			wantCursor: true,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			repo := &fakeQueryRepository{records: records}
+			h := NewQueryHandler(repo, &fakeSyncRunRepository{}, nil, nil)
+
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			opt := cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })
+			if diff := cmp.Diff(tt.wantQuery, repo.got, opt); diff != "" {
+				t.Errorf("QueryRecords() query mismatch (-want +got):\n%s", diff)
+			}
+
+			var got listResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			assert.Len(t, got.Videos, tt.wantLen)
+			assert.Equal(t, tt.wantCursor, got.NextCursor != "")
+		})
+	}
+}
+
+func TestQueryHandler_HandleResponseBody(t *testing.T) {
+	t.Parallel()
+
+	repo := &fakeQueryRepository{records: []*realtime.Record{
+		{
+			SourceID:    "source1",
+			ChannelID:   "channel1",
+			Title:       "title1",
+			Status:      status.Upcoming,
+			Kind:        kind.Premiere,
+			ChatID:      "chat1",
+			PublishedAt: timePtr(time.Date(2024, 1, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))),
+			UpdatedAt:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}}
+	h := NewQueryHandler(repo, &fakeSyncRunRepository{}, nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	publishedAt := "2024-01-01T00:00:00Z"
+	want := listResponse{
+		Videos: []videoResponse{
+			{
+				SourceID:    "source1",
+				ChannelID:   "channel1",
+				Title:       "title1",
+				Status:      status.Upcoming,
+				Kind:        kind.Premiere,
+				ChatID:      "chat1",
+				PublishedAt: &publishedAt,
+				ScheduledAt: nil,
+				UpdatedAt:   "2024-01-01T12:00:00Z",
+			},
+		},
+	}
+
+	var got listResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("response mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestQueryHandler_HandleCursorRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	repo := &fakeQueryRepository{records: []*realtime.Record{
+		{SourceID: "source1", Title: "title1", Status: status.Live, UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 123, time.UTC)},
+		{SourceID: "source2", Title: "title2", Status: status.Live, UpdatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}}
+	h := NewQueryHandler(repo, &fakeSyncRunRepository{}, nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodGet, "/?limit=1", nil))
+
+	var first listResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &first); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodGet, "/?limit=1&cursor="+first.NextCursor, nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotNil(t, repo.got.After) {
+		assert.Equal(t, "source1", repo.got.After.SourceID)
+		assert.True(t, time.Date(2024, 1, 1, 0, 0, 0, 123, time.UTC).Equal(repo.got.After.UpdatedAt))
+	}
+}
+
+func TestQueryHandler_HandleInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		method   string
+		target   string
+		wantCode int
+		wantErr  string
+	}{
+		"unknown status": {
+			method:   http.MethodGet,
+			target:   "/?status=deleted",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"unknown kind": {
+			method:   http.MethodGet,
+			target:   "/?kind=podcast",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"unknown excluded kind": {
+			method:   http.MethodGet,
+			target:   "/?excludeKind=Short",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"undefined status": {
+			method:   http.MethodGet,
+			target:   "/?status=undefined",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"invalid time": {
+			method:   http.MethodGet,
+			target:   "/?since=yesterday",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"reversed range": {
+			method:   http.MethodGet,
+			target:   "/?publishedFrom=2024-01-02T00:00:00Z&publishedTo=2024-01-01T00:00:00Z",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"limit too large": {
+			method:   http.MethodGet,
+			target:   "/?limit=1000",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"limit not a number": {
+			method:   http.MethodGet,
+			target:   "/?limit=ten",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"malformed cursor": {
+			method:   http.MethodGet,
+			target:   "/?cursor=bm90LWpzb24",
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid_parameter",
+		},
+		"method not allowed": {
+			method:   http.MethodPost,
+			target:   "/",
+			wantCode: http.StatusMethodNotAllowed,
+			wantErr:  "method_not_allowed",
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			h := NewQueryHandler(&fakeQueryRepository{}, &fakeSyncRunRepository{}, nil, nil)
+
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+
+			var got errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantErr, got.Error.Code)
+			assert.NotEmpty(t, got.Error.Message)
+		})
+	}
+}
+
+func TestQueryHandler_HandleRepositoryError(t *testing.T) {
+	t.Parallel()
+
+	h := NewQueryHandler(&fakeQueryRepository{err: errors.New("connection refused")}, &fakeSyncRunRepository{}, nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	var got errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "internal", got.Error.Code)
+	assert.NotContains(t, got.Error.Message, "connection refused")
+}
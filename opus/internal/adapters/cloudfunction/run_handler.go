@@ -0,0 +1,116 @@
+package cloudfunction
+
+import (
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRunLimit = 20
+	maxRunLimit     = 100
+)
+
+type syncRunResponse struct {
+	ID            int64                       `json:"id"`
+	Operation     string                      `json:"operation"`
+	Trigger       string                      `json:"trigger"`
+	Outcome       string                      `json:"outcome"`
+	StartedAt     string                      `json:"startedAt"`
+	FinishedAt    *string                     `json:"finishedAt"`
+	DurationMs    *int64                      `json:"durationMs"`
+	ChannelCounts map[string]run.ChannelCount `json:"channelCounts"`
+	APICalls      int                         `json:"apiCalls"`
+	QuotaUnits    int                         `json:"quotaUnits"`
+	Errors        []string                    `json:"errors"`
+}
+
+type runListResponse struct {
+	Runs []syncRunResponse `json:"runs"`
+}
+
+// listRuns returns the most recent sync runs first.
+func (h *QueryHandler) listRuns(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRunLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxRunLimit {
+			writeError(w, http.StatusBadRequest, "invalid_parameter", "limit must be an integer between 1 and "+strconv.Itoa(maxRunLimit))
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.runs.ListRuns(r.Context(), limit)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(
+			"Failed to list sync runs",
+			slog.Group("Query", "error", err),
+		)
+		writeError(w, http.StatusInternalServerError, "internal", "failed to list sync runs")
+		return
+	}
+
+	resp := runListResponse{Runs: make([]syncRunResponse, 0, len(runs))}
+	for _, sr := range runs {
+		resp.Runs = append(resp.Runs, toSyncRunResponse(sr))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *QueryHandler) getRun(w http.ResponseWriter, r *http.Request, rawID string) {
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil || id < 1 {
+		writeError(w, http.StatusBadRequest, "invalid_parameter", "run id must be a positive integer")
+		return
+	}
+
+	sr, err := h.runs.GetRun(r.Context(), id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(
+			"Failed to get sync run",
+			"id", id,
+			slog.Group("Query", "error", err),
+		)
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get sync run")
+		return
+	}
+	if sr == nil {
+		writeError(w, http.StatusNotFound, "not_found", "sync run "+rawID+" is not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toSyncRunResponse(sr))
+}
+
+func toSyncRunResponse(sr *realtime.SyncRun) syncRunResponse {
+	resp := syncRunResponse{
+		ID:            sr.ID,
+		Operation:     sr.Operation,
+		Trigger:       sr.Trigger,
+		Outcome:       sr.Outcome,
+		StartedAt:     sr.StartedAt.UTC().Format(time.RFC3339),
+		FinishedAt:    formatNillableTime(sr.FinishedAt),
+		ChannelCounts: sr.ChannelCounts,
+		APICalls:      sr.APICalls,
+		QuotaUnits:    sr.QuotaUnits,
+		Errors:        sr.Errors,
+	}
+	if resp.ChannelCounts == nil {
+		resp.ChannelCounts = map[string]run.ChannelCount{}
+	}
+	if resp.Errors == nil {
+		resp.Errors = []string{}
+	}
+	if sr.FinishedAt != nil {
+		d := sr.FinishedAt.Sub(sr.StartedAt).Milliseconds()
+		resp.DurationMs = &d
+	}
+
+	return resp
+}
@@ -0,0 +1,282 @@
+package cloudfunction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/service"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSyncRunRepository struct {
+	mu     sync.Mutex
+	runs   map[int64]run.Stats
+	nextID int64
+	err    error
+}
+
+func (f *fakeSyncRunRepository) StartRun(_ context.Context, s run.Stats) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.runs == nil {
+		f.runs = make(map[int64]run.Stats)
+	}
+	f.nextID++
+	f.runs[f.nextID] = s
+	return f.nextID, nil
+}
+
+func (f *fakeSyncRunRepository) FinishRun(_ context.Context, id int64, s run.Stats) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	f.runs[id] = s
+	return nil
+}
+
+func (f *fakeSyncRunRepository) ListRuns(_ context.Context, limit int) ([]*realtime.SyncRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	res := make([]*realtime.SyncRun, 0, limit)
+	for id := f.nextID; id > 0 && len(res) < limit; id-- {
+		res = append(res, toFakeSyncRun(id, f.runs[id]))
+	}
+	return res, nil
+}
+
+func (f *fakeSyncRunRepository) GetRun(_ context.Context, id int64) (*realtime.SyncRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	s, ok := f.runs[id]
+	if !ok {
+		return nil, nil
+	}
+	return toFakeSyncRun(id, s), nil
+}
+
+func toFakeSyncRun(id int64, s run.Stats) *realtime.SyncRun {
+	return &realtime.SyncRun{
+		ID:            id,
+		Operation:     string(s.Operation),
+		Trigger:       string(s.Trigger),
+		Outcome:       string(s.Outcome),
+		StartedAt:     s.StartedAt,
+		FinishedAt:    s.FinishedAt,
+		ChannelCounts: s.ChannelCounts,
+		APICalls:      s.APICalls,
+		QuotaUnits:    s.QuotaUnits,
+		Errors:        s.Errors,
+	}
+}
+
+type recordingSyncService struct {
+	err error
+}
+
+func (s *recordingSyncService) SyncVideosWithRSS(ctx context.Context, _ service.SyncOptions) error {
+	run.FromContext(ctx).AddFetched("channel1", 2)
+	run.FromContext(ctx).AddUpserted("channel1", 1)
+	run.FromContext(ctx).AddAPICall(1)
+	return s.err
+}
+
+func TestHandler_HandleRecordsRun(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		userAgent string
+		syncErr   error
+		held      bool
+		wantCode  int
+		want      run.Stats
+	}{
+		"succeeded": {
+			userAgent: "Google-Cloud-Scheduler",
+			wantCode:  http.StatusOK,
+			want: run.Stats{
+				Operation:     run.OperationRSSSync,
+				Trigger:       run.TriggerScheduler,
+				StartedAt:     start,
+				FinishedAt:    &start,
+				Outcome:       run.OutcomeSucceeded,
+				ChannelCounts: map[string]run.ChannelCount{"channel1": {Fetched: 2, Upserted: 1}},
+				APICalls:      1,
+				QuotaUnits:    1,
+			},
+		},
+		"failed": {
+			userAgent: "curl/8.0",
+			syncErr:   errors.New("quota exceeded"),
+			wantCode:  http.StatusInternalServerError,
+			want: run.Stats{
+				Operation:     run.OperationRSSSync,
+				Trigger:       run.TriggerManual,
+				StartedAt:     start,
+				FinishedAt:    &start,
+				Outcome:       run.OutcomeFailed,
+				ChannelCounts: map[string]run.ChannelCount{"channel1": {Fetched: 2, Upserted: 1}},
+				APICalls:      1,
+				QuotaUnits:    1,
+				Errors:        []string{"quota exceeded"},
+			},
+		},
+		"skipped": {
+			userAgent: "Google-Cloud-Scheduler",
+			held:      true,
+			wantCode:  http.StatusOK,
+			want: run.Stats{
+				Operation:     run.OperationRSSSync,
+				Trigger:       run.TriggerScheduler,
+				StartedAt:     start,
+				FinishedAt:    &start,
+				Outcome:       run.OutcomeSkipped,
+				ChannelCounts: map[string]run.ChannelCount{},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			locker := lock.NewMemoryLocker()
+			if tt.held {
+				_, err := locker.TryLock(context.Background(), syncLockKey)
+				assert.NoError(t, err)
+			}
+			runs := &fakeSyncRunRepository{}
+			h := NewCloudFunctionHandler(&recordingSyncService{err: tt.syncErr}, nil, locker, runs, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+			h.now = func() time.Time { return start }
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			rec := httptest.NewRecorder()
+			h.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Len(t, runs.runs, 1)
+			if diff := cmp.Diff(tt.want, runs.runs[1]); diff != "" {
+				t.Errorf("recorded run mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleLedgerFailure(t *testing.T) {
+	t.Parallel()
+
+	// the sync itself must succeed even if the ledger is unavailable
+	svc := &fakeSyncService{}
+	runs := &fakeSyncRunRepository{err: errors.New("connection refused")}
+	h := NewCloudFunctionHandler(svc, nil, lock.NewMemoryLocker(), runs, NewHealthHandler(nil, &fakeSyncStateRepository{}, nil, time.Hour))
+
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, svc.called)
+}
+
+func TestQueryHandler_HandleRuns(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(1500 * time.Millisecond)
+	runs := &fakeSyncRunRepository{}
+	for _, o := range []run.Outcome{run.OutcomeSucceeded, run.OutcomeFailed, run.OutcomeRunning} {
+		r := run.NewRecorder(run.OperationRSSSync, run.TriggerScheduler, start)
+		r.AddFetched("channel1", 1)
+		if o != run.OutcomeRunning {
+			r.Finish(o, end)
+		}
+		_, _ = runs.StartRun(context.Background(), r.Stats())
+	}
+	h := NewQueryHandler(&fakeQueryRepository{}, runs, nil, nil)
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+		rec := httptest.NewRecorder()
+		h.Handle(rec, httptest.NewRequest(http.MethodGet, "/runs?limit=2", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var got runListResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		ids := make([]int64, 0, len(got.Runs))
+		for _, r := range got.Runs {
+			ids = append(ids, r.ID)
+		}
+		assert.Equal(t, []int64{3, 2}, ids)
+		assert.Nil(t, got.Runs[0].FinishedAt)
+		assert.Nil(t, got.Runs[0].DurationMs)
+	})
+
+	t.Run("detail", func(t *testing.T) {
+		t.Parallel()
+		rec := httptest.NewRecorder()
+		h.Handle(rec, httptest.NewRequest(http.MethodGet, "/runs/1", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		finished := "2024-10-01T09:00:01Z"
+		duration := int64(1500)
+		want := syncRunResponse{
+			ID:            1,
+			Operation:     "rss_sync",
+			Trigger:       "scheduler",
+			Outcome:       "succeeded",
+			StartedAt:     "2024-10-01T09:00:00Z",
+			FinishedAt:    &finished,
+			DurationMs:    &duration,
+			ChannelCounts: map[string]run.ChannelCount{"channel1": {Fetched: 1, Upserted: 0}},
+			Errors:        []string{},
+		}
+		var got syncRunResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("response mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	errTests := map[string]struct {
+		path     string
+		wantCode int
+	}{
+		"not found":     {path: "/runs/99", wantCode: http.StatusNotFound},
+		"invalid id":    {path: "/runs/abc", wantCode: http.StatusBadRequest},
+		"invalid limit": {path: "/runs?limit=0", wantCode: http.StatusBadRequest},
+	}
+	for name, tt := range errTests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
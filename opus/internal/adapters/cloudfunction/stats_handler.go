@@ -0,0 +1,65 @@
+package cloudfunction
+
+import (
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type statsPointResponse struct {
+	TakenAt      string `json:"takenAt"`
+	ViewCount    int64  `json:"viewCount"`
+	LikeCount    int64  `json:"likeCount"`
+	CommentCount int64  `json:"commentCount"`
+	// ConcurrentViewers is null while the video is not live
+	ConcurrentViewers *int64 `json:"concurrentViewers"`
+}
+
+type statsSeriesResponse struct {
+	SourceID string               `json:"sourceId"`
+	Series   []statsPointResponse `json:"series"`
+}
+
+// getStatsSeries returns the statistics snapshots of a video in the order they were taken,
+// optionally bounded by the from and to parameters.
+func (h *QueryHandler) getStatsSeries(w http.ResponseWriter, r *http.Request, sourceID string) {
+	if sourceID == "" {
+		writeError(w, http.StatusBadRequest, "invalid_parameter", "source id is required")
+		return
+	}
+	from, err := parseTimeParam(r.URL.Query(), "from")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+	to, err := parseTimeParam(r.URL.Query(), "to")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_parameter", err.Error())
+		return
+	}
+
+	series, err := h.stats.GetStatsSeries(r.Context(), sourceID, from, to)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(
+			"Failed to get stats series",
+			"sourceID", sourceID,
+			slog.Group("Query", "error", err),
+		)
+		writeError(w, http.StatusInternalServerError, "internal", "failed to get stats series")
+		return
+	}
+
+	resp := statsSeriesResponse{SourceID: sourceID, Series: make([]statsPointResponse, 0, len(series))}
+	for _, s := range series {
+		resp.Series = append(resp.Series, statsPointResponse{
+			TakenAt:           s.TakenAt.UTC().Format(time.RFC3339),
+			ViewCount:         s.ViewCount,
+			LikeCount:         s.LikeCount,
+			CommentCount:      s.CommentCount,
+			ConcurrentViewers: s.ConcurrentViewers,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,78 @@
+package cloudfunction
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/stats"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryHandler_GetStatsSeries(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	viewers := int64(300)
+	repo := realtime.NewMemory()
+	snapshots := make([]stats.Snapshot, 0, 3)
+	for i, v := range []*int64{&viewers, &viewers, nil} {
+		s, err := stats.NewSnapshot("a", int64(1000*(i+1)), 10, 1, v, base.Add(time.Duration(i)*10*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshots = append(snapshots, *s)
+	}
+	if err := repo.InsertStatsSnapshots(context.Background(), snapshots); err != nil {
+		t.Fatal(err)
+	}
+	h := NewQueryHandler(&fakeQueryRepository{}, &fakeSyncRunRepository{}, repo, nil)
+
+	tests := map[string]struct {
+		path      string
+		wantCode  int
+		wantViews []int64
+	}{
+		"whole series":     {path: "/stats/a", wantCode: http.StatusOK, wantViews: []int64{1000, 2000, 3000}},
+		"bounded":          {path: "/stats/a?from=2024-06-01T00:05:00Z&to=2024-06-01T00:20:00Z", wantCode: http.StatusOK, wantViews: []int64{2000}},
+		"unknown video":    {path: "/stats/unknown", wantCode: http.StatusOK, wantViews: []int64{}},
+		"invalid time":     {path: "/stats/a?from=yesterday", wantCode: http.StatusBadRequest},
+		"without video ID": {path: "/stats/", wantCode: http.StatusBadRequest},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got statsSeriesResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			views := make([]int64, 0, len(got.Series))
+			for _, p := range got.Series {
+				views = append(views, p.ViewCount)
+			}
+			if diff := cmp.Diff(tt.wantViews, views); diff != "" {
+				t.Errorf("series mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// concurrent viewers are null once the video is not live
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodGet, "/stats/a", nil))
+	assert.JSONEq(t, `{"sourceId":"a","series":[
+		{"takenAt":"2024-06-01T00:00:00Z","viewCount":1000,"likeCount":10,"commentCount":1,"concurrentViewers":300},
+		{"takenAt":"2024-06-01T00:10:00Z","viewCount":2000,"likeCount":10,"commentCount":1,"concurrentViewers":300},
+		{"takenAt":"2024-06-01T00:20:00Z","viewCount":3000,"likeCount":10,"commentCount":1,"concurrentViewers":null}
+	]}`, rec.Body.String())
+}
@@ -0,0 +1,46 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"strings"
+)
+
+// Backend is everything Opus stores, implemented by Postgres, SQLite and memory.
+type Backend interface {
+	RealtimeRepository
+	RealtimeQueryRepository
+	SyncStateRepository
+	SyncRunRepository
+	FreeChatRepository
+	StatsRepository
+	ChannelRepository
+	TargetRepository
+	FilteredVideoRepository
+	Ping(ctx context.Context) error
+	Locker() lock.Locker
+	Migrate(ctx context.Context) error
+}
+
+var (
+	_ Backend = (*Realtime)(nil)
+	_ Backend = (*Memory)(nil)
+)
+
+// Open selects the backend by the scheme of dsn.
+//
+//	sqlite://path/to/opus.db  SQLite file, for local runs
+//	sqlite://:memory:         SQLite in memory
+//	memory://                 plain Go maps, for unit tests
+//
+// Any other DSN is passed to Postgres, which is the only backend used in production.
+func Open(ctx context.Context, dsn string) (Backend, error) {
+	if name, ok := strings.CutPrefix(dsn, "sqlite://"); ok {
+		return NewSQLiteClient(ctx, name)
+	}
+	if strings.HasPrefix(dsn, "memory://") {
+		return NewMemory(), nil
+	}
+
+	return NewRealtimeClient(dsn)
+}
@@ -0,0 +1,96 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/channel"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/uptrace/bun"
+	"log/slog"
+	"time"
+)
+
+// Channel is a row of the channels table, which videos.channel_id references.
+// A channel first stored by a video is a placeholder with only its ID, until the channel sync fills it.
+type Channel struct {
+	bun.BaseModel `bun:"table:channels"`
+
+	ID                string     `bun:",pk,type:varchar(255)"`
+	Title             string     `bun:",type:varchar(255)"`
+	Handle            string     `bun:",type:varchar(255)"`
+	AvatarURL         string     `bun:",type:text"`
+	SubscriberCount   *int64     `bun:",type:bigint"`
+	UploadsPlaylistID string     `bun:",type:varchar(255)"`
+	UpdatedAt         *time.Time `bun:",type:timestamptz"`
+}
+
+func toChannelModel(c *channel.Channel) *Channel {
+	updatedAt := c.UpdatedAt()
+	return &Channel{
+		ID:                c.ID(),
+		Title:             c.Title(),
+		Handle:            c.Handle(),
+		AvatarURL:         c.AvatarURL(),
+		SubscriberCount:   c.SubscriberCount(),
+		UploadsPlaylistID: c.UploadsPlaylistID(),
+		UpdatedAt:         &updatedAt,
+	}
+}
+
+// placeholderChannels are the channels of videos, with nothing but their IDs
+func placeholderChannels(videos []*Record) []*Channel {
+	seen := make(map[string]bool)
+	chs := make([]*Channel, 0)
+	for _, v := range videos {
+		if v.ChannelID == "" || seen[v.ChannelID] {
+			continue
+		}
+		seen[v.ChannelID] = true
+		chs = append(chs, &Channel{ID: v.ChannelID})
+	}
+
+	return chs
+}
+
+func (r *Realtime) UpsertChannels(ctx context.Context, channels []channel.Channel) error {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	models := make([]*Channel, 0, len(channels))
+	for _, c := range channels {
+		models = append(models, toChannelModel(&c))
+	}
+
+	if _, err := r.db.NewInsert().Model(&models).
+		On("conflict (id) do update").
+		Set("title = EXCLUDED.title").
+		Set("handle = EXCLUDED.handle").
+		Set("avatar_url = EXCLUDED.avatar_url").
+		Set("subscriber_count = EXCLUDED.subscriber_count").
+		Set("uploads_playlist_id = EXCLUDED.uploads_playlist_id").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to upsert channels",
+			"channels", len(channels),
+			slog.Group("Realtime", "error", err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// GetChannels returns every channel ordered by ID, including the placeholders.
+func (r *Realtime) GetChannels(ctx context.Context) ([]*Channel, error) {
+	channels := make([]*Channel, 0)
+	if err := r.db.NewSelect().Model(&channels).Order("id").Scan(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to get channels",
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	return channels, nil
+}
@@ -3,35 +3,85 @@ package realtime
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/migration"
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
+	"github.com/uptrace/bun/extra/bunotel"
 	"log/slog"
 )
 
 type Realtime struct {
-	db *bun.DB
+	db     *bun.DB
+	locker lock.Locker
 }
 
 func NewRealtimeClient(dsn string) (*Realtime, error) {
 	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
 	db := bun.NewDB(sqldb, pgdialect.New())
+	db.AddQueryHook(bunotel.NewQueryHook(bunotel.WithDBName("realtime")))
 
-	return &Realtime{db: db}, nil
+	return &Realtime{db: db, locker: lock.NewPostgresLocker(sqldb)}, nil
 }
 
+// Locker returns an advisory lock backed by the same database,
+// or a lock in the process for SQLite, which is only used by a single local instance.
+func (r *Realtime) Locker() lock.Locker {
+	return r.locker
+}
+
+// Migrate applies pending migrations. SQLite has no migration files, so its tables are created from the models.
+func (r *Realtime) Migrate(ctx context.Context) (err error) {
+	if r.db.Dialect().Name() == dialect.SQLite {
+		return r.createTables(ctx)
+	}
+
+	m, err := migration.NewPostgresRunner(ctx, r.db.DB)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, m.Close())
+	}()
+
+	return m.Up()
+}
+
+// upsertedColumns are the columns UpsertRecords overwrites on a stored video.
+// Its publication is kept, and so is its channel unless it was stored before channel_id existed.
+var upsertedColumns = []string{"title", "description", "status", "kind", "chat_status", "scheduled_at", "actual_start_at", "actual_end_at", "updated_at"}
+
 func (r *Realtime) UpsertRecords(ctx context.Context, videos []video.Video) error {
 	rec := make([]*Record, 0, len(videos))
 	for _, v := range videos {
 		rec = append(rec, toDBModel(&v))
 	}
 
-	if _, err := r.db.NewInsert().Model(&rec).
-		On("conflict (source_id) do update").
-		Set("source_id = EXCLUDED.source_id").
-		Exec(ctx); err != nil {
-		slog.Error(
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// videos.channel_id references channels, so an unknown channel is stored as a placeholder first
+		if chs := placeholderChannels(rec); len(chs) > 0 {
+			if _, err := tx.NewInsert().Model(&chs).On("conflict (id) do nothing").Exec(ctx); err != nil {
+				return err
+			}
+		}
+
+		// a sync brings the current state of a stored video, but a chat ID is kept once the API stops returning it
+		q := tx.NewInsert().Model(&rec).On("conflict (source_id) do update")
+		for _, c := range upsertedColumns {
+			q = q.Set("? = EXCLUDED.?", bun.Ident(c), bun.Ident(c))
+		}
+		_, err := q.Set("chat_id = COALESCE(NULLIF(EXCLUDED.chat_id, ''), ?TableAlias.chat_id)").
+			Set("channel_id = COALESCE(NULLIF(?TableAlias.channel_id, ''), EXCLUDED.channel_id)").
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error(
 			"Failed to upsert records into realtime",
 			"videos", videos,
 			slog.Group("Realtime", "error", err),
@@ -42,14 +92,46 @@ func (r *Realtime) UpsertRecords(ctx context.Context, videos []video.Video) erro
 	return nil
 }
 
-func (r *Realtime) GetRecordsBySourceIDs(ctx context.Context, sourceIDs []string) ([]*Record, error) {
+// updatableColumns are the columns UpdateRecords overwrites. The classification and the identity of a video are kept.
+var updatableColumns = []string{"status", "chat_id", "chat_status", "scheduled_at", "actual_start_at", "actual_end_at", "updated_at"}
+
+// UpdateRecords overwrites the state of videos that are already stored. A video that is not stored is ignored.
+// Like UpsertRecords, a chat ID is kept once the API stops returning it at the end of a stream.
+func (r *Realtime) UpdateRecords(ctx context.Context, videos []video.Video) error {
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, v := range videos {
+			rec := toDBModel(&v)
+			if _, err := tx.NewUpdate().
+				Model(rec).
+				Column(updatableColumns...).
+				Value("chat_id", "COALESCE(NULLIF(?, ''), ?TableAlias.chat_id)", rec.ChatID).
+				Where("source_id = ?", v.SourceID()).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to update records in realtime",
+			"videos", len(videos),
+			slog.Group("Realtime", "error", err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *Realtime) getRecordsBySourceIDs(ctx context.Context, sourceIDs []string) ([]*Record, error) {
 	records := make([]*Record, 0)
 	err := r.db.NewSelect().
 		Model(&records).
 		Where("source_id IN (?)", bun.In(sourceIDs)).
 		Scan(ctx)
 	if err != nil {
-		slog.Error(
+		logging.FromContext(ctx).Error(
 			"Failed to get records by source IDs",
 			"sourceIDs", sourceIDs,
 			slog.Group("Realtime", "error", err),
@@ -68,7 +150,7 @@ func (r *Realtime) GetLastUpdatedUnixOfVideo(ctx context.Context) (int64, error)
 		Limit(1).
 		Scan(ctx)
 	if err != nil {
-		slog.Error(
+		logging.FromContext(ctx).Error(
 			"Failed to get the last updated video",
 			slog.Group("Realtime", "error", err),
 		)
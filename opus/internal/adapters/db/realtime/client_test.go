@@ -2,20 +2,27 @@ package realtime
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Code-Hex/synchro"
 	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
-	"github.com/KasumiMercury/patotta-stone-functions-go/opus/status"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/test/migrate"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/test/testcontainers"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/google/go-cmp/cmp"
 	"log"
 	"os"
 	"testing"
 	"time"
 )
 
-var clt *Realtime
+var (
+	clt *Realtime
+	dsn string
+)
 
 func TestMain(m *testing.M) {
 	ctx := context.Background()
@@ -33,15 +40,19 @@ func TestMain(m *testing.M) {
 	// add SSL mode
 	connStr += "sslmode=disable"
 	fmt.Println(connStr)
+	dsn = connStr
 
 	clt, err = NewRealtimeClient(connStr)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if err := migrate.Migrate(connStr, "../../../../migrations"); err != nil {
+	if err := migrate.Migrate(connStr); err != nil {
 		log.Fatalf("failed to migrate: %v", err)
 	}
+	if err := migrate.Seed(connStr); err != nil {
+		log.Fatalf("failed to seed: %v", err)
+	}
 
 	code := m.Run()
 
@@ -52,7 +63,7 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
-func TestRealtime_GetRecordsBySourceIDs(t *testing.T) {
+func TestRealtime_getRecordsBySourceIDs(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
@@ -67,7 +78,7 @@ func TestRealtime_GetRecordsBySourceIDs(t *testing.T) {
 				{
 					SourceID:    "get_source_id",
 					Title:       "get_title",
-					Status:      "archived",
+					Status:      status.Archived,
 					ChatID:      "get_chat_id",
 					ScheduledAt: nil,
 					UpdatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
@@ -95,7 +106,7 @@ func TestRealtime_GetRecordsBySourceIDs(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := clt.GetRecordsBySourceIDs(context.Background(), tt.sourceIDs)
+			got, err := clt.getRecordsBySourceIDs(context.Background(), tt.sourceIDs)
 			if err != nil {
 				t.Errorf("error: %v", err)
 			}
@@ -126,6 +137,8 @@ func TestRealtime_UpsertRecords(t *testing.T) {
 					synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+					video.Live{},
+					kind.Unknown,
 				)
 
 				return []video.Video{*v}
@@ -144,6 +157,8 @@ func TestRealtime_UpsertRecords(t *testing.T) {
 					synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 					synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
 					synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+					video.Live{},
+					kind.Unknown,
 				)
 
 				return []video.Video{*v}
@@ -164,7 +179,7 @@ func TestRealtime_UpsertRecords(t *testing.T) {
 				srcIDs = append(srcIDs, v.SourceID())
 			}
 
-			records, err := clt.GetRecordsBySourceIDs(context.Background(), srcIDs)
+			records, err := clt.getRecordsBySourceIDs(context.Background(), srcIDs)
 			if err != nil {
 				t.Errorf("error: %v", err)
 			}
@@ -180,8 +195,8 @@ func TestRealtime_UpsertRecords(t *testing.T) {
 				if r.Title != tt.video[i].Title() {
 					t.Errorf("want: %v, got: %v", tt.video[i].Title(), r.Title)
 				}
-				if r.Status != tt.video[i].Status().String() {
-					t.Errorf("want: %v, got: %v", tt.video[i].Status().String(), r.Status)
+				if r.Status != tt.video[i].Status() {
+					t.Errorf("want: %v, got: %v", tt.video[i].Status(), r.Status)
 				}
 				if r.ChatID != tt.video[i].ChatID() {
 					t.Errorf("want: %v, got: %v", tt.video[i].ChatID(), r.ChatID)
@@ -194,6 +209,51 @@ func TestRealtime_UpsertRecords(t *testing.T) {
 	}
 }
 
+func TestRealtime_UpsertRecords_legacyChannel(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// a video stored before channel_id existed has a NULL channel since migration 000013
+	if _, err := clt.db.ExecContext(ctx, `INSERT INTO videos (source_id, title, status, published_at, updated_at) VALUES ('legacy_source_id', 'legacy_title', 'archived', ?, ?)`,
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	got, err := clt.GetVideosBySourceIDs(ctx, []string{"legacy_source_id"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(got) != 1 || got[0].ChannelID() != "" {
+		t.Fatalf("want the legacy video without a channel, got: %v", got)
+	}
+
+	// the next sync fills the channel in
+	v, _ := video.NewVideo(
+		"already_channel_id",
+		"legacy_source_id",
+		"legacy_title",
+		"",
+		"",
+		status.Archived,
+		synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		synchro.Time[tz.AsiaTokyo]{},
+		synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+		video.Live{},
+		kind.Unknown,
+	)
+	if err := clt.UpsertRecords(ctx, []video.Video{*v}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	records, err := clt.getRecordsBySourceIDs(ctx, []string{"legacy_source_id"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(records) != 1 || records[0].ChannelID != "already_channel_id" {
+		t.Errorf("want: %v, got: %v", "already_channel_id", records)
+	}
+}
+
 func TestRealtime_GetLastUpdatedUnixOfVideo(t *testing.T) {
 	t.Parallel()
 
@@ -221,3 +281,265 @@ func TestRealtime_GetLastUpdatedUnixOfVideo(t *testing.T) {
 		})
 	}
 }
+
+func TestRealtime_QueryRecords(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query VideoQuery
+		want  []string
+	}{
+		{
+			name: "first page ordered by updated_at and source_id",
+			query: VideoQuery{
+				Statuses: []status.Status{status.Archived},
+				Limit:    1,
+			},
+			want: []string{"already_exists"},
+		},
+		{
+			name: "since",
+			query: VideoQuery{
+				Statuses: []status.Status{status.Archived},
+				Since:    &since,
+			},
+			want: []string{"last_data"},
+		},
+		{
+			name: "after cursor",
+			query: VideoQuery{
+				After: &Cursor{UpdatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), SourceID: "last_data"},
+			},
+			want: []string{},
+		},
+		{
+			name: "no matching status",
+			query: VideoQuery{
+				Statuses: []status.Status{status.Live},
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := clt.QueryRecords(context.Background(), tt.query)
+			if err != nil {
+				t.Errorf("error: %v", err)
+			}
+
+			ids := make([]string, 0, len(got))
+			for _, r := range got {
+				ids = append(ids, r.SourceID)
+			}
+			if diff := cmp.Diff(tt.want, ids); diff != "" {
+				t.Errorf("QueryRecords() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRealtime_MarkChannelsSynced(t *testing.T) {
+	t.Parallel()
+
+	syncedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := clt.MarkChannelsSynced(context.Background(), []string{"synced_channel"}, syncedAt); err != nil {
+		t.Errorf("error: %v", err)
+	}
+	// marking again must update the existing state
+	syncedAt = syncedAt.Add(time.Hour)
+	if err := clt.MarkChannelsSynced(context.Background(), []string{"synced_channel"}, syncedAt); err != nil {
+		t.Errorf("error: %v", err)
+	}
+
+	states, err := clt.GetSyncStates(context.Background())
+	if err != nil {
+		t.Errorf("error: %v", err)
+	}
+
+	found := false
+	for _, s := range states {
+		if s.ChannelID != "synced_channel" {
+			continue
+		}
+		found = true
+		if !s.LastSyncedAt.Equal(syncedAt) {
+			t.Errorf("want: %v, got: %v", syncedAt, s.LastSyncedAt)
+		}
+	}
+	if !found {
+		t.Errorf("sync state of synced_channel is not found")
+	}
+}
+
+func TestRealtime_SyncRuns(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	startedAt := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	rec := run.NewRecorder(run.OperationRSSSync, run.TriggerScheduler, startedAt)
+	id, err := clt.StartRun(ctx, rec.Stats())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	rec.AddFetched("run_channel", 3)
+	rec.AddUpserted("run_channel", 2)
+	rec.AddAPICall(1)
+	rec.AddError(errors.New("partial failure"))
+	rec.Finish(run.OutcomeFailed, startedAt.Add(time.Minute))
+	if err := clt.FinishRun(ctx, id, rec.Stats()); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	got, err := clt.GetRun(ctx, id)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	finishedAt := startedAt.Add(time.Minute)
+	want := &SyncRun{
+		ID:            id,
+		Operation:     "rss_sync",
+		Trigger:       "scheduler",
+		Outcome:       "failed",
+		StartedAt:     startedAt,
+		FinishedAt:    &finishedAt,
+		ChannelCounts: map[string]run.ChannelCount{"run_channel": {Fetched: 3, Upserted: 2}},
+		APICalls:      1,
+		QuotaUnits:    1,
+		Errors:        []string{"partial failure"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetRun() mismatch (-want +got):\n%s", diff)
+	}
+
+	runs, err := clt.ListRuns(ctx, 10)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(runs) == 0 || runs[0].ID != id {
+		t.Errorf("the latest run must come first: %v", runs)
+	}
+
+	missing, err := clt.GetRun(ctx, id+1000)
+	if err != nil {
+		t.Errorf("error: %v", err)
+	}
+	if missing != nil {
+		t.Errorf("want: nil, got: %v", missing)
+	}
+}
+
+func TestRealtime_GetVideos(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	v, err := video.NewVideo(
+		"read_channel_id",
+		"read_source_id",
+		"read_title",
+		"read_description",
+		"read_chat_id",
+		status.Upcoming,
+		synchro.In[tz.AsiaTokyo](time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)),
+		synchro.In[tz.AsiaTokyo](time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)),
+		synchro.In[tz.AsiaTokyo](time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)),
+		video.Live{},
+		kind.Unknown,
+	)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := clt.UpsertRecords(ctx, []video.Video{*v}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	sourceIDs := func(videos []video.Video) []string {
+		ids := make([]string, 0, len(videos))
+		for _, v := range videos {
+			ids = append(ids, v.SourceID())
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name        string
+		read        func() ([]video.Video, error)
+		want        []string
+		wantInvalid []string
+	}{
+		{
+			name: "by source IDs",
+			read: func() ([]video.Video, error) {
+				return clt.GetVideosBySourceIDs(ctx, []string{"read_source_id"})
+			},
+			want: []string{"read_source_id"},
+		},
+		{
+			name: "rows without channel and published time are reported",
+			read: func() ([]video.Video, error) {
+				return clt.GetVideosBySourceIDs(ctx, []string{"read_source_id", "get_source_id"})
+			},
+			want:        []string{"read_source_id"},
+			wantInvalid: []string{"get_source_id"},
+		},
+		{
+			name: "by status",
+			read: func() ([]video.Video, error) {
+				return clt.GetVideosByStatus(ctx, status.Upcoming)
+			},
+			want: []string{"read_source_id"},
+		},
+		{
+			name: "by channel",
+			read: func() ([]video.Video, error) {
+				return clt.GetVideosByChannel(ctx, "read_channel_id")
+			},
+			want: []string{"read_source_id"},
+		},
+		{
+			name: "scheduled between",
+			read: func() ([]video.Video, error) {
+				return clt.GetVideosScheduledBetween(ctx, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC))
+			},
+			want: []string{"read_source_id"},
+		},
+		{
+			name: "scheduled outside the window",
+			read: func() ([]video.Video, error) {
+				return clt.GetVideosScheduledBetween(ctx, time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC))
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.read()
+
+			var invalid *InvalidRecordsError
+			if tt.wantInvalid == nil {
+				if err != nil {
+					t.Errorf("error: %v", err)
+				}
+			} else if !errors.As(err, &invalid) {
+				t.Errorf("want InvalidRecordsError, got: %v", err)
+			} else if diff := cmp.Diff(tt.wantInvalid, invalid.SourceIDs); diff != "" {
+				t.Errorf("invalid source IDs mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.want, sourceIDs(got)); diff != "" {
+				t.Errorf("source IDs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package realtime_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime/realtimetest"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/test/migrate"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+var databases atomic.Int64
+
+func TestPostgres_Contract(t *testing.T) {
+	t.Parallel()
+
+	realtimetest.Run(t, func(t *testing.T) realtime.Backend {
+		b, err := realtime.Open(context.Background(), newDatabase(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	})
+}
+
+// newDatabase creates an empty, migrated database in the container of TestMain,
+// so that the contract does not see the rows of the other tests
+func newDatabase(t *testing.T) string {
+	t.Helper()
+
+	name := fmt.Sprintf("contract_%d", databases.Add(1))
+	admin := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(realtime.PostgresDSN())))
+	defer admin.Close()
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	u, err := url.Parse(realtime.PostgresDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/" + name
+	if err := migrate.Migrate(u.String()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return u.String()
+}
@@ -0,0 +1,26 @@
+package realtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InvalidRecordsError reports rows that could not be rebuilt into valid videos.
+// The read that returns it still returns every valid video.
+type InvalidRecordsError struct {
+	SourceIDs []string
+	Errs      []error
+}
+
+func (e *InvalidRecordsError) Error() string {
+	return fmt.Sprintf("%d invalid video records: %s", len(e.SourceIDs), strings.Join(e.SourceIDs, ", "))
+}
+
+func (e *InvalidRecordsError) Unwrap() []error {
+	return e.Errs
+}
+
+func (e *InvalidRecordsError) add(sourceID string, err error) {
+	e.SourceIDs = append(e.SourceIDs, sourceID)
+	e.Errs = append(e.Errs, err)
+}
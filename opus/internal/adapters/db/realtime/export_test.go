@@ -0,0 +1,6 @@
+package realtime
+
+// PostgresDSN exposes the database of TestMain to the contract test, which lives in the external test package.
+func PostgresDSN() string {
+	return dsn
+}
@@ -0,0 +1,68 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/uptrace/bun"
+	"log/slog"
+	"time"
+)
+
+// FilteredVideo is a video dropped by the filters of its channel.
+// Fingerprint identifies the filters, so that the video is evaluated again once they change.
+type FilteredVideo struct {
+	bun.BaseModel `bun:"table:filtered_videos"`
+
+	SourceID    string    `bun:",pk,type:varchar(255)"`
+	ChannelID   string    `bun:",type:varchar(255)"`
+	Rule        string    `bun:",type:text"`
+	Fingerprint string    `bun:",type:varchar(255)"`
+	FilteredAt  time.Time `bun:",type:timestamptz"`
+}
+
+// RememberFilteredVideos records the videos, replacing the rule and the fingerprint of those filtered before.
+func (r *Realtime) RememberFilteredVideos(ctx context.Context, videos []*FilteredVideo) error {
+	if len(videos) == 0 {
+		return nil
+	}
+
+	if _, err := r.db.NewInsert().Model(&videos).
+		On("conflict (source_id) do update").
+		Set("channel_id = EXCLUDED.channel_id").
+		Set("rule = EXCLUDED.rule").
+		Set("fingerprint = EXCLUDED.fingerprint").
+		Set("filtered_at = EXCLUDED.filtered_at").
+		Exec(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to remember filtered videos",
+			"videos", len(videos),
+			slog.Group("Realtime", "error", err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// GetFilterFingerprints returns the fingerprint of the filters that dropped each video. A video never filtered is not in the map.
+func (r *Realtime) GetFilterFingerprints(ctx context.Context, sourceIDs []string) (map[string]string, error) {
+	fps := make(map[string]string)
+	if len(sourceIDs) == 0 {
+		return fps, nil
+	}
+
+	rows := make([]*FilteredVideo, 0)
+	if err := r.db.NewSelect().Model(&rows).Where("source_id IN (?)", bun.In(sourceIDs)).Scan(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to get filtered videos",
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	for _, v := range rows {
+		fps[v.SourceID] = v.Fingerprint
+	}
+
+	return fps, nil
+}
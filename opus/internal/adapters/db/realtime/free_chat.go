@@ -0,0 +1,62 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/schema"
+	"log/slog"
+)
+
+// FreeChat is the row of the free_chats table, whose schema is shared with Animus.
+type FreeChat = schema.FreeChat
+
+func toFreeChatModel(v *video.Video) *FreeChat {
+	return &FreeChat{
+		ChannelID: v.ChannelID(),
+		SourceID:  v.SourceID(),
+		ChatID:    v.ChatID(),
+		UpdatedAt: v.UpdatedAt().StdTime(),
+	}
+}
+
+// SetFreeChats makes each video the active free chat of its channel, replacing the previous one.
+func (r *Realtime) SetFreeChats(ctx context.Context, videos []video.Video) error {
+	if len(videos) == 0 {
+		return nil
+	}
+
+	chats := make([]*FreeChat, 0, len(videos))
+	for _, v := range videos {
+		chats = append(chats, toFreeChatModel(&v))
+	}
+
+	if _, err := r.db.NewInsert().Model(&chats).
+		On("conflict (channel_id) do update").
+		Set("source_id = EXCLUDED.source_id").
+		Set("chat_id = EXCLUDED.chat_id").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to set free chats",
+			"videos", len(videos),
+			slog.Group("Realtime", "error", err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *Realtime) GetFreeChats(ctx context.Context) ([]*FreeChat, error) {
+	chats := make([]*FreeChat, 0)
+	if err := r.db.NewSelect().Model(&chats).Order("channel_id").Scan(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to get free chats",
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	return chats, nil
+}
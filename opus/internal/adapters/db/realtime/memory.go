@@ -0,0 +1,483 @@
+package realtime
+
+import (
+	"cmp"
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/channel"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/stats"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory keeps everything in maps of the process. It is meant for unit tests,
+// and behaves like the SQL backends as the contract tests in realtimetest check.
+type Memory struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	states    map[string]SyncState
+	freeChats map[string]FreeChat
+	channels  map[string]Channel
+	targets   []Target
+	filtered  map[string]FilteredVideo
+	snapshots []StatsSnapshot
+	runs      []SyncRun
+	lastRunID int64
+	locker    lock.Locker
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		records:   make(map[string]Record),
+		states:    make(map[string]SyncState),
+		freeChats: make(map[string]FreeChat),
+		channels:  make(map[string]Channel),
+		filtered:  make(map[string]FilteredVideo),
+		locker:    lock.NewMemoryLocker(),
+	}
+}
+
+func (m *Memory) UpsertRecords(_ context.Context, videos []video.Video) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range videos {
+		if _, ok := m.channels[v.ChannelID()]; !ok {
+			m.channels[v.ChannelID()] = Channel{ID: v.ChannelID()}
+		}
+		u := toDBModel(&v)
+		r, ok := m.records[v.SourceID()]
+		if !ok {
+			m.records[v.SourceID()] = *u
+			continue
+		}
+		r.Title, r.Description, r.Status, r.Kind, r.ChatStatus = u.Title, u.Description, u.Status, u.Kind, u.ChatStatus
+		r.ScheduledAt, r.ActualStartAt, r.ActualEndAt = u.ScheduledAt, u.ActualStartAt, u.ActualEndAt
+		r.UpdatedAt = u.UpdatedAt
+		if u.ChatID != "" {
+			r.ChatID = u.ChatID
+		}
+		if r.ChannelID == "" {
+			r.ChannelID = u.ChannelID
+		}
+		m.records[v.SourceID()] = r
+	}
+
+	return nil
+}
+
+func (m *Memory) UpdateRecords(_ context.Context, videos []video.Video) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range videos {
+		r, ok := m.records[v.SourceID()]
+		if !ok {
+			continue
+		}
+		u := toDBModel(&v)
+		r.Status, r.ChatStatus = u.Status, u.ChatStatus
+		r.ScheduledAt, r.ActualStartAt, r.ActualEndAt = u.ScheduledAt, u.ActualStartAt, u.ActualEndAt
+		r.UpdatedAt = u.UpdatedAt
+		if u.ChatID != "" {
+			r.ChatID = u.ChatID
+		}
+		m.records[v.SourceID()] = r
+	}
+
+	return nil
+}
+
+func (m *Memory) GetVideosBySourceIDs(ctx context.Context, sourceIDs []string) ([]video.Video, error) {
+	return m.selectVideos(ctx, func(r *Record) bool {
+		return slices.Contains(sourceIDs, r.SourceID)
+	})
+}
+
+func (m *Memory) GetVideosByStatus(ctx context.Context, statuses ...status.Status) ([]video.Video, error) {
+	return m.selectVideos(ctx, func(r *Record) bool {
+		return slices.Contains(statuses, r.Status)
+	})
+}
+
+func (m *Memory) GetVideosByChannel(ctx context.Context, channelID string) ([]video.Video, error) {
+	return m.selectVideos(ctx, func(r *Record) bool {
+		return r.ChannelID == channelID
+	})
+}
+
+// GetVideosScheduledBetween returns the videos scheduled in [from, to).
+func (m *Memory) GetVideosScheduledBetween(ctx context.Context, from, to time.Time) ([]video.Video, error) {
+	return m.selectVideos(ctx, func(r *Record) bool {
+		return r.ScheduledAt != nil && !r.ScheduledAt.Before(from) && r.ScheduledAt.Before(to)
+	})
+}
+
+func (m *Memory) selectVideos(ctx context.Context, cond func(*Record) bool) ([]video.Video, error) {
+	records := m.filter(cond)
+	slices.SortFunc(records, func(a, b *Record) int {
+		return strings.Compare(a.SourceID, b.SourceID)
+	})
+
+	return toVideos(ctx, records)
+}
+
+func (m *Memory) GetLastUpdatedUnixOfVideo(_ context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var last time.Time
+	for _, r := range m.records {
+		if r.UpdatedAt.After(last) {
+			last = r.UpdatedAt
+		}
+	}
+	if last.IsZero() {
+		return 0, nil
+	}
+
+	return last.Unix(), nil
+}
+
+func (m *Memory) QueryRecords(_ context.Context, q VideoQuery) ([]*Record, error) {
+	records := m.filter(func(r *Record) bool {
+		switch {
+		case len(q.Statuses) > 0 && !slices.Contains(q.Statuses, r.Status):
+			return false
+		case len(q.Kinds) > 0 && !slices.Contains(q.Kinds, r.Kind):
+			return false
+		case slices.Contains(q.ExcludeKinds, r.Kind):
+			return false
+		case len(q.ChannelIDs) > 0 && !slices.Contains(q.ChannelIDs, r.ChannelID):
+			return false
+		case q.PublishedFrom != nil && (r.PublishedAt == nil || r.PublishedAt.Before(*q.PublishedFrom)):
+			return false
+		case q.PublishedTo != nil && (r.PublishedAt == nil || !r.PublishedAt.Before(*q.PublishedTo)):
+			return false
+		case q.ScheduledFrom != nil && (r.ScheduledAt == nil || r.ScheduledAt.Before(*q.ScheduledFrom)):
+			return false
+		case q.ScheduledTo != nil && (r.ScheduledAt == nil || !r.ScheduledAt.Before(*q.ScheduledTo)):
+			return false
+		case q.Since != nil && !r.UpdatedAt.After(*q.Since):
+			return false
+		case q.After != nil && compareCursor(r, q.After) <= 0:
+			return false
+		}
+		return true
+	})
+
+	slices.SortFunc(records, func(a, b *Record) int {
+		return compareCursor(a, &Cursor{UpdatedAt: b.UpdatedAt, SourceID: b.SourceID})
+	})
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[:q.Limit]
+	}
+
+	return records, nil
+}
+
+// filter returns copies of the records that satisfy cond, so that callers cannot change the stored ones
+func (m *Memory) filter(cond func(*Record) bool) []*Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := make([]*Record, 0)
+	for _, r := range m.records {
+		r := r
+		if cond(&r) {
+			records = append(records, &r)
+		}
+	}
+
+	return records
+}
+
+func (m *Memory) MarkChannelsSynced(_ context.Context, channelIDs []string, syncedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range channelIDs {
+		st := m.states[c]
+		st.ChannelID, st.LastSyncedAt = c, syncedAt
+		m.states[c] = st
+	}
+
+	return nil
+}
+
+func (m *Memory) SetRSSWatermarks(_ context.Context, watermarks map[string]time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for c, w := range watermarks {
+		st, ok := m.states[c]
+		if !ok {
+			continue
+		}
+		w := w
+		st.RSSWatermark = &w
+		m.states[c] = st
+	}
+
+	return nil
+}
+
+func (m *Memory) GetSyncStates(_ context.Context) ([]*SyncState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := make([]*SyncState, 0, len(m.states))
+	for _, st := range m.states {
+		st := st
+		states = append(states, &st)
+	}
+	slices.SortFunc(states, func(a, b *SyncState) int {
+		return strings.Compare(a.ChannelID, b.ChannelID)
+	})
+
+	return states, nil
+}
+
+func (m *Memory) SetFreeChats(_ context.Context, videos []video.Video) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range videos {
+		m.freeChats[v.ChannelID()] = *toFreeChatModel(&v)
+	}
+
+	return nil
+}
+
+func (m *Memory) GetFreeChats(_ context.Context) ([]*FreeChat, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chats := make([]*FreeChat, 0, len(m.freeChats))
+	for _, c := range m.freeChats {
+		c := c
+		chats = append(chats, &c)
+	}
+	slices.SortFunc(chats, func(a, b *FreeChat) int {
+		return strings.Compare(a.ChannelID, b.ChannelID)
+	})
+
+	return chats, nil
+}
+
+func (m *Memory) UpsertChannels(_ context.Context, channels []channel.Channel) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range channels {
+		m.channels[c.ID()] = *toChannelModel(&c)
+	}
+
+	return nil
+}
+
+func (m *Memory) GetChannels(_ context.Context) ([]*Channel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	channels := make([]*Channel, 0, len(m.channels))
+	for _, c := range m.channels {
+		c := c
+		channels = append(channels, &c)
+	}
+	slices.SortFunc(channels, func(a, b *Channel) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return channels, nil
+}
+
+func (m *Memory) LoadTargets(_ context.Context) ([]config.Channel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chs := make([]config.Channel, 0, len(m.targets))
+	for _, t := range m.targets {
+		chs = append(chs, t.toChannel())
+	}
+
+	return chs, nil
+}
+
+func (m *Memory) SaveTargets(_ context.Context, channels []config.Channel) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.targets = make([]Target, 0, len(channels))
+	for i, c := range channels {
+		t := toTargetModel(c)
+		t.ID = int64(i + 1)
+		m.targets = append(m.targets, *t)
+	}
+
+	return nil
+}
+
+func (m *Memory) InsertStatsSnapshots(_ context.Context, snapshots []stats.Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range snapshots {
+		sm := toStatsSnapshotModel(&s)
+		sm.ID = int64(len(m.snapshots) + 1)
+		m.snapshots = append(m.snapshots, *sm)
+	}
+
+	return nil
+}
+
+func (m *Memory) RememberFilteredVideos(_ context.Context, videos []*FilteredVideo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range videos {
+		m.filtered[v.SourceID] = *v
+	}
+
+	return nil
+}
+
+func (m *Memory) GetFilterFingerprints(_ context.Context, sourceIDs []string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fps := make(map[string]string)
+	for _, id := range sourceIDs {
+		if v, ok := m.filtered[id]; ok {
+			fps[id] = v.Fingerprint
+		}
+	}
+
+	return fps, nil
+}
+
+func (m *Memory) GetLastStatsSnapshotTimes(_ context.Context, sourceIDs []string) (map[string]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last := make(map[string]time.Time)
+	for _, s := range m.snapshots {
+		if slices.Contains(sourceIDs, s.SourceID) && s.TakenAt.After(last[s.SourceID]) {
+			last[s.SourceID] = s.TakenAt
+		}
+	}
+
+	return last, nil
+}
+
+func (m *Memory) GetStatsSeries(_ context.Context, sourceID string, from, to *time.Time) ([]*StatsSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	series := make([]*StatsSnapshot, 0)
+	for _, s := range m.snapshots {
+		if s.SourceID != sourceID || (from != nil && s.TakenAt.Before(*from)) || (to != nil && !s.TakenAt.Before(*to)) {
+			continue
+		}
+		s := s
+		series = append(series, &s)
+	}
+	slices.SortStableFunc(series, func(a, b *StatsSnapshot) int {
+		return a.TakenAt.Compare(b.TakenAt)
+	})
+
+	return series, nil
+}
+
+// StartRun inserts a new run and returns its ID.
+func (m *Memory) StartRun(_ context.Context, s run.Stats) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastRunID++
+	sr := toSyncRunModel(s)
+	sr.ID = m.lastRunID
+	m.runs = append(m.runs, *sr)
+
+	return sr.ID, nil
+}
+
+// FinishRun overwrites the run with its final statistics.
+func (m *Memory) FinishRun(_ context.Context, id int64, s run.Stats) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.runs {
+		if m.runs[i].ID == id {
+			sr := toSyncRunModel(s)
+			sr.ID = id
+			m.runs[i] = *sr
+		}
+	}
+
+	return nil
+}
+
+// ListRuns returns the most recent runs first.
+func (m *Memory) ListRuns(_ context.Context, limit int) ([]*SyncRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runs := make([]*SyncRun, 0, len(m.runs))
+	for _, r := range m.runs {
+		r := r
+		runs = append(runs, &r)
+	}
+	slices.SortFunc(runs, func(a, b *SyncRun) int {
+		if c := b.StartedAt.Compare(a.StartedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+
+	return runs, nil
+}
+
+// GetRun returns nil without an error if the run does not exist.
+func (m *Memory) GetRun(_ context.Context, id int64) (*SyncRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.runs {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *Memory) Ping(_ context.Context) error {
+	return nil
+}
+
+func (m *Memory) Locker() lock.Locker {
+	return m.locker
+}
+
+// Migrate has nothing to do, since maps need no schema.
+func (m *Memory) Migrate(_ context.Context) error {
+	return nil
+}
+
+// compareCursor orders records the way the SQL backends do, by updated_at and then source_id
+func compareCursor(r *Record, c *Cursor) int {
+	if d := r.UpdatedAt.Compare(c.UpdatedAt); d != 0 {
+		return d
+	}
+	return strings.Compare(r.SourceID, c.SourceID)
+}
@@ -0,0 +1,87 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/uptrace/bun"
+	"log/slog"
+	"time"
+)
+
+// VideoQuery is a set of conditions to list records of the videos table.
+// Records are always ordered by updated_at and source_id in ascending order,
+// so that a client can resume from the last record it has seen.
+type VideoQuery struct {
+	Statuses      []status.Status
+	Kinds         []kind.Kind
+	ExcludeKinds  []kind.Kind
+	ChannelIDs    []string
+	PublishedFrom *time.Time
+	PublishedTo   *time.Time
+	ScheduledFrom *time.Time
+	ScheduledTo   *time.Time
+	Since         *time.Time
+	After         *Cursor
+	Limit         int
+}
+
+// Cursor points to the last record of the previous page.
+type Cursor struct {
+	UpdatedAt time.Time
+	SourceID  string
+}
+
+func (r *Realtime) QueryRecords(ctx context.Context, q VideoQuery) ([]*Record, error) {
+	records := make([]*Record, 0)
+	query := r.db.NewSelect().Model(&records)
+
+	if len(q.Statuses) > 0 {
+		query = query.Where("status IN (?)", bun.In(q.Statuses))
+	}
+	if len(q.Kinds) > 0 {
+		query = query.Where("kind IN (?)", bun.In(q.Kinds))
+	}
+	if len(q.ExcludeKinds) > 0 {
+		query = query.Where("kind NOT IN (?)", bun.In(q.ExcludeKinds))
+	}
+	if len(q.ChannelIDs) > 0 {
+		query = query.Where("channel_id IN (?)", bun.In(q.ChannelIDs))
+	}
+	if q.PublishedFrom != nil {
+		query = query.Where("published_at >= ?", *q.PublishedFrom)
+	}
+	if q.PublishedTo != nil {
+		query = query.Where("published_at < ?", *q.PublishedTo)
+	}
+	if q.ScheduledFrom != nil {
+		query = query.Where("scheduled_at >= ?", *q.ScheduledFrom)
+	}
+	if q.ScheduledTo != nil {
+		query = query.Where("scheduled_at < ?", *q.ScheduledTo)
+	}
+	if q.Since != nil {
+		query = query.Where("updated_at > ?", *q.Since)
+	}
+	if q.After != nil {
+		query = query.Where("(updated_at, source_id) > (?, ?)", q.After.UpdatedAt, q.After.SourceID)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	err := query.
+		Order("updated_at ASC", "source_id ASC").
+		Scan(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to query records",
+			"query", q,
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -4,30 +4,57 @@ import (
 	"github.com/Code-Hex/synchro"
 	"github.com/Code-Hex/synchro/tz"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
-	"github.com/uptrace/bun"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/schema"
 	"time"
 )
 
-type Record struct {
-	bun.BaseModel `bun:"table:videos"`
-
-	SourceID    string     `bun:",type:varchar(255),unique"`
-	Title       string     `bun:",type:varchar(255)"`
-	Status      string     `bun:",type:varchar(255)"`
-	ChatID      string     `bun:",type:varchar(255)"`
-	ScheduledAt *time.Time `bun:",type:timestamptz"`
-	UpdatedAt   time.Time  `bun:",type:timestamptz"`
-}
+// Record is the row of the videos table, whose schema is shared with Animus.
+type Record = schema.Video
 
 func toDBModel(v *video.Video) *Record {
 	return &Record{
-		SourceID:    v.SourceID(),
-		Title:       v.Title(),
-		Status:      v.Status().String(),
-		ChatID:      v.ChatID(),
-		ScheduledAt: synchroTimeToNillableTime(v.ScheduledAt()),
-		UpdatedAt:   v.UpdatedAt().StdTime(),
+		SourceID:      v.SourceID(),
+		ChannelID:     v.ChannelID(),
+		Title:         v.Title(),
+		Description:   v.Description(),
+		Status:        v.Status(),
+		Kind:          v.Kind(),
+		ChatID:        v.ChatID(),
+		ChatStatus:    v.ChatStatus(),
+		PublishedAt:   synchroTimeToNillableTime(v.PublishedAt()),
+		ScheduledAt:   synchroTimeToNillableTime(v.ScheduledAt()),
+		ActualStartAt: synchroTimeToNillableTime(v.ActualStartAt()),
+		ActualEndAt:   synchroTimeToNillableTime(v.ActualEndAt()),
+		UpdatedAt:     v.UpdatedAt().StdTime(),
+	}
+}
+
+// fromDBModel rebuilds a video from a record, so that it is validated the same way as a fetched one.
+func fromDBModel(r *Record) (*video.Video, error) {
+	return video.Rehydrate(video.Snapshot{
+		ChannelID:   r.ChannelID,
+		SourceID:    r.SourceID,
+		Title:       r.Title,
+		Description: r.Description,
+		ChatID:      r.ChatID,
+		Status:      r.Status,
+		Kind:        r.Kind,
+		PublishedAt: nillableTimeToSynchroTime(r.PublishedAt),
+		ScheduledAt: nillableTimeToSynchroTime(r.ScheduledAt),
+		UpdatedAt:   synchro.In[tz.AsiaTokyo](r.UpdatedAt),
+		Live: video.Live{
+			ChatStatus:    r.ChatStatus,
+			ActualStartAt: nillableTimeToSynchroTime(r.ActualStartAt),
+			ActualEndAt:   nillableTimeToSynchroTime(r.ActualEndAt),
+		},
+	})
+}
+
+func nillableTimeToSynchroTime(t *time.Time) synchro.Time[tz.AsiaTokyo] {
+	if t == nil {
+		return synchro.Time[tz.AsiaTokyo]{}
 	}
+	return synchro.In[tz.AsiaTokyo](*t)
 }
 
 func synchroTimeToNillableTime(t synchro.Time[tz.AsiaTokyo]) *time.Time {
@@ -1,10 +1,13 @@
 package realtime
 
 import (
+	"context"
+	"errors"
 	"github.com/Code-Hex/synchro"
 	"github.com/Code-Hex/synchro/tz"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
-	"github.com/KasumiMercury/patotta-stone-functions-go/opus/status"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 	"github.com/google/go-cmp/cmp"
 	"testing"
 	"time"
@@ -34,17 +37,30 @@ func Test_toDBModel(t *testing.T) {
 						synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 						synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 						synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+						video.Live{
+							ChatStatus:    status.ChatEnded,
+							ActualStartAt: synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)),
+							ActualEndAt:   synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)),
+						},
+						kind.Live,
 					)
 					return v
 				}(),
 			},
 			want: &Record{
-				SourceID:    "sourceID",
-				Title:       "title",
-				Status:      status.Archived.String(),
-				ChatID:      "chatID",
-				ScheduledAt: timeToPtr(utcToJST(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))),
-				UpdatedAt:   utcToJST(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				SourceID:      "sourceID",
+				ChannelID:     "channelID",
+				Title:         "title",
+				Description:   "description",
+				Status:        status.Archived,
+				Kind:          kind.Live,
+				ChatID:        "chatID",
+				ChatStatus:    status.ChatEnded,
+				PublishedAt:   timeToPtr(utcToJST(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))),
+				ScheduledAt:   timeToPtr(utcToJST(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))),
+				ActualStartAt: timeToPtr(utcToJST(time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC))),
+				ActualEndAt:   timeToPtr(utcToJST(time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC))),
+				UpdatedAt:     utcToJST(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 			},
 		},
 		{
@@ -61,15 +77,20 @@ func Test_toDBModel(t *testing.T) {
 						synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 						synchro.In[tz.AsiaTokyo](time.Time{}),
 						synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+						video.Live{},
+						kind.Unknown,
 					)
 					return v
 				}(),
 			},
 			want: &Record{
 				SourceID:    "sourceID",
+				ChannelID:   "channelID",
 				Title:       "title",
-				Status:      status.Archived.String(),
+				Description: "description",
+				Status:      status.Archived,
 				ChatID:      "chatID",
+				PublishedAt: timeToPtr(utcToJST(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))),
 				ScheduledAt: nil,
 				UpdatedAt:   utcToJST(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
 			},
@@ -93,3 +114,162 @@ func timeToPtr(t time.Time) *time.Time {
 func utcToJST(t time.Time) time.Time {
 	return t.In(time.FixedZone("JST", 9*60*60))
 }
+
+func Test_fromDBModel(t *testing.T) {
+	t.Parallel()
+
+	jst := func(y int, m time.Month, d int) synchro.Time[tz.AsiaTokyo] {
+		return synchro.In[tz.AsiaTokyo](time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+	}
+
+	tests := []struct {
+		name    string
+		record  *Record
+		want    *video.Video
+		wantErr bool
+	}{
+		{
+			name: "normal",
+			record: &Record{
+				SourceID:    "sourceID",
+				ChannelID:   "channelID",
+				Title:       "title",
+				Description: "description",
+				Status:      status.Upcoming,
+				ChatID:      "chatID",
+				PublishedAt: timeToPtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				ScheduledAt: timeToPtr(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+				UpdatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			want: func() *video.Video {
+				v, _ := video.NewVideo("channelID", "sourceID", "title", "description", "chatID", status.Upcoming, jst(2024, 1, 1), jst(2024, 1, 2), jst(2024, 1, 1), video.Live{}, kind.Unknown)
+				return v
+			}(),
+		},
+		{
+			name: "lower case status and no scheduledAt",
+			record: &Record{
+				SourceID:    "sourceID",
+				ChannelID:   "channelID",
+				Title:       "title",
+				Status:      status.Archived,
+				PublishedAt: timeToPtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				UpdatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			want: func() *video.Video {
+				v, _ := video.NewVideo("channelID", "sourceID", "title", "", "", status.Archived, jst(2024, 1, 1), synchro.Time[tz.AsiaTokyo]{}, jst(2024, 1, 1), video.Live{}, kind.Unknown)
+				return v
+			}(),
+		},
+		{
+			name: "live broadcast",
+			record: &Record{
+				SourceID:      "sourceID",
+				ChannelID:     "channelID",
+				Title:         "title",
+				Status:        status.Live,
+				Kind:          kind.Live,
+				ChatID:        "chatID",
+				ChatStatus:    status.ChatEnabled,
+				PublishedAt:   timeToPtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				ScheduledAt:   timeToPtr(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+				ActualStartAt: timeToPtr(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+				UpdatedAt:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			},
+			want: func() *video.Video {
+				v, _ := video.NewVideo("channelID", "sourceID", "title", "", "chatID", status.Live, jst(2024, 1, 1), jst(2024, 1, 2), jst(2024, 1, 2), video.Live{ChatStatus: status.ChatEnabled, ActualStartAt: jst(2024, 1, 2)}, kind.Live)
+				return v
+			}(),
+		},
+		{
+			name: "actualEndAt without actualStartAt",
+			record: &Record{
+				SourceID:    "sourceID",
+				ChannelID:   "channelID",
+				Title:       "title",
+				Status:      status.Archived,
+				PublishedAt: timeToPtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				ActualEndAt: timeToPtr(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+				UpdatedAt:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: true,
+		},
+		{
+			name: "undefined status",
+			record: &Record{
+				SourceID:    "sourceID",
+				ChannelID:   "channelID",
+				Title:       "title",
+				Status:      status.Undefined,
+				PublishedAt: timeToPtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				UpdatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			wantErr: true,
+		},
+		{
+			name: "row written before channel_id existed",
+			record: &Record{
+				SourceID:    "sourceID",
+				Title:       "title",
+				Status:      status.Archived,
+				PublishedAt: timeToPtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				UpdatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			want: func() *video.Video {
+				v, _ := video.Rehydrate(video.Snapshot{SourceID: "sourceID", Title: "title", Status: status.Archived, PublishedAt: jst(2024, 1, 1), UpdatedAt: jst(2024, 1, 1)})
+				return v
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := fromDBModel(tt.record)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fromDBModel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(toDBModel(tt.want), toDBModel(got)); diff != "" {
+				t.Errorf("fromDBModel() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_toVideos(t *testing.T) {
+	t.Parallel()
+
+	valid := &Record{
+		SourceID:    "valid",
+		ChannelID:   "channelID",
+		Title:       "title",
+		Status:      status.Archived,
+		PublishedAt: timeToPtr(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		UpdatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	noTitle := *valid
+	noTitle.SourceID = "no_title"
+	noTitle.Title = ""
+	badStatus := *valid
+	badStatus.SourceID = "bad_status"
+	badStatus.Status = status.Undefined
+
+	got, err := toVideos(context.Background(), []*Record{valid, &noTitle, &badStatus})
+
+	if len(got) != 1 || got[0].SourceID() != "valid" {
+		t.Errorf("want only the valid video, got: %v", got)
+	}
+	var invalid *InvalidRecordsError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("want InvalidRecordsError, got: %v", err)
+	}
+	if diff := cmp.Diff([]string{"no_title", "bad_status"}, invalid.SourceIDs); diff != "" {
+		t.Errorf("invalid source IDs mismatch (-want +got):\n%s", diff)
+	}
+	if want := "2 invalid video records: no_title, bad_status"; err.Error() != want {
+		t.Errorf("want: %q, got: %q", want, err.Error())
+	}
+}
@@ -2,16 +2,71 @@ package realtime
 
 import (
 	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/channel"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/stats"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 	"time"
 )
 
 type RealtimeRepository interface {
 	UpsertRecords(ctx context.Context, videos []video.Video) error
-	GetRecordsBySourceIDs(ctx context.Context, sourceIDs []string) ([]*Record, error)
-	InsertRecords(ctx context.Context, videos []video.Video) error
 	UpdateRecords(ctx context.Context, videos []video.Video) error
+	// The reads below return every valid video, together with an *InvalidRecordsError
+	// listing the rows that failed validation if there are any.
+	GetVideosBySourceIDs(ctx context.Context, sourceIDs []string) ([]video.Video, error)
+	GetVideosByStatus(ctx context.Context, statuses ...status.Status) ([]video.Video, error)
+	GetVideosByChannel(ctx context.Context, channelID string) ([]video.Video, error)
+	GetVideosScheduledBetween(ctx context.Context, from, to time.Time) ([]video.Video, error)
 	GetLastUpdatedUnixOfVideo(ctx context.Context) (int64, error)
-	UpdateScheduledAtBySourceID(ctx context.Context, sourceID string, scheduledAt time.Time) error
-	UpdateStatusBySourceID(ctx context.Context, sourceID string, status string) error
+}
+
+type RealtimeQueryRepository interface {
+	QueryRecords(ctx context.Context, q VideoQuery) ([]*Record, error)
+}
+
+type SyncStateRepository interface {
+	MarkChannelsSynced(ctx context.Context, channelIDs []string, syncedAt time.Time) error
+	SetRSSWatermarks(ctx context.Context, watermarks map[string]time.Time) error
+	GetSyncStates(ctx context.Context) ([]*SyncState, error)
+}
+
+// FreeChatRepository keeps the active free-chat room of each channel, which Animus collects chats from.
+type FreeChatRepository interface {
+	SetFreeChats(ctx context.Context, videos []video.Video) error
+	GetFreeChats(ctx context.Context) ([]*FreeChat, error)
+}
+
+// StatsRepository keeps the statistics of videos sampled over time.
+type StatsRepository interface {
+	InsertStatsSnapshots(ctx context.Context, snapshots []stats.Snapshot) error
+	GetLastStatsSnapshotTimes(ctx context.Context, sourceIDs []string) (map[string]time.Time, error)
+	GetStatsSeries(ctx context.Context, sourceID string, from, to *time.Time) ([]*StatsSnapshot, error)
+}
+
+// ChannelRepository keeps the metadata of the channels that videos belong to.
+type ChannelRepository interface {
+	UpsertChannels(ctx context.Context, channels []channel.Channel) error
+	GetChannels(ctx context.Context) ([]*Channel, error)
+}
+
+// TargetRepository keeps the target channels, which take precedence over the embedded target.json.
+type TargetRepository interface {
+	config.TargetStore
+	SaveTargets(ctx context.Context, channels []config.Channel) error
+}
+
+// FilteredVideoRepository remembers the videos dropped by the filters of their channels, so that they are not fetched again.
+type FilteredVideoRepository interface {
+	RememberFilteredVideos(ctx context.Context, videos []*FilteredVideo) error
+	GetFilterFingerprints(ctx context.Context, sourceIDs []string) (map[string]string, error)
+}
+
+type SyncRunRepository interface {
+	StartRun(ctx context.Context, s run.Stats) (int64, error)
+	FinishRun(ctx context.Context, id int64, s run.Stats) error
+	ListRuns(ctx context.Context, limit int) ([]*SyncRun, error)
+	GetRun(ctx context.Context, id int64) (*SyncRun, error)
 }
@@ -0,0 +1,762 @@
+// Package realtimetest is the contract every realtime.Backend has to satisfy.
+// Each backend runs it from its own tests, so that the memory and SQLite backends used locally
+// keep behaving like the Postgres one used in production.
+package realtimetest
+
+import (
+	"context"
+	"errors"
+	"github.com/Code-Hex/synchro"
+	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/channel"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/stats"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"testing"
+	"time"
+)
+
+// Run runs the contract against the backends returned by open.
+// open is called once per subtest and must return an empty backend.
+func Run(t *testing.T, open func(t *testing.T) realtime.Backend) {
+	t.Helper()
+
+	tests := map[string]func(t *testing.T, b realtime.Backend){
+		"upsert and read by source IDs": testUpsertAndRead,
+		"upsert overwrites the state":   testUpsertExisting,
+		"update stored rows":            testUpdateRecords,
+		"read by status":                testReadByStatus,
+		"read by channel":               testReadByChannel,
+		"read scheduled between":        testReadScheduledBetween,
+		"last updated":                  testLastUpdated,
+		"query records":                 testQueryRecords,
+		"sync states":                   testSyncStates,
+		"sync runs":                     testSyncRuns,
+		"free chats":                    testFreeChats,
+		"stats snapshots":               testStatsSnapshots,
+		"channels":                      testChannels,
+		"targets":                       testTargets,
+		"filtered videos":               testFilteredVideos,
+		"locker":                        testLocker,
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			b := open(t)
+			if err := b.Ping(context.Background()); err != nil {
+				t.Fatalf("Ping() error: %v", err)
+			}
+			tt(t, b)
+		})
+	}
+}
+
+var timeEqual = cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })
+
+func jst(y int, m time.Month, d, h int) synchro.Time[tz.AsiaTokyo] {
+	return synchro.In[tz.AsiaTokyo](time.Date(y, m, d, h, 0, 0, 0, time.UTC))
+}
+
+func newVideo(t *testing.T, sourceID, channelID string, st status.Status, scheduledAt, updatedAt synchro.Time[tz.AsiaTokyo]) video.Video {
+	t.Helper()
+	return newVideoOfKind(t, sourceID, channelID, st, kind.Live, scheduledAt, updatedAt)
+}
+
+func newVideoOfKind(t *testing.T, sourceID, channelID string, st status.Status, k kind.Kind, scheduledAt, updatedAt synchro.Time[tz.AsiaTokyo]) video.Video {
+	t.Helper()
+	var live video.Live
+	switch {
+	case k == kind.Upload || k == kind.Short:
+	case st == status.Upcoming:
+		live = video.Live{ChatStatus: status.ChatEnabled}
+	case st == status.Live:
+		live = video.Live{ChatStatus: status.ChatEnabled, ActualStartAt: jst(2024, 1, 1, 1)}
+	case st == status.Archived:
+		live = video.Live{ChatStatus: status.ChatEnded, ActualStartAt: jst(2024, 1, 1, 1), ActualEndAt: jst(2024, 1, 1, 3)}
+	}
+	v, err := video.NewVideo(channelID, sourceID, sourceID+"_title", sourceID+"_description", sourceID+"_chat", st, jst(2024, 1, 1, 0), scheduledAt, updatedAt, live, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *v
+}
+
+func upsert(t *testing.T, b realtime.Backend, videos ...video.Video) {
+	t.Helper()
+	if err := b.UpsertRecords(context.Background(), videos); err != nil {
+		t.Fatalf("UpsertRecords() error: %v", err)
+	}
+}
+
+func sourceIDs(t *testing.T, videos []video.Video, err error) []string {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	ids := make([]string, 0, len(videos))
+	for _, v := range videos {
+		ids = append(ids, v.SourceID())
+	}
+	return ids
+}
+
+func testUpsertAndRead(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	want := newVideo(t, "b", "channel", status.Upcoming, jst(2024, 1, 2, 12), jst(2024, 1, 1, 1))
+	upsert(t, b, want, newVideo(t, "a", "channel", status.Archived, synchro.Time[tz.AsiaTokyo]{}, jst(2024, 1, 1, 2)))
+
+	got, err := b.GetVideosBySourceIDs(ctx, []string{"b", "a", "missing"})
+	if diff := cmp.Diff([]string{"a", "b"}, sourceIDs(t, got, err)); diff != "" {
+		t.Errorf("GetVideosBySourceIDs() mismatch (-want +got):\n%s", diff)
+	}
+
+	g := got[1]
+	if g.ChannelID() != want.ChannelID() || g.Title() != want.Title() || g.Description() != want.Description() ||
+		g.ChatID() != want.ChatID() || g.Status() != want.Status() {
+		t.Errorf("want: %+v, got: %+v", want, g)
+	}
+	for name, pair := range map[string][2]synchro.Time[tz.AsiaTokyo]{
+		"publishedAt": {want.PublishedAt(), g.PublishedAt()},
+		"scheduledAt": {want.ScheduledAt(), g.ScheduledAt()},
+		"updatedAt":   {want.UpdatedAt(), g.UpdatedAt()},
+	} {
+		if !pair[0].StdTime().Equal(pair[1].StdTime()) {
+			t.Errorf("%s want: %v, got: %v", name, pair[0], pair[1])
+		}
+	}
+	if !got[0].ScheduledAt().IsZero() {
+		t.Errorf("want no scheduledAt, got: %v", got[0].ScheduledAt())
+	}
+	if got[0].ChatStatus() != status.ChatEnded || g.ChatStatus() != status.ChatEnabled {
+		t.Errorf("want chat status ended and enabled, got: %q and %q", got[0].ChatStatus(), g.ChatStatus())
+	}
+	if !got[0].ActualStartAt().StdTime().Equal(jst(2024, 1, 1, 1).StdTime()) || !got[0].ActualEndAt().StdTime().Equal(jst(2024, 1, 1, 3).StdTime()) {
+		t.Errorf("want the actual times of the broadcast, got: %v - %v", got[0].ActualStartAt(), got[0].ActualEndAt())
+	}
+	if !g.ActualStartAt().IsZero() || !g.ActualEndAt().IsZero() {
+		t.Errorf("want no actual times before the broadcast, got: %v - %v", g.ActualStartAt(), g.ActualEndAt())
+	}
+
+	got, err = b.GetVideosBySourceIDs(ctx, []string{})
+	if diff := cmp.Diff([]string{}, sourceIDs(t, got, err)); diff != "" {
+		t.Errorf("GetVideosBySourceIDs() with no IDs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testUpsertExisting(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	upsert(t, b, newVideo(t, "a", "channel", status.Upcoming, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)))
+	upsert(t, b, newVideoOfKind(t, "a", "other", status.Live, kind.Premiere, jst(2024, 1, 2, 1), jst(2024, 1, 1, 1)))
+
+	got, err := b.GetVideosBySourceIDs(ctx, []string{"a"})
+	if diff := cmp.Diff([]string{"a"}, sourceIDs(t, got, err)); diff != "" {
+		t.Fatalf("GetVideosBySourceIDs() mismatch (-want +got):\n%s", diff)
+	}
+	// the state is overwritten, and the channel is kept
+	g := got[0]
+	if g.ChannelID() != "channel" || g.Status() != status.Live || g.Kind() != kind.Premiere || g.ChatStatus() != status.ChatEnabled {
+		t.Errorf("want the live state in the first channel, got: %+v", g)
+	}
+	if !g.ScheduledAt().StdTime().Equal(jst(2024, 1, 2, 1).StdTime()) || !g.ActualStartAt().StdTime().Equal(jst(2024, 1, 1, 1).StdTime()) {
+		t.Errorf("want the times of the live state, got: %v and %v", g.ScheduledAt(), g.ActualStartAt())
+	}
+	if !g.UpdatedAt().StdTime().Equal(jst(2024, 1, 1, 1).StdTime()) {
+		t.Errorf("want the updatedAt of the second upsert, got: %v", g.UpdatedAt())
+	}
+
+	// an archive without a chat ID keeps the stored one
+	archived, err := video.NewVideo("channel", "a", "a_title", "a_description", "", status.Archived, jst(2024, 1, 1, 0), jst(2024, 1, 2, 1), jst(2024, 1, 1, 4),
+		video.Live{ChatStatus: status.ChatEnded, ActualStartAt: jst(2024, 1, 1, 1), ActualEndAt: jst(2024, 1, 1, 3)}, kind.Premiere)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upsert(t, b, *archived)
+	got, err = b.GetVideosBySourceIDs(ctx, []string{"a"})
+	if diff := cmp.Diff([]string{"a"}, sourceIDs(t, got, err)); diff != "" {
+		t.Fatalf("GetVideosBySourceIDs() mismatch (-want +got):\n%s", diff)
+	}
+	if got[0].Status() != status.Archived || got[0].ChatID() != "a_chat" {
+		t.Errorf("want the archive with the stored chat ID, got: %q and %q", got[0].Status(), got[0].ChatID())
+	}
+}
+
+func testUpdateRecords(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	upsert(t, b,
+		newVideo(t, "a", "channel", status.Upcoming, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)),
+		newVideo(t, "b", "channel", status.Upcoming, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)),
+	)
+
+	// the channel and kind of an update are ignored, and a video that is not stored is not inserted
+	archived := newVideoOfKind(t, "a", "other", status.Archived, kind.Premiere, jst(2024, 1, 3, 0), jst(2024, 1, 4, 0))
+	missing := newVideo(t, "missing", "channel", status.Missed, jst(2024, 1, 2, 0), jst(2024, 1, 4, 0))
+	if err := b.UpdateRecords(ctx, []video.Video{archived, missing}); err != nil {
+		t.Fatalf("UpdateRecords() error: %v", err)
+	}
+	if err := b.UpdateRecords(ctx, nil); err != nil {
+		t.Fatalf("UpdateRecords() with no video error: %v", err)
+	}
+
+	got, err := b.GetVideosBySourceIDs(ctx, []string{"a", "b", "missing"})
+	if diff := cmp.Diff([]string{"a", "b"}, sourceIDs(t, got, err)); diff != "" {
+		t.Fatalf("GetVideosBySourceIDs() mismatch (-want +got):\n%s", diff)
+	}
+	for _, v := range got {
+		switch v.SourceID() {
+		case "a":
+			if v.Status() != status.Archived || v.ChatStatus() != status.ChatEnded || !v.ScheduledAt().Equal(jst(2024, 1, 3, 0)) || !v.UpdatedAt().Equal(jst(2024, 1, 4, 0)) {
+				t.Errorf("want the state of a to be updated, got: %+v", v)
+			}
+			if v.ChannelID() != "channel" || v.Kind() != kind.Live {
+				t.Errorf("want the channel and kind of a to be kept, got: %+v", v)
+			}
+		case "b":
+			if v.Status() != status.Upcoming {
+				t.Errorf("want b to be kept, got: %+v", v)
+			}
+		}
+	}
+
+	// an archive without a chat ID keeps the stored one
+	ended, err := video.NewVideo("channel", "b", "b_title", "", "", status.Archived, jst(2024, 1, 1, 0), jst(2024, 1, 2, 0), jst(2024, 1, 4, 0),
+		video.Live{ChatStatus: status.ChatEnded, ActualStartAt: jst(2024, 1, 2, 0), ActualEndAt: jst(2024, 1, 2, 2)}, kind.Live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.UpdateRecords(ctx, []video.Video{*ended}); err != nil {
+		t.Fatalf("UpdateRecords() error: %v", err)
+	}
+	got, err = b.GetVideosBySourceIDs(ctx, []string{"b"})
+	if diff := cmp.Diff([]string{"b"}, sourceIDs(t, got, err)); diff != "" {
+		t.Fatalf("GetVideosBySourceIDs() mismatch (-want +got):\n%s", diff)
+	}
+	if got[0].Status() != status.Archived || got[0].ChatID() != "b_chat" {
+		t.Errorf("want the archive with the stored chat ID, got: %q and %q", got[0].Status(), got[0].ChatID())
+	}
+}
+
+func testReadByStatus(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	upsert(t, b,
+		newVideo(t, "upcoming", "channel", status.Upcoming, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)),
+		newVideo(t, "live", "channel", status.Live, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)),
+		newVideo(t, "archived", "channel", status.Archived, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)),
+	)
+
+	got, err := b.GetVideosByStatus(ctx, status.Upcoming, status.Live)
+	if diff := cmp.Diff([]string{"live", "upcoming"}, sourceIDs(t, got, err)); diff != "" {
+		t.Errorf("GetVideosByStatus() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = b.GetVideosByStatus(ctx)
+	if diff := cmp.Diff([]string{}, sourceIDs(t, got, err)); diff != "" {
+		t.Errorf("GetVideosByStatus() with no status mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testReadByChannel(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	upsert(t, b,
+		newVideo(t, "main2", "main", status.Upcoming, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)),
+		newVideo(t, "main1", "main", status.Archived, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)),
+		newVideo(t, "sub1", "sub", status.Upcoming, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)),
+	)
+
+	got, err := b.GetVideosByChannel(ctx, "main")
+	if diff := cmp.Diff([]string{"main1", "main2"}, sourceIDs(t, got, err)); diff != "" {
+		t.Errorf("GetVideosByChannel() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testReadScheduledBetween(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	upsert(t, b,
+		newVideo(t, "before", "channel", status.Upcoming, jst(2024, 1, 1, 23), jst(2024, 1, 1, 0)),
+		newVideo(t, "from", "channel", status.Upcoming, jst(2024, 1, 2, 0), jst(2024, 1, 1, 0)),
+		newVideo(t, "within", "channel", status.Upcoming, jst(2024, 1, 2, 12), jst(2024, 1, 1, 0)),
+		newVideo(t, "to", "channel", status.Upcoming, jst(2024, 1, 3, 0), jst(2024, 1, 1, 0)),
+		newVideo(t, "unscheduled", "channel", status.Upcoming, synchro.Time[tz.AsiaTokyo]{}, jst(2024, 1, 1, 0)),
+	)
+
+	got, err := b.GetVideosScheduledBetween(ctx, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC))
+	if diff := cmp.Diff([]string{"from", "within"}, sourceIDs(t, got, err)); diff != "" {
+		t.Errorf("GetVideosScheduledBetween() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testLastUpdated(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+
+	got, err := b.GetLastUpdatedUnixOfVideo(ctx)
+	if err != nil || got != 0 {
+		t.Errorf("want 0 for an empty table, got: %v, %v", got, err)
+	}
+
+	upsert(t, b,
+		newVideo(t, "old", "channel", status.Archived, synchro.Time[tz.AsiaTokyo]{}, jst(2024, 1, 1, 0)),
+		newVideo(t, "new", "channel", status.Archived, synchro.Time[tz.AsiaTokyo]{}, jst(2024, 2, 1, 0)),
+	)
+	got, err = b.GetLastUpdatedUnixOfVideo(ctx)
+	if err != nil {
+		t.Fatalf("GetLastUpdatedUnixOfVideo() error: %v", err)
+	}
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).Unix(); got != want {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+}
+
+func testQueryRecords(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	upsert(t, b,
+		newVideo(t, "a", "main", status.Upcoming, jst(2024, 1, 5, 0), jst(2024, 1, 1, 0)),
+		newVideo(t, "b", "main", status.Live, jst(2024, 1, 5, 0), jst(2024, 1, 1, 0)),
+		newVideoOfKind(t, "c", "sub", status.Upcoming, kind.Premiere, jst(2024, 1, 6, 0), jst(2024, 1, 2, 0)),
+		newVideoOfKind(t, "d", "sub", status.Archived, kind.Upload, synchro.Time[tz.AsiaTokyo]{}, jst(2024, 1, 3, 0)),
+		newVideoOfKind(t, "e", "sub", status.Archived, kind.Short, synchro.Time[tz.AsiaTokyo]{}, jst(2024, 1, 4, 0)),
+	)
+
+	from := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		query realtime.VideoQuery
+		want  []string
+	}{
+		"all ordered by updated_at and source_id": {
+			query: realtime.VideoQuery{},
+			want:  []string{"a", "b", "c", "d", "e"},
+		},
+		"kind": {
+			query: realtime.VideoQuery{Kinds: []kind.Kind{kind.Premiere, kind.Upload}},
+			want:  []string{"c", "d"},
+		},
+		"excluded kinds": {
+			query: realtime.VideoQuery{ExcludeKinds: []kind.Kind{kind.Upload, kind.Short}},
+			want:  []string{"a", "b", "c"},
+		},
+		"status and channel": {
+			query: realtime.VideoQuery{Statuses: []status.Status{status.Upcoming}, ChannelIDs: []string{"main"}},
+			want:  []string{"a"},
+		},
+		"scheduled from": {
+			query: realtime.VideoQuery{ScheduledFrom: &from},
+			want:  []string{"c"},
+		},
+		"since excludes the boundary": {
+			query: realtime.VideoQuery{Since: &since},
+			want:  []string{"c", "d", "e"},
+		},
+		"after cursor with limit": {
+			query: realtime.VideoQuery{After: &realtime.Cursor{UpdatedAt: since, SourceID: "a"}, Limit: 2},
+			want:  []string{"b", "c"},
+		},
+	}
+
+	for name, tt := range tests {
+		got, err := b.QueryRecords(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("%s: QueryRecords() error: %v", name, err)
+		}
+		ids := make([]string, 0, len(got))
+		for _, r := range got {
+			ids = append(ids, r.SourceID)
+		}
+		if diff := cmp.Diff(tt.want, ids); diff != "" {
+			t.Errorf("%s: QueryRecords() mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func testSyncStates(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	syncedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := b.MarkChannelsSynced(ctx, []string{"main", "sub"}, syncedAt); err != nil {
+		t.Fatalf("MarkChannelsSynced() error: %v", err)
+	}
+	// marking again must update the existing state
+	if err := b.MarkChannelsSynced(ctx, []string{"sub"}, syncedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("MarkChannelsSynced() error: %v", err)
+	}
+	if err := b.MarkChannelsSynced(ctx, nil, syncedAt); err != nil {
+		t.Fatalf("MarkChannelsSynced() with no channel error: %v", err)
+	}
+
+	got, err := b.GetSyncStates(ctx)
+	if err != nil {
+		t.Fatalf("GetSyncStates() error: %v", err)
+	}
+	states := make(map[string]time.Time, len(got))
+	for _, s := range got {
+		states[s.ChannelID] = s.LastSyncedAt
+	}
+	want := map[string]time.Time{"main": syncedAt, "sub": syncedAt.Add(time.Hour)}
+	if diff := cmp.Diff(want, states, timeEqual); diff != "" {
+		t.Errorf("GetSyncStates() mismatch (-want +got):\n%s", diff)
+	}
+
+	// a watermark is only set on a channel already marked, and is kept when the channel is marked again
+	watermark := syncedAt.Add(-time.Minute)
+	if err := b.SetRSSWatermarks(ctx, map[string]time.Time{"main": watermark, "unknown": watermark}); err != nil {
+		t.Fatalf("SetRSSWatermarks() error: %v", err)
+	}
+	if err := b.MarkChannelsSynced(ctx, []string{"main"}, syncedAt.Add(2*time.Hour)); err != nil {
+		t.Fatalf("MarkChannelsSynced() error: %v", err)
+	}
+
+	got, err = b.GetSyncStates(ctx)
+	if err != nil {
+		t.Fatalf("GetSyncStates() error: %v", err)
+	}
+	watermarks := make(map[string]*time.Time, len(got))
+	for _, s := range got {
+		watermarks[s.ChannelID] = s.RSSWatermark
+	}
+	wantWatermarks := map[string]*time.Time{"main": &watermark, "sub": nil}
+	if diff := cmp.Diff(wantWatermarks, watermarks, timeEqual); diff != "" {
+		t.Errorf("GetSyncStates() watermarks mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testFreeChats(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	updatedAt := jst(2024, 5, 1, 0)
+
+	got, err := b.GetFreeChats(ctx)
+	if err != nil {
+		t.Fatalf("GetFreeChats() error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("GetFreeChats() of an empty backend = %d rows, want none", len(got))
+	}
+
+	first := newVideoOfKind(t, "first", "main", status.Upcoming, kind.FreeChat, jst(2025, 1, 1, 0), updatedAt)
+	sub := newVideoOfKind(t, "sub", "sub", status.Upcoming, kind.FreeChat, jst(2025, 1, 1, 0), updatedAt)
+	if err := b.SetFreeChats(ctx, []video.Video{first, sub}); err != nil {
+		t.Fatalf("SetFreeChats() error: %v", err)
+	}
+	// a new room replaces the previous one of the channel
+	second := newVideoOfKind(t, "second", "main", status.Upcoming, kind.FreeChat, jst(2025, 6, 1, 0), updatedAt.Add(time.Hour))
+	if err := b.SetFreeChats(ctx, []video.Video{second}); err != nil {
+		t.Fatalf("SetFreeChats() error: %v", err)
+	}
+	if err := b.SetFreeChats(ctx, nil); err != nil {
+		t.Fatalf("SetFreeChats() with no video error: %v", err)
+	}
+
+	got, err = b.GetFreeChats(ctx)
+	if err != nil {
+		t.Fatalf("GetFreeChats() error: %v", err)
+	}
+	want := []*realtime.FreeChat{
+		{ChannelID: "main", SourceID: "second", ChatID: "second_chat", UpdatedAt: updatedAt.Add(time.Hour).StdTime()},
+		{ChannelID: "sub", SourceID: "sub", ChatID: "sub_chat", UpdatedAt: updatedAt.StdTime()},
+	}
+	if diff := cmp.Diff(want, got, timeEqual, cmpopts.IgnoreFields(realtime.FreeChat{}, "BaseModel")); diff != "" {
+		t.Errorf("GetFreeChats() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testChannels(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	updatedAt := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	subscribers := int64(1000)
+
+	newChannel := func(id, title string, subscribers *int64, updatedAt time.Time) channel.Channel {
+		c, err := channel.NewChannel(id, title, "@"+id, "https://example.com/"+id+".jpg", subscribers, "UU"+id, updatedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *c
+	}
+
+	// storing a video stores its channel as a placeholder
+	videos := []video.Video{
+		newVideo(t, "a", "main", status.Upcoming, jst(2024, 7, 2, 0), jst(2024, 7, 1, 0)),
+		newVideo(t, "b", "sub", status.Upcoming, jst(2024, 7, 2, 0), jst(2024, 7, 1, 0)),
+	}
+	if err := b.UpsertRecords(ctx, videos); err != nil {
+		t.Fatalf("UpsertRecords() error: %v", err)
+	}
+
+	if err := b.UpsertChannels(ctx, []channel.Channel{newChannel("main", "old title", nil, updatedAt)}); err != nil {
+		t.Fatalf("UpsertChannels() error: %v", err)
+	}
+	// a refresh overwrites the stored metadata
+	if err := b.UpsertChannels(ctx, []channel.Channel{newChannel("main", "title", &subscribers, updatedAt.Add(time.Hour))}); err != nil {
+		t.Fatalf("UpsertChannels() error: %v", err)
+	}
+	if err := b.UpsertChannels(ctx, nil); err != nil {
+		t.Fatalf("UpsertChannels() with no channel error: %v", err)
+	}
+	// a channel already stored is not reset by the videos stored later
+	if err := b.UpsertRecords(ctx, videos[:1]); err != nil {
+		t.Fatalf("UpsertRecords() error: %v", err)
+	}
+
+	got, err := b.GetChannels(ctx)
+	if err != nil {
+		t.Fatalf("GetChannels() error: %v", err)
+	}
+	refreshedAt := updatedAt.Add(time.Hour)
+	want := []*realtime.Channel{
+		{
+			ID:                "main",
+			Title:             "title",
+			Handle:            "@main",
+			AvatarURL:         "https://example.com/main.jpg",
+			SubscriberCount:   &subscribers,
+			UploadsPlaylistID: "UUmain",
+			UpdatedAt:         &refreshedAt,
+		},
+		{ID: "sub"},
+	}
+	if diff := cmp.Diff(want, got, timeEqual, cmpopts.IgnoreFields(realtime.Channel{}, "BaseModel")); diff != "" {
+		t.Errorf("GetChannels() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testTargets(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	enabled, disabled := true, false
+
+	got, err := b.LoadTargets(ctx)
+	if err != nil {
+		t.Fatalf("LoadTargets() error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("LoadTargets() of an empty backend = %d rows, want none", len(got))
+	}
+
+	first := []config.Channel{
+		{Display: "main", ChannelId: "UCeLzT-7b2PBcunJplmWtoDg", Enabled: &enabled},
+		{Display: "old", Handle: "@old", Enabled: &disabled},
+	}
+	if err := b.SaveTargets(ctx, first); err != nil {
+		t.Fatalf("SaveTargets() error: %v", err)
+	}
+	// saving replaces every target
+	want := []config.Channel{
+		{Display: "sub", Handle: "@sub", Enabled: &enabled, Discovery: config.DiscoveryNone},
+		{Display: "main", ChannelId: "UCeLzT-7b2PBcunJplmWtoDg", Enabled: &disabled, Filters: &config.Filters{
+			ExcludeTitle:    []string{"#shorts"},
+			IncludeKind:     []kind.Kind{kind.Live, kind.Premiere},
+			MinDuration:     "10m",
+			IgnoreSourceIDs: []string{"ignoredID"},
+		}},
+	}
+	if err := b.SaveTargets(ctx, want); err != nil {
+		t.Fatalf("SaveTargets() error: %v", err)
+	}
+
+	got, err = b.LoadTargets(ctx)
+	if err != nil {
+		t.Fatalf("LoadTargets() error: %v", err)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(config.Filters{})); diff != "" {
+		t.Errorf("LoadTargets() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testFilteredVideos(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	at := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	err := b.RememberFilteredVideos(ctx, []*realtime.FilteredVideo{
+		{SourceID: "clip", ChannelID: "channel", Rule: "includeKind", Fingerprint: "old", FilteredAt: at},
+		{SourceID: "short", ChannelID: "channel", Rule: "excludeKind short", Fingerprint: "old", FilteredAt: at},
+	})
+	if err != nil {
+		t.Fatalf("RememberFilteredVideos() error: %v", err)
+	}
+	// filtering again under other rules replaces the fingerprint
+	err = b.RememberFilteredVideos(ctx, []*realtime.FilteredVideo{
+		{SourceID: "short", ChannelID: "channel", Rule: "minDuration 10m", Fingerprint: "new", FilteredAt: at.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("RememberFilteredVideos() error: %v", err)
+	}
+
+	got, err := b.GetFilterFingerprints(ctx, []string{"clip", "short", "stream"})
+	if err != nil {
+		t.Fatalf("GetFilterFingerprints() error: %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{"clip": "old", "short": "new"}, got); diff != "" {
+		t.Errorf("GetFilterFingerprints() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = b.GetFilterFingerprints(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetFilterFingerprints() error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("GetFilterFingerprints() of no IDs = %v, want none", got)
+	}
+}
+
+func testStatsSnapshots(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	viewers := int64(300)
+
+	snapshot := func(sourceID string, views int64, viewers *int64, takenAt time.Time) stats.Snapshot {
+		s, err := stats.NewSnapshot(sourceID, views, views/10, views/100, viewers, takenAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *s
+	}
+
+	// inserted out of order, to check the series is ordered by the time taken
+	if err := b.InsertStatsSnapshots(ctx, []stats.Snapshot{
+		snapshot("a", 2000, &viewers, base.Add(10*time.Minute)),
+		snapshot("a", 1000, &viewers, base),
+		snapshot("b", 500, nil, base.Add(time.Hour)),
+	}); err != nil {
+		t.Fatalf("InsertStatsSnapshots() error: %v", err)
+	}
+	if err := b.InsertStatsSnapshots(ctx, []stats.Snapshot{snapshot("a", 3000, nil, base.Add(20*time.Minute))}); err != nil {
+		t.Fatalf("InsertStatsSnapshots() error: %v", err)
+	}
+	if err := b.InsertStatsSnapshots(ctx, nil); err != nil {
+		t.Fatalf("InsertStatsSnapshots() with no snapshot error: %v", err)
+	}
+
+	last, err := b.GetLastStatsSnapshotTimes(ctx, []string{"a", "b", "never"})
+	if err != nil {
+		t.Fatalf("GetLastStatsSnapshotTimes() error: %v", err)
+	}
+	wantLast := map[string]time.Time{"a": base.Add(20 * time.Minute), "b": base.Add(time.Hour)}
+	if diff := cmp.Diff(wantLast, last, timeEqual); diff != "" {
+		t.Errorf("GetLastStatsSnapshotTimes() mismatch (-want +got):\n%s", diff)
+	}
+
+	from, to := base.Add(time.Minute), base.Add(time.Hour)
+	tests := map[string]struct {
+		from, to *time.Time
+		want     []int64
+	}{
+		"whole series": {want: []int64{1000, 2000, 3000}},
+		"bounded":      {from: &from, to: &to, want: []int64{2000, 3000}},
+	}
+	for name, tt := range tests {
+		series, err := b.GetStatsSeries(ctx, "a", tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("%s: GetStatsSeries() error: %v", name, err)
+		}
+		views := make([]int64, 0, len(series))
+		for _, s := range series {
+			views = append(views, s.ViewCount)
+		}
+		if diff := cmp.Diff(tt.want, views); diff != "" {
+			t.Errorf("%s: GetStatsSeries() mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+
+	series, err := b.GetStatsSeries(ctx, "a", nil, nil)
+	if err != nil {
+		t.Fatalf("GetStatsSeries() error: %v", err)
+	}
+	if len(series) != 3 || series[0].ConcurrentViewers == nil || *series[0].ConcurrentViewers != viewers || series[2].ConcurrentViewers != nil {
+		t.Errorf("want the concurrent viewers to be kept only while live, got: %+v", series)
+	}
+}
+
+func testSyncRuns(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	startedAt := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	earlier := run.NewRecorder(run.OperationRSSSync, run.TriggerManual, startedAt.Add(-time.Hour))
+	earlierID, err := b.StartRun(ctx, earlier.Stats())
+	if err != nil {
+		t.Fatalf("StartRun() error: %v", err)
+	}
+
+	rec := run.NewRecorder(run.OperationRSSSync, run.TriggerScheduler, startedAt)
+	id, err := b.StartRun(ctx, rec.Stats())
+	if err != nil {
+		t.Fatalf("StartRun() error: %v", err)
+	}
+	if id == earlierID {
+		t.Fatalf("want distinct IDs, got %d twice", id)
+	}
+
+	rec.AddFetched("channel", 3)
+	rec.AddUpserted("channel", 2)
+	rec.AddAPICall(1)
+	rec.AddError(errors.New("partial failure"))
+	rec.Finish(run.OutcomeFailed, startedAt.Add(time.Minute))
+	if err := b.FinishRun(ctx, id, rec.Stats()); err != nil {
+		t.Fatalf("FinishRun() error: %v", err)
+	}
+
+	got, err := b.GetRun(ctx, id)
+	if err != nil {
+		t.Fatalf("GetRun() error: %v", err)
+	}
+	finishedAt := startedAt.Add(time.Minute)
+	want := &realtime.SyncRun{
+		ID:            id,
+		Operation:     "rss_sync",
+		Trigger:       "scheduler",
+		Outcome:       "failed",
+		StartedAt:     startedAt,
+		FinishedAt:    &finishedAt,
+		ChannelCounts: map[string]run.ChannelCount{"channel": {Fetched: 3, Upserted: 2}},
+		APICalls:      1,
+		QuotaUnits:    1,
+		Errors:        []string{"partial failure"},
+	}
+	if diff := cmp.Diff(want, got, timeEqual); diff != "" {
+		t.Errorf("GetRun() mismatch (-want +got):\n%s", diff)
+	}
+
+	runs, err := b.ListRuns(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListRuns() error: %v", err)
+	}
+	ids := make([]int64, 0, len(runs))
+	for _, r := range runs {
+		ids = append(ids, r.ID)
+	}
+	if diff := cmp.Diff([]int64{id, earlierID}, ids); diff != "" {
+		t.Errorf("ListRuns() must return the latest run first (-want +got):\n%s", diff)
+	}
+
+	missing, err := b.GetRun(ctx, id+1000)
+	if err != nil || missing != nil {
+		t.Errorf("want nil for a missing run, got: %v, %v", missing, err)
+	}
+}
+
+func testLocker(t *testing.T, b realtime.Backend) {
+	ctx := context.Background()
+	key := "realtimetest:" + t.Name()
+
+	l, err := b.Locker().TryLock(ctx, key)
+	if err != nil {
+		t.Fatalf("TryLock() error: %v", err)
+	}
+	if _, err := b.Locker().TryLock(ctx, key); !errors.Is(err, lock.ErrHeld) {
+		t.Errorf("want ErrHeld while the lock is held, got: %v", err)
+	}
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock() error: %v", err)
+	}
+
+	l, err = b.Locker().TryLock(ctx, key)
+	if err != nil {
+		t.Fatalf("TryLock() after unlock error: %v", err)
+	}
+	if err := l.Unlock(ctx); err != nil {
+		t.Errorf("Unlock() error: %v", err)
+	}
+}
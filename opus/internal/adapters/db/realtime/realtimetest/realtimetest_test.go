@@ -0,0 +1,27 @@
+package realtimetest
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+
+	Run(t, func(t *testing.T) realtime.Backend {
+		return realtime.NewMemory()
+	})
+}
+
+func TestSQLite(t *testing.T) {
+	t.Parallel()
+
+	Run(t, func(t *testing.T) realtime.Backend {
+		b, err := realtime.Open(context.Background(), "sqlite://:memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	})
+}
@@ -0,0 +1,44 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+	"github.com/uptrace/bun/extra/bunotel"
+)
+
+// NewSQLiteClient opens a SQLite database for local runs, and creates the tables if they do not exist.
+// name is a file path, or ":memory:" for a database that lives as long as the client.
+func NewSQLiteClient(ctx context.Context, name string) (*Realtime, error) {
+	sqldb, err := sql.Open(sqliteshim.ShimName, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	// a single connection serializes the writes, and keeps an in-memory database alive
+	sqldb.SetMaxOpenConns(1)
+
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	db.AddQueryHook(bunotel.NewQueryHook(bunotel.WithDBName("realtime")))
+
+	r := &Realtime{db: db, locker: lock.NewMemoryLocker()}
+	if err := r.createTables(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Realtime) createTables(ctx context.Context) error {
+	for _, m := range []interface{}{(*Record)(nil), (*SyncState)(nil), (*SyncRun)(nil), (*FreeChat)(nil), (*StatsSnapshot)(nil), (*Channel)(nil), (*Target)(nil), (*FilteredVideo)(nil)} {
+		if _, err := r.db.NewCreateTable().Model(m).IfNotExists().Exec(ctx); err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/stats"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/uptrace/bun"
+	"log/slog"
+	"time"
+)
+
+type StatsSnapshot struct {
+	bun.BaseModel `bun:"table:video_stats_snapshots"`
+
+	ID                int64     `bun:",pk,autoincrement"`
+	SourceID          string    `bun:",type:varchar(255)"`
+	ViewCount         int64     `bun:",type:bigint"`
+	LikeCount         int64     `bun:",type:bigint"`
+	CommentCount      int64     `bun:",type:bigint"`
+	ConcurrentViewers *int64    `bun:",type:bigint"`
+	TakenAt           time.Time `bun:",type:timestamptz"`
+}
+
+func toStatsSnapshotModel(s *stats.Snapshot) *StatsSnapshot {
+	return &StatsSnapshot{
+		SourceID:          s.SourceID(),
+		ViewCount:         s.ViewCount(),
+		LikeCount:         s.LikeCount(),
+		CommentCount:      s.CommentCount(),
+		ConcurrentViewers: s.ConcurrentViewers(),
+		TakenAt:           s.TakenAt(),
+	}
+}
+
+func (r *Realtime) InsertStatsSnapshots(ctx context.Context, snapshots []stats.Snapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	models := make([]*StatsSnapshot, 0, len(snapshots))
+	for _, s := range snapshots {
+		models = append(models, toStatsSnapshotModel(&s))
+	}
+
+	if _, err := r.db.NewInsert().Model(&models).Exec(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to insert stats snapshots",
+			"snapshots", len(snapshots),
+			slog.Group("Realtime", "error", err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// GetLastStatsSnapshotTimes returns when each video was sampled last. A video never sampled is not in the map.
+func (r *Realtime) GetLastStatsSnapshotTimes(ctx context.Context, sourceIDs []string) (map[string]time.Time, error) {
+	last := make(map[string]time.Time)
+	if len(sourceIDs) == 0 {
+		return last, nil
+	}
+
+	rows := make([]*StatsSnapshot, 0)
+	err := r.db.NewSelect().
+		Model(&rows).
+		Column("source_id").
+		ColumnExpr("MAX(taken_at) AS taken_at").
+		Where("source_id IN (?)", bun.In(sourceIDs)).
+		Group("source_id").
+		Scan(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to get last stats snapshot times",
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	for _, s := range rows {
+		last[s.SourceID] = s.TakenAt
+	}
+
+	return last, nil
+}
+
+// GetStatsSeries returns the snapshots of a video taken in [from, to) in the order they were taken.
+// A nil bound leaves that side open.
+func (r *Realtime) GetStatsSeries(ctx context.Context, sourceID string, from, to *time.Time) ([]*StatsSnapshot, error) {
+	series := make([]*StatsSnapshot, 0)
+	q := r.db.NewSelect().Model(&series).Where("source_id = ?", sourceID)
+	if from != nil {
+		q = q.Where("taken_at >= ?", *from)
+	}
+	if to != nil {
+		q = q.Where("taken_at < ?", *to)
+	}
+
+	if err := q.Order("taken_at ASC").Scan(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to get stats series",
+			"sourceID", sourceID,
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	return series, nil
+}
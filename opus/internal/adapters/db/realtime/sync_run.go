@@ -0,0 +1,116 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/uptrace/bun"
+	"log/slog"
+	"time"
+)
+
+type SyncRun struct {
+	bun.BaseModel `bun:"table:sync_runs"`
+
+	ID            int64                       `bun:",pk,autoincrement"`
+	Operation     string                      `bun:",type:varchar(64)"`
+	Trigger       string                      `bun:",type:varchar(64)"`
+	Outcome       string                      `bun:",type:varchar(32)"`
+	StartedAt     time.Time                   `bun:",type:timestamptz"`
+	FinishedAt    *time.Time                  `bun:",type:timestamptz,nullzero"`
+	ChannelCounts map[string]run.ChannelCount `bun:",type:jsonb"`
+	APICalls      int                         `bun:"api_calls"`
+	QuotaUnits    int                         `bun:",type:integer"`
+	Errors        []string                    `bun:",type:jsonb"`
+}
+
+func toSyncRunModel(s run.Stats) *SyncRun {
+	errs := s.Errors
+	if errs == nil {
+		errs = []string{}
+	}
+	counts := s.ChannelCounts
+	if counts == nil {
+		counts = map[string]run.ChannelCount{}
+	}
+
+	return &SyncRun{
+		Operation:     string(s.Operation),
+		Trigger:       string(s.Trigger),
+		Outcome:       string(s.Outcome),
+		StartedAt:     s.StartedAt,
+		FinishedAt:    s.FinishedAt,
+		ChannelCounts: counts,
+		APICalls:      s.APICalls,
+		QuotaUnits:    s.QuotaUnits,
+		Errors:        errs,
+	}
+}
+
+// StartRun inserts a new run and returns its ID.
+func (r *Realtime) StartRun(ctx context.Context, s run.Stats) (int64, error) {
+	m := toSyncRunModel(s)
+	if _, err := r.db.NewInsert().Model(m).Returning("id").Exec(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to insert sync run",
+			"operation", s.Operation,
+			slog.Group("Realtime", "error", err),
+		)
+		return 0, err
+	}
+
+	return m.ID, nil
+}
+
+// FinishRun overwrites the run with its final statistics.
+func (r *Realtime) FinishRun(ctx context.Context, id int64, s run.Stats) error {
+	m := toSyncRunModel(s)
+	m.ID = id
+	if _, err := r.db.NewUpdate().Model(m).WherePK().Exec(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to update sync run",
+			"id", id,
+			slog.Group("Realtime", "error", err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// ListRuns returns the most recent runs first.
+func (r *Realtime) ListRuns(ctx context.Context, limit int) ([]*SyncRun, error) {
+	runs := make([]*SyncRun, 0)
+	err := r.db.NewSelect().
+		Model(&runs).
+		Order("started_at DESC", "id DESC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to list sync runs",
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// GetRun returns nil without an error if the run does not exist.
+func (r *Realtime) GetRun(ctx context.Context, id int64) (*SyncRun, error) {
+	runs := make([]*SyncRun, 0, 1)
+	if err := r.db.NewSelect().Model(&runs).Where("id = ?", id).Scan(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to get sync run",
+			"id", id,
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	return runs[0], nil
+}
@@ -0,0 +1,87 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/uptrace/bun"
+	"log/slog"
+	"time"
+)
+
+type SyncState struct {
+	bun.BaseModel `bun:"table:channel_sync_states"`
+
+	ChannelID    string    `bun:",pk,type:varchar(255)"`
+	LastSyncedAt time.Time `bun:",type:timestamptz"`
+	// RSSWatermark is the update time of the newest feed entry taken by a sync, nil until the first one.
+	// The next sync of the channel only takes the entries updated after it.
+	RSSWatermark *time.Time `bun:"rss_watermark,type:timestamptz"`
+}
+
+func (r *Realtime) MarkChannelsSynced(ctx context.Context, channelIDs []string, syncedAt time.Time) error {
+	if len(channelIDs) == 0 {
+		return nil
+	}
+
+	states := make([]*SyncState, 0, len(channelIDs))
+	for _, c := range channelIDs {
+		states = append(states, &SyncState{ChannelID: c, LastSyncedAt: syncedAt})
+	}
+
+	if _, err := r.db.NewInsert().Model(&states).
+		On("conflict (channel_id) do update").
+		Set("last_synced_at = EXCLUDED.last_synced_at").
+		Exec(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to mark channels as synced",
+			"channelIDs", channelIDs,
+			slog.Group("Realtime", "error", err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// SetRSSWatermarks sets the RSS watermark of the channels, which must already be marked as synced.
+func (r *Realtime) SetRSSWatermarks(ctx context.Context, watermarks map[string]time.Time) error {
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for c, w := range watermarks {
+			if _, err := tx.NewUpdate().
+				Model((*SyncState)(nil)).
+				Set("rss_watermark = ?", w).
+				Where("channel_id = ?", c).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to set RSS watermarks",
+			"channels", len(watermarks),
+			slog.Group("Realtime", "error", err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *Realtime) GetSyncStates(ctx context.Context) ([]*SyncState, error) {
+	states := make([]*SyncState, 0)
+	if err := r.db.NewSelect().Model(&states).Scan(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to get sync states",
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func (r *Realtime) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
@@ -0,0 +1,97 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/uptrace/bun"
+	"log/slog"
+)
+
+// Target is a row of the targets table. When it has rows, they replace the target.json embedded in Opus,
+// so that a channel can be added without a redeploy.
+type Target struct {
+	bun.BaseModel `bun:"table:targets"`
+
+	ID        int64           `bun:",pk,autoincrement"`
+	Display   string          `bun:",type:varchar(255)"`
+	ChannelID string          `bun:",type:varchar(255)"`
+	Handle    string          `bun:",type:varchar(255)"`
+	URL       string          `bun:",type:text"`
+	Enabled   bool            `bun:",type:boolean"`
+	Discovery string          `bun:",type:varchar(255)"`
+	Filters   *config.Filters `bun:",type:jsonb"`
+}
+
+func toTargetModel(c config.Channel) *Target {
+	return &Target{
+		Display:   c.Display,
+		ChannelID: c.ChannelId,
+		Handle:    c.Handle,
+		URL:       c.URL,
+		Enabled:   c.IsEnabled(),
+		Discovery: string(c.Discovery),
+		Filters:   c.Filters,
+	}
+}
+
+func (t *Target) toChannel() config.Channel {
+	enabled := t.Enabled
+	return config.Channel{
+		Display:   t.Display,
+		ChannelId: t.ChannelID,
+		Handle:    t.Handle,
+		URL:       t.URL,
+		Enabled:   &enabled,
+		Discovery: config.Discovery(t.Discovery),
+		Filters:   t.Filters,
+	}
+}
+
+// LoadTargets returns the targets in the order they were saved.
+func (r *Realtime) LoadTargets(ctx context.Context) ([]config.Channel, error) {
+	rows := make([]*Target, 0)
+	if err := r.db.NewSelect().Model(&rows).Order("id").Scan(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to load targets",
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	chs := make([]config.Channel, 0, len(rows))
+	for _, t := range rows {
+		chs = append(chs, t.toChannel())
+	}
+
+	return chs, nil
+}
+
+// SaveTargets replaces every target with channels.
+func (r *Realtime) SaveTargets(ctx context.Context, channels []config.Channel) error {
+	models := make([]*Target, 0, len(channels))
+	for _, c := range channels {
+		models = append(models, toTargetModel(c))
+	}
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Model((*Target)(nil)).Where("TRUE").Exec(ctx); err != nil {
+			return err
+		}
+		if len(models) == 0 {
+			return nil
+		}
+		_, err := tx.NewInsert().Model(&models).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to save targets",
+			"targets", len(channels),
+			slog.Group("Realtime", "error", err),
+		)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/channel"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/stats"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/lock"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"time"
+)
+
+// Unavailable stands in for a backend that could not be opened, so that the handlers stay reachable.
+// Every call fails with the error that kept it from opening.
+type Unavailable struct {
+	err error
+}
+
+var _ Backend = Unavailable{}
+
+func NewUnavailable(err error) Unavailable {
+	return Unavailable{err: err}
+}
+
+func (u Unavailable) UpsertRecords(_ context.Context, _ []video.Video) error {
+	return u.err
+}
+
+func (u Unavailable) UpdateRecords(_ context.Context, _ []video.Video) error {
+	return u.err
+}
+
+func (u Unavailable) GetVideosBySourceIDs(_ context.Context, _ []string) ([]video.Video, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) GetVideosByStatus(_ context.Context, _ ...status.Status) ([]video.Video, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) GetVideosByChannel(_ context.Context, _ string) ([]video.Video, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) GetVideosScheduledBetween(_ context.Context, _, _ time.Time) ([]video.Video, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) GetLastUpdatedUnixOfVideo(_ context.Context) (int64, error) {
+	return 0, u.err
+}
+
+func (u Unavailable) QueryRecords(_ context.Context, _ VideoQuery) ([]*Record, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) MarkChannelsSynced(_ context.Context, _ []string, _ time.Time) error {
+	return u.err
+}
+
+func (u Unavailable) SetRSSWatermarks(_ context.Context, _ map[string]time.Time) error {
+	return u.err
+}
+
+func (u Unavailable) GetSyncStates(_ context.Context) ([]*SyncState, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) StartRun(_ context.Context, _ run.Stats) (int64, error) {
+	return 0, u.err
+}
+
+func (u Unavailable) FinishRun(_ context.Context, _ int64, _ run.Stats) error {
+	return u.err
+}
+
+func (u Unavailable) ListRuns(_ context.Context, _ int) ([]*SyncRun, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) GetRun(_ context.Context, _ int64) (*SyncRun, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) SetFreeChats(_ context.Context, _ []video.Video) error {
+	return u.err
+}
+
+func (u Unavailable) GetFreeChats(_ context.Context) ([]*FreeChat, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) InsertStatsSnapshots(_ context.Context, _ []stats.Snapshot) error {
+	return u.err
+}
+
+func (u Unavailable) GetLastStatsSnapshotTimes(_ context.Context, _ []string) (map[string]time.Time, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) GetStatsSeries(_ context.Context, _ string, _, _ *time.Time) ([]*StatsSnapshot, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) UpsertChannels(_ context.Context, _ []channel.Channel) error {
+	return u.err
+}
+
+func (u Unavailable) GetChannels(_ context.Context) ([]*Channel, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) LoadTargets(_ context.Context) ([]config.Channel, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) SaveTargets(_ context.Context, _ []config.Channel) error {
+	return u.err
+}
+
+func (u Unavailable) RememberFilteredVideos(_ context.Context, _ []*FilteredVideo) error {
+	return u.err
+}
+
+func (u Unavailable) GetFilterFingerprints(_ context.Context, _ []string) (map[string]string, error) {
+	return nil, u.err
+}
+
+func (u Unavailable) Ping(_ context.Context) error {
+	return u.err
+}
+
+func (u Unavailable) Migrate(_ context.Context) error {
+	return u.err
+}
+
+func (u Unavailable) Locker() lock.Locker {
+	return u
+}
+
+// TryLock fails, so that no operation runs without the database.
+func (u Unavailable) TryLock(_ context.Context, _ string) (lock.Lock, error) {
+	return nil, u.err
+}
@@ -0,0 +1,81 @@
+package realtime
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/uptrace/bun"
+	"log/slog"
+	"time"
+)
+
+func (r *Realtime) GetVideosBySourceIDs(ctx context.Context, sourceIDs []string) ([]video.Video, error) {
+	records, err := r.getRecordsBySourceIDs(ctx, sourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return toVideos(ctx, records)
+}
+
+func (r *Realtime) GetVideosByStatus(ctx context.Context, statuses ...status.Status) ([]video.Video, error) {
+	if len(statuses) == 0 {
+		return []video.Video{}, nil
+	}
+
+	return r.selectVideos(ctx, "get videos by status", func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("status IN (?)", bun.In(statuses))
+	})
+}
+
+func (r *Realtime) GetVideosByChannel(ctx context.Context, channelID string) ([]video.Video, error) {
+	return r.selectVideos(ctx, "get videos by channel", func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("channel_id = ?", channelID)
+	})
+}
+
+// GetVideosScheduledBetween returns the videos scheduled in [from, to).
+func (r *Realtime) GetVideosScheduledBetween(ctx context.Context, from, to time.Time) ([]video.Video, error) {
+	return r.selectVideos(ctx, "get videos scheduled between", func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("scheduled_at >= ?", from).Where("scheduled_at < ?", to)
+	})
+}
+
+func (r *Realtime) selectVideos(ctx context.Context, op string, cond func(*bun.SelectQuery) *bun.SelectQuery) ([]video.Video, error) {
+	records := make([]*Record, 0)
+	if err := cond(r.db.NewSelect().Model(&records)).Order("source_id ASC").Scan(ctx); err != nil {
+		logging.FromContext(ctx).Error(
+			"Failed to "+op,
+			slog.Group("Realtime", "error", err),
+		)
+		return nil, err
+	}
+
+	return toVideos(ctx, records)
+}
+
+// toVideos rebuilds every valid record and reports the rest as an InvalidRecordsError.
+func toVideos(ctx context.Context, records []*Record) ([]video.Video, error) {
+	videos := make([]video.Video, 0, len(records))
+	invalid := &InvalidRecordsError{}
+	for _, rec := range records {
+		v, err := fromDBModel(rec)
+		if err != nil {
+			invalid.add(rec.SourceID, err)
+			continue
+		}
+		videos = append(videos, *v)
+	}
+
+	if len(invalid.SourceIDs) > 0 {
+		logging.FromContext(ctx).Warn(
+			"Found invalid video records",
+			"sourceIDs", invalid.SourceIDs,
+			slog.Group("Realtime", "error", invalid),
+		)
+		return videos, invalid
+	}
+
+	return videos, nil
+}
@@ -6,19 +6,32 @@ import (
 	"github.com/Code-Hex/synchro/tz"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/rss/dto"
 	"github.com/mmcdole/gofeed"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Client struct {
 	parser ParserRepository
+	tracer trace.Tracer
 }
 
 func NewRssClient(p ParserRepository) *Client {
-	return &Client{parser: p}
+	return &Client{
+		parser: p,
+		tracer: otel.Tracer("github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/rss"),
+	}
 }
 
 func (c *Client) FetchRssItems(ctx context.Context, url string, limitUnix int64) ([]dto.Item, error) {
+	ctx, span := c.tracer.Start(ctx, "RSS Fetch", trace.WithAttributes(attribute.String("rss.url", url)))
+	defer span.End()
+
 	feed, err := c.parser.ParseURLWithContext(url, ctx)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to parse feed")
 		return nil, err
 	}
 
@@ -40,6 +53,11 @@ func (c *Client) FetchRssItems(ctx context.Context, url string, limitUnix int64)
 		})
 	}
 
+	span.SetAttributes(
+		attribute.Int("rss.items.total", len(feed.Items)),
+		attribute.Int("rss.items.updated", len(items)),
+	)
+
 	return items, nil
 }
 
@@ -40,6 +40,36 @@ func (m *MockClient) EXPECT() *MockClientMockRecorder {
 	return m.recorder
 }
 
+// ChannelList mocks base method.
+func (m *MockClient) ChannelList(ctx context.Context, part, id []string) (*youtube.ChannelListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChannelList", ctx, part, id)
+	ret0, _ := ret[0].(*youtube.ChannelListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChannelList indicates an expected call of ChannelList.
+func (mr *MockClientMockRecorder) ChannelList(ctx, part, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChannelList", reflect.TypeOf((*MockClient)(nil).ChannelList), ctx, part, id)
+}
+
+// ChannelListByHandle mocks base method.
+func (m *MockClient) ChannelListByHandle(ctx context.Context, part []string, handle string) (*youtube.ChannelListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChannelListByHandle", ctx, part, handle)
+	ret0, _ := ret[0].(*youtube.ChannelListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChannelListByHandle indicates an expected call of ChannelListByHandle.
+func (mr *MockClientMockRecorder) ChannelListByHandle(ctx, part, handle any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChannelListByHandle", reflect.TypeOf((*MockClient)(nil).ChannelListByHandle), ctx, part, handle)
+}
+
 // VideoList mocks base method.
 func (m *MockClient) VideoList(ctx context.Context, part, id []string) (*youtube.VideoListResponse, error) {
 	m.ctrl.T.Helper()
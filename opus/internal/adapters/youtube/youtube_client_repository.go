@@ -9,4 +9,6 @@ import (
 
 type Client interface {
 	VideoList(ctx context.Context, part []string, id []string) (*youtube.VideoListResponse, error)
+	ChannelList(ctx context.Context, part []string, id []string) (*youtube.ChannelListResponse, error)
+	ChannelListByHandle(ctx context.Context, part []string, handle string) (*youtube.ChannelListResponse, error)
 }
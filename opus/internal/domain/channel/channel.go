@@ -0,0 +1,73 @@
+// Package channel is the metadata of a YouTube channel that Opus follows, refreshed from channels.list.
+package channel
+
+import (
+	"fmt"
+	"time"
+)
+
+// Channel is a channel as the API last described it.
+type Channel struct {
+	id     string
+	title  string
+	handle string
+	// avatarURL is empty if the channel has no thumbnail
+	avatarURL string
+	// subscriberCount is nil if the channel hides it
+	subscriberCount   *int64
+	uploadsPlaylistID string
+	updatedAt         time.Time
+}
+
+func NewChannel(id, title, handle, avatarURL string, subscriberCount *int64, uploadsPlaylistID string, updatedAt time.Time) (*Channel, error) {
+	if id == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	if title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if subscriberCount != nil && *subscriberCount < 0 {
+		return nil, fmt.Errorf("subscriberCount must not be negative")
+	}
+	if updatedAt.IsZero() {
+		return nil, fmt.Errorf("updatedAt is required")
+	}
+
+	return &Channel{
+		id:                id,
+		title:             title,
+		handle:            handle,
+		avatarURL:         avatarURL,
+		subscriberCount:   subscriberCount,
+		uploadsPlaylistID: uploadsPlaylistID,
+		updatedAt:         updatedAt,
+	}, nil
+}
+
+func (c *Channel) ID() string {
+	return c.id
+}
+func (c *Channel) Title() string {
+	return c.title
+}
+func (c *Channel) Handle() string {
+	return c.handle
+}
+func (c *Channel) AvatarURL() string {
+	return c.avatarURL
+}
+func (c *Channel) SubscriberCount() *int64 {
+	return c.subscriberCount
+}
+func (c *Channel) UploadsPlaylistID() string {
+	return c.uploadsPlaylistID
+}
+func (c *Channel) UpdatedAt() time.Time {
+	return c.updatedAt
+}
+
+// Due reports whether a channel refreshed at updatedAt is refreshed again at now.
+// A channel never refreshed has a zero updatedAt, and is always due.
+func Due(updatedAt, now time.Time, interval time.Duration) bool {
+	return updatedAt.IsZero() || now.Sub(updatedAt) >= interval
+}
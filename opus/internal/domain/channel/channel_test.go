@@ -0,0 +1,70 @@
+package channel
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewChannel(t *testing.T) {
+	t.Parallel()
+
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	subscribers := int64(1000)
+	negative := int64(-1)
+
+	tests := map[string]struct {
+		id          string
+		title       string
+		subscribers *int64
+		updatedAt   time.Time
+		wantErr     bool
+	}{
+		"valid":                  {id: "a", title: "title", subscribers: &subscribers, updatedAt: updatedAt},
+		"hidden subscribers":     {id: "a", title: "title", updatedAt: updatedAt},
+		"without ID":             {title: "title", updatedAt: updatedAt, wantErr: true},
+		"without title":          {id: "a", updatedAt: updatedAt, wantErr: true},
+		"negative subscribers":   {id: "a", title: "title", subscribers: &negative, updatedAt: updatedAt, wantErr: true},
+		"without an update time": {id: "a", title: "title", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewChannel(tt.id, tt.title, "@handle", "", tt.subscribers, "UUa", tt.updatedAt)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.id, got.ID())
+				assert.Equal(t, tt.subscribers, got.SubscriberCount())
+				assert.Equal(t, tt.updatedAt, got.UpdatedAt())
+			}
+		})
+	}
+}
+
+func TestDue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		updatedAt time.Time
+		want      bool
+	}{
+		"never refreshed":       {want: true},
+		"refreshed a day ago":   {updatedAt: now.Add(-24 * time.Hour), want: true},
+		"refreshed an hour ago": {updatedAt: now.Add(-time.Hour), want: false},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, Due(tt.updatedAt, now, 24*time.Hour))
+		})
+	}
+}
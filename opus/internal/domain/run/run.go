@@ -0,0 +1,158 @@
+package run
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Operation string
+
+const (
+	OperationRSSSync         Operation = "rss_sync"
+	OperationScheduleRefresh Operation = "schedule_refresh"
+	OperationReconcile       Operation = "reconcile"
+	OperationStaleCheck      Operation = "stale_check"
+	OperationStatsSnapshot   Operation = "stats_snapshot"
+	OperationChannelSync     Operation = "channel_sync"
+)
+
+type Trigger string
+
+const (
+	TriggerScheduler Trigger = "scheduler"
+	TriggerManual    Trigger = "manual"
+	TriggerPubSub    Trigger = "pubsub"
+)
+
+type Outcome string
+
+const (
+	OutcomeRunning   Outcome = "running"
+	OutcomeSucceeded Outcome = "succeeded"
+	OutcomeFailed    Outcome = "failed"
+	OutcomeSkipped   Outcome = "skipped"
+)
+
+// ChannelCount is the number of items a run handled for one channel.
+type ChannelCount struct {
+	Fetched  int `json:"fetched"`
+	Upserted int `json:"upserted"`
+}
+
+// Stats is a snapshot of what a run has done so far.
+type Stats struct {
+	Operation     Operation
+	Trigger       Trigger
+	StartedAt     time.Time
+	FinishedAt    *time.Time
+	Outcome       Outcome
+	ChannelCounts map[string]ChannelCount
+	APICalls      int
+	QuotaUnits    int
+	Errors        []string
+}
+
+// Recorder collects the statistics of one run.
+// Every method is safe to call on a nil Recorder, so that code paths
+// which are not part of a recorded run do not need to check for it.
+type Recorder struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+func NewRecorder(op Operation, trigger Trigger, startedAt time.Time) *Recorder {
+	return &Recorder{stats: Stats{
+		Operation:     op,
+		Trigger:       trigger,
+		StartedAt:     startedAt,
+		Outcome:       OutcomeRunning,
+		ChannelCounts: make(map[string]ChannelCount),
+	}}
+}
+
+func (r *Recorder) AddFetched(channelID string, n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.stats.ChannelCounts[channelID]
+	c.Fetched += n
+	r.stats.ChannelCounts[channelID] = c
+}
+
+func (r *Recorder) AddUpserted(channelID string, n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.stats.ChannelCounts[channelID]
+	c.Upserted += n
+	r.stats.ChannelCounts[channelID] = c
+}
+
+// AddAPICall counts one call to the YouTube Data API and the quota units it consumed.
+func (r *Recorder) AddAPICall(quotaUnits int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.APICalls++
+	r.stats.QuotaUnits += quotaUnits
+}
+
+func (r *Recorder) AddError(err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Errors = append(r.stats.Errors, err.Error())
+}
+
+func (r *Recorder) Finish(outcome Outcome, finishedAt time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Outcome = outcome
+	r.stats.FinishedAt = &finishedAt
+}
+
+func (r *Recorder) Stats() Stats {
+	if r == nil {
+		return Stats{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.stats
+	s.ChannelCounts = make(map[string]ChannelCount, len(r.stats.ChannelCounts))
+	for k, v := range r.stats.ChannelCounts {
+		s.ChannelCounts[k] = v
+	}
+	s.Errors = append([]string(nil), r.stats.Errors...)
+
+	return s
+}
+
+type recorderKey struct{}
+
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// FromContext returns the Recorder of the current run, or nil if the context is not part of a recorded run.
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
@@ -0,0 +1,62 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+
+	r := NewRecorder(OperationRSSSync, TriggerScheduler, start)
+	ctx := WithRecorder(context.Background(), r)
+
+	FromContext(ctx).AddFetched("channel1", 3)
+	FromContext(ctx).AddFetched("channel2", 1)
+	FromContext(ctx).AddUpserted("channel1", 2)
+	FromContext(ctx).AddAPICall(1)
+	FromContext(ctx).AddAPICall(1)
+	FromContext(ctx).AddError(errors.New("quota exceeded"))
+	FromContext(ctx).AddError(nil)
+	FromContext(ctx).Finish(OutcomeFailed, end)
+
+	want := Stats{
+		Operation:  OperationRSSSync,
+		Trigger:    TriggerScheduler,
+		StartedAt:  start,
+		FinishedAt: &end,
+		Outcome:    OutcomeFailed,
+		ChannelCounts: map[string]ChannelCount{
+			"channel1": {Fetched: 3, Upserted: 2},
+			"channel2": {Fetched: 1, Upserted: 0},
+		},
+		APICalls:   2,
+		QuotaUnits: 2,
+		Errors:     []string{"quota exceeded"},
+	}
+	if diff := cmp.Diff(want, r.Stats()); diff != "" {
+		t.Errorf("Stats() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	t.Parallel()
+
+	// code outside of a recorded run must not panic
+	r := FromContext(context.Background())
+	r.AddFetched("channel1", 1)
+	r.AddUpserted("channel1", 1)
+	r.AddAPICall(1)
+	r.AddError(errors.New("error"))
+	r.Finish(OutcomeSucceeded, time.Now())
+
+	if diff := cmp.Diff(Stats{}, r.Stats()); diff != "" {
+		t.Errorf("Stats() mismatch (-want +got):\n%s", diff)
+	}
+}
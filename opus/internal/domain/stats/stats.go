@@ -0,0 +1,96 @@
+// Package stats is the statistics of a video sampled over time, to chart how a stream was watched.
+package stats
+
+import (
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"time"
+)
+
+// Snapshot is the statistics of a video at one moment.
+type Snapshot struct {
+	sourceID     string
+	viewCount    int64
+	likeCount    int64
+	commentCount int64
+	// concurrentViewers is only known while the video is live
+	concurrentViewers *int64
+	takenAt           time.Time
+}
+
+func NewSnapshot(sourceID string, viewCount, likeCount, commentCount int64, concurrentViewers *int64, takenAt time.Time) (*Snapshot, error) {
+	if sourceID == "" {
+		return nil, fmt.Errorf("sourceID is required")
+	}
+	if viewCount < 0 || likeCount < 0 || commentCount < 0 {
+		return nil, fmt.Errorf("counts must not be negative")
+	}
+	if concurrentViewers != nil && *concurrentViewers < 0 {
+		return nil, fmt.Errorf("concurrentViewers must not be negative")
+	}
+	if takenAt.IsZero() {
+		return nil, fmt.Errorf("takenAt is required")
+	}
+
+	return &Snapshot{
+		sourceID:          sourceID,
+		viewCount:         viewCount,
+		likeCount:         likeCount,
+		commentCount:      commentCount,
+		concurrentViewers: concurrentViewers,
+		takenAt:           takenAt,
+	}, nil
+}
+
+func (s *Snapshot) SourceID() string {
+	return s.sourceID
+}
+func (s *Snapshot) ViewCount() int64 {
+	return s.viewCount
+}
+func (s *Snapshot) LikeCount() int64 {
+	return s.likeCount
+}
+func (s *Snapshot) CommentCount() int64 {
+	return s.commentCount
+}
+func (s *Snapshot) ConcurrentViewers() *int64 {
+	return s.concurrentViewers
+}
+func (s *Snapshot) TakenAt() time.Time {
+	return s.takenAt
+}
+
+// Schedule decides when a video is sampled again. A live video is sampled more densely, to follow its viewers.
+type Schedule struct {
+	LiveInterval time.Duration
+	Interval     time.Duration
+	// Window is how long after it ends or is published an archived video is still sampled
+	Window time.Duration
+}
+
+// Tracked reports whether a video of st that ended, or was published, at endedAt is still sampled at now.
+func (s Schedule) Tracked(st status.Status, endedAt, now time.Time) bool {
+	switch st {
+	case status.Upcoming, status.Live:
+		return true
+	case status.Archived:
+		return !endedAt.IsZero() && now.Sub(endedAt) < s.Window
+	default:
+		return false
+	}
+}
+
+// Due reports whether a tracked video of st, last sampled at lastTakenAt, is sampled at now.
+// A video that has never been sampled is always due.
+func (s Schedule) Due(st status.Status, lastTakenAt, now time.Time) bool {
+	if lastTakenAt.IsZero() {
+		return true
+	}
+	interval := s.Interval
+	if st == status.Live {
+		interval = s.LiveInterval
+	}
+
+	return now.Sub(lastTakenAt) >= interval
+}
@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	t.Parallel()
+
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	viewers := int64(120)
+	negative := int64(-1)
+
+	tests := map[string]struct {
+		sourceID string
+		views    int64
+		viewers  *int64
+		takenAt  time.Time
+		wantErr  bool
+	}{
+		"live":                 {sourceID: "a", views: 10, viewers: &viewers, takenAt: takenAt},
+		"archived":             {sourceID: "a", views: 10, takenAt: takenAt},
+		"without source ID":    {views: 10, takenAt: takenAt, wantErr: true},
+		"negative count":       {sourceID: "a", views: -1, takenAt: takenAt, wantErr: true},
+		"negative viewers":     {sourceID: "a", viewers: &negative, takenAt: takenAt, wantErr: true},
+		"without a time taken": {sourceID: "a", views: 10, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewSnapshot(tt.sourceID, tt.views, 2, 3, tt.viewers, tt.takenAt)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.views, got.ViewCount())
+				assert.Equal(t, tt.viewers, got.ConcurrentViewers())
+				assert.Equal(t, tt.takenAt, got.TakenAt())
+			}
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	t.Parallel()
+
+	s := Schedule{LiveInterval: 5 * time.Minute, Interval: time.Hour, Window: 7 * 24 * time.Hour}
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		status      status.Status
+		endedAt     time.Time
+		lastTakenAt time.Time
+		wantTracked bool
+		wantDue     bool
+	}{
+		"live, sampled a while ago":     {status: status.Live, lastTakenAt: now.Add(-5 * time.Minute), wantTracked: true, wantDue: true},
+		"live, sampled just now":        {status: status.Live, lastTakenAt: now.Add(-time.Minute), wantTracked: true, wantDue: false},
+		"upcoming, sampled a while ago": {status: status.Upcoming, lastTakenAt: now.Add(-30 * time.Minute), wantTracked: true, wantDue: false},
+		"upcoming, never sampled":       {status: status.Upcoming, wantTracked: true, wantDue: true},
+		"archived within the window":    {status: status.Archived, endedAt: now.Add(-24 * time.Hour), lastTakenAt: now.Add(-2 * time.Hour), wantTracked: true, wantDue: true},
+		"archived beyond the window":    {status: status.Archived, endedAt: now.Add(-8 * 24 * time.Hour), wantTracked: false, wantDue: true},
+		"archived without an end":       {status: status.Archived, wantTracked: false, wantDue: true},
+		"missed":                        {status: status.Missed, wantTracked: false, wantDue: true},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.wantTracked, s.Tracked(tt.status, tt.endedAt, now))
+			assert.Equal(t, tt.wantDue, s.Due(tt.status, tt.lastTakenAt, now))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package video
+
+import (
+	"github.com/Code-Hex/synchro"
+	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"strings"
+	"time"
+)
+
+// FreeChatRule tells a free-chat room from an ordinary upcoming broadcast.
+// A room is an upcoming broadcast scheduled beyond the horizon, or whose title has one of the keywords.
+type FreeChatRule struct {
+	// Horizon is how far ahead of now a room is scheduled. 0 disables the check.
+	Horizon  time.Duration
+	Keywords []string
+}
+
+// Matches reports whether a video of the given title, status, kind and schedule is a free-chat room at now.
+func (r FreeChatRule) Matches(title string, st status.Status, k kind.Kind, scheduledAt, now synchro.Time[tz.AsiaTokyo]) bool {
+	if st != status.Upcoming || (k != kind.Live && k != kind.Premiere) {
+		return false
+	}
+
+	if r.Horizon > 0 && !scheduledAt.IsZero() && scheduledAt.After(now.Add(r.Horizon)) {
+		return true
+	}
+
+	t := strings.ToLower(title)
+	for _, kw := range r.Keywords {
+		if kw != "" && strings.Contains(t, strings.ToLower(kw)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package video
+
+import (
+	"github.com/Code-Hex/synchro"
+	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"testing"
+	"time"
+)
+
+func TestFreeChatRule_Matches(t *testing.T) {
+	t.Parallel()
+
+	now := synchro.New[tz.AsiaTokyo](2024, 1, 1, 0, 0, 0, 0)
+	rule := FreeChatRule{Horizon: 30 * 24 * time.Hour, Keywords: []string{"フリーチャット", "Free Chat"}}
+
+	tests := map[string]struct {
+		rule        FreeChatRule
+		title       string
+		status      status.Status
+		kind        kind.Kind
+		scheduledAt synchro.Time[tz.AsiaTokyo]
+		want        bool
+	}{
+		"beyond the horizon": {
+			rule: rule, title: "待機所", status: status.Upcoming, kind: kind.Live,
+			scheduledAt: now.Add(60 * 24 * time.Hour), want: true,
+		},
+		"within the horizon": {
+			rule: rule, title: "歌枠", status: status.Upcoming, kind: kind.Live,
+			scheduledAt: now.Add(24 * time.Hour), want: false,
+		},
+		"keyword in the title": {
+			rule: rule, title: "【フリーチャット】雑談部屋", status: status.Upcoming, kind: kind.Live,
+			scheduledAt: now.Add(24 * time.Hour), want: true,
+		},
+		"keyword in another case": {
+			rule: rule, title: "FREE CHAT room", status: status.Upcoming, kind: kind.Premiere,
+			scheduledAt: now.Add(24 * time.Hour), want: true,
+		},
+		"horizon disabled": {
+			rule: FreeChatRule{}, title: "待機所", status: status.Upcoming, kind: kind.Live,
+			scheduledAt: now.Add(365 * 24 * time.Hour), want: false,
+		},
+		"not upcoming": {
+			rule: rule, title: "【フリーチャット】", status: status.Live, kind: kind.Live,
+			scheduledAt: now.Add(60 * 24 * time.Hour), want: false,
+		},
+		"not a broadcast": {
+			rule: rule, title: "【フリーチャット】", status: status.Upcoming, kind: kind.MembersOnly,
+			scheduledAt: now.Add(60 * 24 * time.Hour), want: false,
+		},
+		"without schedule": {
+			rule: rule, title: "待機所", status: status.Upcoming, kind: kind.Live,
+			want: false,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.rule.Matches(tt.title, tt.status, tt.kind, tt.scheduledAt, now); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
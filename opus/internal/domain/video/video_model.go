@@ -4,7 +4,8 @@ import (
 	"fmt"
 	"github.com/Code-Hex/synchro"
 	"github.com/Code-Hex/synchro/tz"
-	"github.com/KasumiMercury/patotta-stone-functions-go/opus/status"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 )
 
 type Video struct {
@@ -17,9 +18,18 @@ type Video struct {
 	publishedAt synchro.Time[tz.AsiaTokyo]
 	scheduledAt synchro.Time[tz.AsiaTokyo]
 	updatedAt   synchro.Time[tz.AsiaTokyo]
+	live        Live
+	kind        kind.Kind
 }
 
-func NewVideo(channelID, sourceID, title, description, chatID string, status status.Status, publishedAt, scheduledAt, updatedAt synchro.Time[tz.AsiaTokyo]) (*Video, error) {
+// Live is the state of the broadcast of a video. It is zero for a video that is not a broadcast.
+type Live struct {
+	ChatStatus    status.Chat
+	ActualStartAt synchro.Time[tz.AsiaTokyo]
+	ActualEndAt   synchro.Time[tz.AsiaTokyo]
+}
+
+func NewVideo(channelID, sourceID, title, description, chatID string, status status.Status, publishedAt, scheduledAt, updatedAt synchro.Time[tz.AsiaTokyo], live Live, kind kind.Kind) (*Video, error) {
 	v := &Video{
 		channelID:   channelID,
 		sourceID:    sourceID,
@@ -30,17 +40,86 @@ func NewVideo(channelID, sourceID, title, description, chatID string, status sta
 		publishedAt: publishedAt,
 		scheduledAt: scheduledAt,
 		updatedAt:   updatedAt,
+		live:        live,
+		kind:        kind,
 	}
 
-	if err := v.validate(); err != nil {
+	if err := v.validate(true); err != nil {
 		return nil, err
 	}
 
 	return v, nil
 }
 
-func (v *Video) validate() error {
-	if v.channelID == "" {
+// Snapshot is the persisted state of a video.
+type Snapshot struct {
+	ChannelID   string
+	SourceID    string
+	Title       string
+	Description string
+	ChatID      string
+	Status      status.Status
+	PublishedAt synchro.Time[tz.AsiaTokyo]
+	ScheduledAt synchro.Time[tz.AsiaTokyo]
+	UpdatedAt   synchro.Time[tz.AsiaTokyo]
+	Live        Live
+	Kind        kind.Kind
+}
+
+// Rehydrate rebuilds a video from its persisted state.
+// It applies the same invariants as NewVideo, so that a row broken outside the application is rejected when it is read,
+// except for the channel, which videos stored before channel_id existed do not have until a sync fetches them again.
+func Rehydrate(s Snapshot) (*Video, error) {
+	v := &Video{
+		channelID:   s.ChannelID,
+		sourceID:    s.SourceID,
+		title:       s.Title,
+		description: s.Description,
+		chatID:      s.ChatID,
+		status:      s.Status,
+		publishedAt: s.PublishedAt,
+		scheduledAt: s.ScheduledAt,
+		updatedAt:   s.UpdatedAt,
+		live:        s.Live,
+		kind:        s.Kind,
+	}
+
+	if err := v.validate(false); err != nil {
+		return nil, fmt.Errorf("invalid video %s: %w", s.SourceID, err)
+	}
+
+	return v, nil
+}
+
+// WithStatus returns a copy of the video moved to st as of updatedAt, for a video that is found in another state
+// without being fetched again, such as a broadcast that was cancelled.
+func (v *Video) WithStatus(st status.Status, updatedAt synchro.Time[tz.AsiaTokyo]) (*Video, error) {
+	c := *v
+	c.status = st
+	c.updatedAt = updatedAt
+
+	if err := c.validate(c.channelID != ""); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// WithSchedule returns a copy of the video rescheduled to scheduledAt as of updatedAt.
+func (v *Video) WithSchedule(scheduledAt, updatedAt synchro.Time[tz.AsiaTokyo]) (*Video, error) {
+	c := *v
+	c.scheduledAt = scheduledAt
+	c.updatedAt = updatedAt
+
+	if err := c.validate(c.channelID != ""); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (v *Video) validate(requireChannel bool) error {
+	if requireChannel && v.channelID == "" {
 		return fmt.Errorf("channelID is required")
 	}
 	if v.sourceID == "" {
@@ -57,6 +136,13 @@ func (v *Video) validate() error {
 		return fmt.Errorf("status is undefined")
 	}
 
+	// kind is unknown for a video written before it was classified
+	if v.kind != kind.Unknown {
+		if _, err := kind.Parse(string(v.kind)); err != nil {
+			return err
+		}
+	}
+
 	if v.publishedAt.IsZero() {
 		return fmt.Errorf("publishedAt is required")
 	}
@@ -71,6 +157,16 @@ func (v *Video) validate() error {
 		return fmt.Errorf("updatedAtUnix is required")
 	}
 
+	// a broadcast cannot end before it starts
+	if !v.live.ActualEndAt.IsZero() {
+		if v.live.ActualStartAt.IsZero() {
+			return fmt.Errorf("actualStartAt is required when actualEndAt is set")
+		}
+		if v.live.ActualEndAt.Before(v.live.ActualStartAt) {
+			return fmt.Errorf("actualEndAt must be after actualStartAt")
+		}
+	}
+
 	return nil
 }
 
@@ -101,3 +197,15 @@ func (v *Video) ScheduledAt() synchro.Time[tz.AsiaTokyo] {
 func (v *Video) UpdatedAt() synchro.Time[tz.AsiaTokyo] {
 	return v.updatedAt
 }
+func (v *Video) Kind() kind.Kind {
+	return v.kind
+}
+func (v *Video) ChatStatus() status.Chat {
+	return v.live.ChatStatus
+}
+func (v *Video) ActualStartAt() synchro.Time[tz.AsiaTokyo] {
+	return v.live.ActualStartAt
+}
+func (v *Video) ActualEndAt() synchro.Time[tz.AsiaTokyo] {
+	return v.live.ActualEndAt
+}
@@ -3,7 +3,8 @@ package video
 import (
 	"github.com/Code-Hex/synchro"
 	"github.com/Code-Hex/synchro/tz"
-	"github.com/KasumiMercury/patotta-stone-functions-go/opus/status"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
 	"reflect"
 	"testing"
 	"time"
@@ -22,6 +23,8 @@ func TestNewVideo(t *testing.T) {
 		publishedAt synchro.Time[tz.AsiaTokyo]
 		scheduledAt synchro.Time[tz.AsiaTokyo]
 		updatedAt   synchro.Time[tz.AsiaTokyo]
+		live        Live
+		kind        kind.Kind
 	}
 	tests := []struct {
 		name    string
@@ -198,7 +201,7 @@ func TestNewVideo(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := NewVideo(tt.args.channelID, tt.args.sourceID, tt.args.title, tt.args.description, tt.args.chatID, tt.args.status, tt.args.publishedAt, tt.args.scheduledAt, tt.args.updatedAt)
+			got, err := NewVideo(tt.args.channelID, tt.args.sourceID, tt.args.title, tt.args.description, tt.args.chatID, tt.args.status, tt.args.publishedAt, tt.args.scheduledAt, tt.args.updatedAt, tt.args.live, tt.args.kind)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewVideo() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -210,6 +213,125 @@ func TestNewVideo(t *testing.T) {
 	}
 }
 
+func TestRehydrate(t *testing.T) {
+	t.Parallel()
+
+	valid := Snapshot{
+		ChannelID:   "channelID",
+		SourceID:    "sourceID",
+		Title:       "title",
+		Description: "description",
+		ChatID:      "chatID",
+		Status:      status.Archived,
+		PublishedAt: synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		ScheduledAt: synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+		UpdatedAt:   synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)),
+		Live: Live{
+			ChatStatus:    status.ChatEnded,
+			ActualStartAt: synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 2, 0, 1, 0, 0, time.UTC)),
+			ActualEndAt:   synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC)),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		s       func() Snapshot
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			s:    func() Snapshot { return valid },
+		},
+		{
+			name: "when status is undefined, return error",
+			s: func() Snapshot {
+				s := valid
+				s.Status = status.Undefined
+				return s
+			},
+			wantErr: true,
+		},
+		{
+			name: "when scheduledAt is before publishedAt, return error",
+			s: func() Snapshot {
+				s := valid
+				s.ScheduledAt = synchro.In[tz.AsiaTokyo](time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC))
+				return s
+			},
+			wantErr: true,
+		},
+		{
+			name: "when the broadcast has started but not ended, not return error",
+			s: func() Snapshot {
+				s := valid
+				s.Status = status.Live
+				s.Live.ChatStatus = status.ChatEnabled
+				s.Live.ActualEndAt = synchro.Time[tz.AsiaTokyo]{}
+				return s
+			},
+		},
+		{
+			name: "when actualEndAt is set without actualStartAt, return error",
+			s: func() Snapshot {
+				s := valid
+				s.Live.ActualStartAt = synchro.Time[tz.AsiaTokyo]{}
+				return s
+			},
+			wantErr: true,
+		},
+		{
+			name: "when actualEndAt is before actualStartAt, return error",
+			s: func() Snapshot {
+				s := valid
+				s.Live.ActualEndAt = synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+				return s
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := tt.s()
+			got, err := Rehydrate(s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Rehydrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			want, _ := NewVideo(s.ChannelID, s.SourceID, s.Title, s.Description, s.ChatID, s.Status, s.PublishedAt, s.ScheduledAt, s.UpdatedAt, s.Live, s.Kind)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Rehydrate() got = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRehydrate_WithoutChannel(t *testing.T) {
+	t.Parallel()
+
+	// videos stored before channel_id existed are read, but a new video needs its channel
+	s := Snapshot{
+		SourceID:    "sourceID",
+		Title:       "title",
+		Status:      status.Upcoming,
+		PublishedAt: synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		UpdatedAt:   synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)),
+	}
+	v, err := Rehydrate(s)
+	if err != nil {
+		t.Fatalf("Rehydrate() error = %v", err)
+	}
+	if _, err := v.WithStatus(status.Missed, s.UpdatedAt); err != nil {
+		t.Errorf("WithStatus() error = %v", err)
+	}
+	if _, err := NewVideo("", s.SourceID, s.Title, "", "", s.Status, s.PublishedAt, s.ScheduledAt, s.UpdatedAt, Live{}, kind.Unknown); err == nil {
+		t.Error("NewVideo() without channel error = nil, want error")
+	}
+}
+
 func TestVideo_ChannelID(t *testing.T) {
 	t.Parallel()
 	// Arrange
@@ -353,3 +475,80 @@ func TestVideo_UpdatedAtUnix(t *testing.T) {
 		t.Errorf("UpdatedAtUnix() got = %v, want %v", got, synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
 	}
 }
+
+func TestVideo_Live(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	start := synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	end := synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC))
+	v := &Video{
+		live: Live{ChatStatus: status.ChatEnded, ActualStartAt: start, ActualEndAt: end},
+	}
+
+	// Act & Assert
+	if got := v.ChatStatus(); got != status.ChatEnded {
+		t.Errorf("ChatStatus() got = %v, want %v", got, status.ChatEnded)
+	}
+	if got := v.ActualStartAt(); got != start {
+		t.Errorf("ActualStartAt() got = %v, want %v", got, start)
+	}
+	if got := v.ActualEndAt(); got != end {
+		t.Errorf("ActualEndAt() got = %v, want %v", got, end)
+	}
+}
+
+func TestVideo_WithStatus(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	publishedAt := synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	updatedAt := synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC))
+	v, err := NewVideo("channelID", "sourceID", "title", "", "chatID", status.Upcoming, publishedAt, publishedAt.Add(time.Hour), publishedAt, Live{ChatStatus: status.ChatEnabled}, kind.Live)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	got, err := v.WithStatus(status.Missed, updatedAt)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("WithStatus() error = %v", err)
+	}
+	if got.Status() != status.Missed || got.UpdatedAt() != updatedAt {
+		t.Errorf("WithStatus() got = %v at %v, want %v at %v", got.Status(), got.UpdatedAt(), status.Missed, updatedAt)
+	}
+	if v.Status() != status.Upcoming {
+		t.Errorf("WithStatus() changed the original to %v", v.Status())
+	}
+	if _, err := v.WithStatus(status.Undefined, updatedAt); err == nil {
+		t.Error("WithStatus() to Undefined error = nil, want an error")
+	}
+}
+
+func TestVideo_WithSchedule(t *testing.T) {
+	t.Parallel()
+	// Arrange
+	publishedAt := synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	updatedAt := synchro.In[tz.AsiaTokyo](time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC))
+	v, err := NewVideo("channelID", "sourceID", "title", "", "chatID", status.Upcoming, publishedAt, publishedAt.Add(time.Hour), publishedAt, Live{ChatStatus: status.ChatEnabled}, kind.Live)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	got, err := v.WithSchedule(publishedAt.Add(24*time.Hour), updatedAt)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("WithSchedule() error = %v", err)
+	}
+	if got.ScheduledAt() != publishedAt.Add(24*time.Hour) || got.UpdatedAt() != updatedAt {
+		t.Errorf("WithSchedule() got = %v at %v, want %v at %v", got.ScheduledAt(), got.UpdatedAt(), publishedAt.Add(24*time.Hour), updatedAt)
+	}
+	if v.ScheduledAt() != publishedAt.Add(time.Hour) {
+		t.Errorf("WithSchedule() changed the original to %v", v.ScheduledAt())
+	}
+	if _, err := v.WithSchedule(publishedAt.Add(-time.Hour), updatedAt); err == nil {
+		t.Error("WithSchedule() before publishedAt error = nil, want an error")
+	}
+}
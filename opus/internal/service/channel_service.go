@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/channel"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"slices"
+	"time"
+)
+
+// ChannelService refreshes the metadata of the target channels from channels.list.
+// It is meant to be called more often than the refresh interval, and fetches only the channels that are due.
+type ChannelService struct {
+	config  config.Config
+	apiRepo api.ApiRepository
+	chRepo  realtime.ChannelRepository
+	tracer  trace.Tracer
+	now     func() time.Time
+}
+
+func NewChannelService(c config.Config, a api.ApiRepository, cr realtime.ChannelRepository) *ChannelService {
+	return &ChannelService{
+		config:  c,
+		apiRepo: a,
+		chRepo:  cr,
+		tracer:  otel.Tracer(instrumentationName),
+		now:     time.Now,
+	}
+}
+
+// ChannelOptions narrows a refresh. The zero value refreshes every due channel.
+type ChannelOptions struct {
+	ChannelIDs []string
+}
+
+func (s *ChannelService) SyncChannels(ctx context.Context, opts ChannelOptions) (err error) {
+	ctx, span := s.tracer.Start(ctx, "ChannelService.SyncChannels")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to sync channels")
+		}
+		span.End()
+	}()
+
+	now := s.now()
+	stored, err := s.chRepo.GetChannels(ctx)
+	if err != nil {
+		return err
+	}
+	updatedAt := make(map[string]time.Time, len(stored))
+	for _, c := range stored {
+		if c.UpdatedAt != nil {
+			updatedAt[c.ID] = *c.UpdatedAt
+		}
+	}
+
+	targets := s.config.Targets()
+	due := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if len(opts.ChannelIDs) > 0 && !slices.Contains(opts.ChannelIDs, t.ChannelId) {
+			continue
+		}
+		if channel.Due(updatedAt[t.ChannelId], now, s.config.Channels.RefreshInterval) {
+			due = append(due, t.ChannelId)
+		}
+	}
+	if len(due) == 0 {
+		logging.FromContext(ctx).Info("No channel is due for a refresh")
+		return nil
+	}
+
+	resp, err := s.apiRepo.FetchChannelsByIDs(ctx, due)
+	if err != nil {
+		return err
+	}
+
+	channels := make([]channel.Channel, 0, len(resp))
+	for _, r := range resp {
+		run.FromContext(ctx).AddFetched(r.Id, 1)
+		c, err := channel.NewChannel(r.Id, r.Title, r.Handle, r.AvatarURL, r.SubscriberCount, r.UploadsPlaylistID, now)
+		if err != nil {
+			logging.FromContext(ctx).Error(
+				"Failed to create a channel",
+				"channelID", r.Id,
+				"error", err,
+			)
+			continue
+		}
+		channels = append(channels, *c)
+	}
+	if len(resp) < len(due) {
+		logging.FromContext(ctx).Warn("Some channels were not returned by the API", "due", len(due), "returned", len(resp))
+	}
+
+	if err := s.chRepo.UpsertChannels(ctx, channels); err != nil {
+		return err
+	}
+	for _, c := range channels {
+		run.FromContext(ctx).AddUpserted(c.ID(), 1)
+	}
+	logging.FromContext(ctx).Info("Refreshed channels", "due", len(due), "refreshed", len(channels))
+
+	return nil
+}
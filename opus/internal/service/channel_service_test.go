@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// fakeChannelAPI returns every requested channel but "gone", which the API does not know
+type fakeChannelAPI struct {
+	fakeStatsAPI
+	requested []string
+}
+
+func (f *fakeChannelAPI) FetchChannelsByIDs(_ context.Context, channelIDs []string) ([]dto.ChannelResponse, error) {
+	f.requested = append(f.requested, channelIDs...)
+	res := make([]dto.ChannelResponse, 0, len(channelIDs))
+	for _, id := range channelIDs {
+		if id == "gone" {
+			continue
+		}
+		res = append(res, dto.ChannelResponse{Id: id, Title: id + " title", UploadsPlaylistID: "UU" + id})
+	}
+	return res, nil
+}
+
+func TestChannelService_SyncChannels(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clock := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	backend := realtime.NewMemory()
+
+	c := withTarget(config.Target{Channel: []config.Channel{
+		{Display: "main", ChannelId: "main"},
+		{Display: "sub", ChannelId: "sub"},
+		{Display: "gone", ChannelId: "gone"},
+	}})
+	c.Channels = config.Channels{RefreshInterval: 24 * time.Hour}
+	api := &fakeChannelAPI{}
+	s := NewChannelService(c, api, backend)
+	s.now = func() time.Time { return clock }
+
+	assert.NoError(t, s.SyncChannels(ctx, ChannelOptions{}))
+	assert.Equal(t, []string{"main", "sub", "gone"}, api.requested)
+
+	// within the interval, only the channel the API did not return is fetched again
+	clock = clock.Add(time.Hour)
+	api.requested = nil
+	assert.NoError(t, s.SyncChannels(ctx, ChannelOptions{}))
+	assert.Equal(t, []string{"gone"}, api.requested)
+
+	// a day later, the channels asked for are refreshed
+	clock = clock.Add(24 * time.Hour)
+	api.requested = nil
+	assert.NoError(t, s.SyncChannels(ctx, ChannelOptions{ChannelIDs: []string{"sub"}}))
+	assert.Equal(t, []string{"sub"}, api.requested)
+
+	got, err := backend.GetChannels(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "main title", got[0].Title)
+		assert.Equal(t, clock.Add(-25*time.Hour), *got[0].UpdatedAt)
+		assert.Equal(t, clock, *got[1].UpdatedAt)
+	}
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Code-Hex/synchro"
+	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"slices"
+)
+
+// RefreshService keeps the stored broadcasts up to date between the RSS syncs, which only see a video when its feed entry changes.
+// The schedule refresh follows the upcoming broadcasts that are rescheduled, and the reconcile follows the broadcasts around their streams.
+type RefreshService struct {
+	config  config.Config
+	apiRepo api.ApiRepository
+	rtdRepo realtime.RealtimeRepository
+	tracer  trace.Tracer
+}
+
+func NewRefreshService(c config.Config, a api.ApiRepository, rt realtime.RealtimeRepository) *RefreshService {
+	return &RefreshService{
+		config:  c,
+		apiRepo: a,
+		rtdRepo: rt,
+		tracer:  otel.Tracer(instrumentationName),
+	}
+}
+
+// RefreshOptions narrows a refresh. The zero value refreshes every channel.
+type RefreshOptions struct {
+	ChannelIDs []string
+	// DryRun logs the changes without writing them
+	DryRun bool
+}
+
+// RefreshSchedules fetches the schedule of every upcoming broadcast and stores those that moved.
+// A video the API no longer returns is left to the stale check.
+func (s *RefreshService) RefreshSchedules(ctx context.Context, opts RefreshOptions) (err error) {
+	ctx, span := s.tracer.Start(ctx, "RefreshService.RefreshSchedules")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to refresh schedules")
+		}
+		span.End()
+	}()
+
+	upcoming, err := s.rtdRepo.GetVideosByStatus(ctx, status.Upcoming)
+	if err := skipInvalidRecords(ctx, err); err != nil {
+		return err
+	}
+	// a free-chat room is scheduled far ahead on purpose
+	targets := s.inChannels(upcoming, opts, func(v video.Video) bool { return v.Kind() != kind.FreeChat })
+	if len(targets) == 0 {
+		logging.FromContext(ctx).Info("No upcoming video to refresh the schedule of")
+		return nil
+	}
+
+	schedules, err := s.apiRepo.FetchScheduledAtByVideoIDs(ctx, sourceIDsOf(ctx, targets))
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]synchro.Time[tz.AsiaTokyo], len(schedules))
+	for _, sc := range schedules {
+		byID[sc.Id] = sc.ScheduledAt
+	}
+
+	now := synchro.Now[tz.AsiaTokyo]()
+	updated := make([]video.Video, 0)
+	for _, v := range targets {
+		sa, ok := byID[v.SourceID()]
+		if !ok || sa.IsZero() || sa.Equal(v.ScheduledAt()) {
+			continue
+		}
+		u, err := v.WithSchedule(sa, now)
+		if err != nil {
+			logging.FromContext(ctx).Error(
+				"Failed to reschedule a video",
+				"sourceID", v.SourceID(),
+				"error", err,
+			)
+			continue
+		}
+		logging.FromContext(ctx).Info("Rescheduled a video", "sourceID", v.SourceID(), "from", v.ScheduledAt().StdTime(), "to", sa.StdTime())
+		updated = append(updated, *u)
+	}
+
+	return s.update(ctx, updated, len(targets), opts.DryRun)
+}
+
+// Reconcile fetches the details of the live broadcasts and of the upcoming ones due within Reconcile.Ahead, and stores those that changed.
+// It is cheap when nothing is on air, so it can run every minute to follow a stream from its start to its end.
+func (s *RefreshService) Reconcile(ctx context.Context, opts RefreshOptions) (err error) {
+	ctx, span := s.tracer.Start(ctx, "RefreshService.Reconcile")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to reconcile")
+		}
+		span.End()
+	}()
+
+	now := synchro.Now[tz.AsiaTokyo]()
+	videos, err := s.rtdRepo.GetVideosByStatus(ctx, status.Upcoming, status.Live)
+	if err := skipInvalidRecords(ctx, err); err != nil {
+		return err
+	}
+	// an upcoming broadcast long past its schedule is left to the stale check
+	targets := s.inChannels(videos, opts, func(v video.Video) bool {
+		if v.Status() == status.Live {
+			return true
+		}
+		return v.Kind() != kind.FreeChat && !v.ScheduledAt().IsZero() &&
+			v.ScheduledAt().Before(now.Add(s.config.Reconcile.Ahead)) && !isStale(v, now, s.config.Stale.GracePeriod)
+	})
+	if len(targets) == 0 {
+		logging.FromContext(ctx).Info("No broadcast to reconcile")
+		return nil
+	}
+
+	// a video the API does not return, or that cannot be extracted, is left as it is
+	details, err := s.apiRepo.FetchVideoDetailsByVideoIDs(ctx, sourceIDsOf(ctx, targets))
+	var failed *api.ExtractionError
+	if err != nil && !errors.As(err, &failed) {
+		return err
+	}
+	byID := make(map[string]int, len(details))
+	for i, d := range details {
+		byID[d.Id] = i
+	}
+
+	updated := make([]video.Video, 0)
+	for _, v := range targets {
+		i, ok := byID[v.SourceID()]
+		if !ok {
+			continue
+		}
+		d := details[i]
+		if d.Status == v.Status() && d.ChatStatus == v.ChatStatus() && d.ScheduledAt.Equal(v.ScheduledAt()) &&
+			d.ActualStartAt.Equal(v.ActualStartAt()) && d.ActualEndAt.Equal(v.ActualEndAt()) {
+			continue
+		}
+
+		u, err := video.NewVideo(
+			d.ChannelId,
+			v.SourceID(),
+			v.Title(),
+			v.Description(),
+			d.ChatId,
+			d.Status,
+			v.PublishedAt(),
+			d.ScheduledAt,
+			now,
+			video.Live{ChatStatus: d.ChatStatus, ActualStartAt: d.ActualStartAt, ActualEndAt: d.ActualEndAt},
+			v.Kind(),
+		)
+		if err != nil {
+			logging.FromContext(ctx).Error(
+				"Failed to reconcile a video",
+				"sourceID", v.SourceID(),
+				"error", err,
+			)
+			continue
+		}
+		logging.FromContext(ctx).Info("Reconciled a video", "sourceID", v.SourceID(), "from", v.Status(), "to", d.Status)
+		updated = append(updated, *u)
+	}
+
+	return s.update(ctx, updated, len(targets), opts.DryRun)
+}
+
+// inChannels returns the videos in the channels of opts that keep accepts.
+func (s *RefreshService) inChannels(videos []video.Video, opts RefreshOptions, keep func(video.Video) bool) []video.Video {
+	res := make([]video.Video, 0)
+	for _, v := range videos {
+		if len(opts.ChannelIDs) > 0 && !slices.Contains(opts.ChannelIDs, v.ChannelID()) {
+			continue
+		}
+		if keep(v) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func (s *RefreshService) update(ctx context.Context, updated []video.Video, checked int, dryRun bool) error {
+	logging.FromContext(ctx).Info("Refresh report", "checked", checked, "changes", len(updated), "dryRun", dryRun)
+	if dryRun || len(updated) == 0 {
+		return nil
+	}
+
+	if err := s.rtdRepo.UpdateRecords(ctx, updated); err != nil {
+		return err
+	}
+	for _, v := range updated {
+		run.FromContext(ctx).AddUpserted(v.ChannelID(), 1)
+	}
+
+	return nil
+}
+
+// sourceIDsOf returns the source IDs of videos, counting each as fetched for the run.
+func sourceIDsOf(ctx context.Context, videos []video.Video) []string {
+	ids := make([]string, 0, len(videos))
+	for _, v := range videos {
+		ids = append(ids, v.SourceID())
+		run.FromContext(ctx).AddFetched(v.ChannelID(), 1)
+	}
+	return ids
+}
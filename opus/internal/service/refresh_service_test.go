@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"github.com/Code-Hex/synchro"
+	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeScheduleAPI returns the schedules of the requested videos that it knows
+type fakeScheduleAPI struct {
+	fakeDetailAPI
+	schedules map[string]synchro.Time[tz.AsiaTokyo]
+}
+
+func (f *fakeScheduleAPI) FetchScheduledAtByVideoIDs(_ context.Context, videoIDs []string) ([]dto.ScheduleResponse, error) {
+	f.requested = append(f.requested, videoIDs...)
+	res := make([]dto.ScheduleResponse, 0, len(videoIDs))
+	for _, id := range videoIDs {
+		if sa, ok := f.schedules[id]; ok {
+			res = append(res, dto.ScheduleResponse{Id: id, ScheduledAt: sa})
+		}
+	}
+	return res, nil
+}
+
+func refreshVideo(t *testing.T, sourceID string, st status.Status, k kind.Kind, scheduledAt synchro.Time[tz.AsiaTokyo], live video.Live) video.Video {
+	t.Helper()
+	v, err := video.NewVideo("channel", sourceID, "title", "", "chat", st, publishedAt, scheduledAt, publishedAt, live, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *v
+}
+
+func TestRefreshService_RefreshSchedules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := realtime.NewMemory()
+	clock := synchro.Now[tz.AsiaTokyo]()
+	upcoming := video.Live{ChatStatus: status.ChatEnabled}
+	if err := backend.UpsertRecords(ctx, []video.Video{
+		refreshVideo(t, "moved", status.Upcoming, kind.Live, clock.Add(time.Hour), upcoming),
+		refreshVideo(t, "kept", status.Upcoming, kind.Live, clock.Add(time.Hour), upcoming),
+		refreshVideo(t, "gone", status.Upcoming, kind.Live, clock.Add(time.Hour), upcoming),
+		refreshVideo(t, "free_chat", status.Upcoming, kind.FreeChat, clock.Add(24*time.Hour), upcoming),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	apiRepo := &fakeScheduleAPI{schedules: map[string]synchro.Time[tz.AsiaTokyo]{
+		"moved": clock.Add(2 * time.Hour),
+		"kept":  clock.Add(time.Hour),
+	}}
+	s := NewRefreshService(config.Config{}, apiRepo, backend)
+
+	// a dry run writes nothing
+	assert.NoError(t, s.RefreshSchedules(ctx, RefreshOptions{DryRun: true}))
+	got, err := backend.GetVideosBySourceIDs(ctx, []string{"moved"})
+	if assert.NoError(t, err) && assert.Len(t, got, 1) {
+		assert.True(t, got[0].ScheduledAt().Equal(clock.Add(time.Hour)))
+	}
+
+	apiRepo.requested = nil
+	assert.NoError(t, s.RefreshSchedules(ctx, RefreshOptions{}))
+
+	// the free-chat room is not asked, and only the moved video is rescheduled
+	slices.Sort(apiRepo.requested)
+	assert.Equal(t, []string{"gone", "kept", "moved"}, apiRepo.requested)
+	got, err = backend.GetVideosBySourceIDs(ctx, []string{"moved", "kept", "gone"})
+	if assert.NoError(t, err) {
+		schedules := make(map[string]time.Time)
+		for _, v := range got {
+			schedules[v.SourceID()] = v.ScheduledAt().StdTime()
+		}
+		assert.True(t, schedules["moved"].Equal(clock.Add(2*time.Hour).StdTime()))
+		assert.True(t, schedules["kept"].Equal(clock.Add(time.Hour).StdTime()))
+		assert.True(t, schedules["gone"].Equal(clock.Add(time.Hour).StdTime()))
+	}
+}
+
+func TestRefreshService_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := realtime.NewMemory()
+	clock := synchro.Now[tz.AsiaTokyo]()
+	upcoming := video.Live{ChatStatus: status.ChatEnabled}
+	onAir := video.Live{ChatStatus: status.ChatEnabled, ActualStartAt: clock.Add(-time.Hour)}
+	if err := backend.UpsertRecords(ctx, []video.Video{
+		refreshVideo(t, "starting", status.Upcoming, kind.Live, clock.Add(10*time.Minute), upcoming),
+		refreshVideo(t, "ending", status.Live, kind.Live, clock.Add(-time.Hour), onAir),
+		refreshVideo(t, "on_air", status.Live, kind.Live, clock.Add(-time.Hour), onAir),
+		refreshVideo(t, "later", status.Upcoming, kind.Live, clock.Add(5*time.Hour), upcoming),
+		refreshVideo(t, "stale", status.Upcoming, kind.Live, clock.Add(-24*time.Hour), upcoming),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	detail := func(sourceID string, st status.Status, scheduledAt synchro.Time[tz.AsiaTokyo], live video.Live) dto.DetailResponse {
+		return dto.DetailResponse{Id: sourceID, ChannelId: "channel", Title: "title", Status: st, Kind: kind.Live, PublishedAt: publishedAt, ScheduledAt: scheduledAt,
+			ChatId: "chat", ChatStatus: live.ChatStatus, ActualStartAt: live.ActualStartAt, ActualEndAt: live.ActualEndAt}
+	}
+	apiRepo := &fakeScheduleAPI{fakeDetailAPI: fakeDetailAPI{details: map[string]dto.DetailResponse{
+		"starting": detail("starting", status.Live, clock.Add(10*time.Minute), video.Live{ChatStatus: status.ChatEnabled, ActualStartAt: clock}),
+		"ending":   detail("ending", status.Archived, clock.Add(-time.Hour), video.Live{ChatStatus: status.ChatEnded, ActualStartAt: clock.Add(-time.Hour), ActualEndAt: clock}),
+		"on_air":   detail("on_air", status.Live, clock.Add(-time.Hour), onAir),
+	}}}
+	s := NewRefreshService(config.Config{Reconcile: config.Reconcile{Ahead: 30 * time.Minute}, Stale: config.Stale{GracePeriod: grace}}, apiRepo, backend)
+
+	assert.NoError(t, s.Reconcile(ctx, RefreshOptions{}))
+
+	// the broadcast far ahead and the one left to the stale check are not asked
+	slices.Sort(apiRepo.requested)
+	assert.Equal(t, []string{"ending", "on_air", "starting"}, apiRepo.requested)
+	got, err := backend.GetVideosBySourceIDs(ctx, []string{"starting", "ending", "on_air"})
+	if assert.NoError(t, err) {
+		statuses := make(map[string]status.Status)
+		for _, v := range got {
+			statuses[v.SourceID()] = v.Status()
+		}
+		assert.Equal(t, map[string]status.Status{"starting": status.Live, "ending": status.Archived, "on_air": status.Live}, statuses)
+	}
+
+	// nothing is on air in another channel, so nothing is asked
+	apiRepo.requested = nil
+	assert.NoError(t, s.Reconcile(ctx, RefreshOptions{ChannelIDs: []string{"other"}}))
+	assert.Empty(t, apiRepo.requested)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Code-Hex/synchro"
+	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// StaleService finds upcoming broadcasts whose schedule passed long ago, and asks the API what became of them.
+// Without it, a cancelled or forgotten frame stays upcoming forever and Animus keeps polling its chat.
+// A video marked missed is asked again within the grace period after, in case it started even later.
+type StaleService struct {
+	config  config.Config
+	apiRepo api.ApiRepository
+	rtdRepo realtime.RealtimeRepository
+	tracer  trace.Tracer
+}
+
+func NewStaleService(c config.Config, a api.ApiRepository, rt realtime.RealtimeRepository) *StaleService {
+	return &StaleService{
+		config:  c,
+		apiRepo: a,
+		rtdRepo: rt,
+		tracer:  otel.Tracer(instrumentationName),
+	}
+}
+
+// StaleOptions narrows a detection pass. The zero value checks every channel.
+type StaleOptions struct {
+	ChannelIDs []string
+	// DryRun reports the changes without writing them
+	DryRun bool
+}
+
+// StaleChange is what the pass found about one stale video.
+type StaleChange struct {
+	SourceID    string        `json:"sourceId"`
+	ChannelID   string        `json:"channelId"`
+	ScheduledAt time.Time     `json:"scheduledAt"`
+	Status      status.Status `json:"status"`
+	Reason      string        `json:"reason"`
+}
+
+// StaleReport is the result of a detection pass. A rescheduled video is a change that stays upcoming.
+type StaleReport struct {
+	Checked int           `json:"checked"`
+	Changes []StaleChange `json:"changes"`
+	DryRun  bool          `json:"dryRun,omitempty"`
+}
+
+func (s *StaleService) DetectStaleUpcoming(ctx context.Context, opts StaleOptions) (report *StaleReport, err error) {
+	ctx, span := s.tracer.Start(ctx, "StaleService.DetectStaleUpcoming")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to detect stale upcoming videos")
+		}
+		span.End()
+	}()
+
+	now := synchro.Now[tz.AsiaTokyo]()
+	candidates, err := s.rtdRepo.GetVideosByStatus(ctx, status.Upcoming, status.Missed)
+	if err := skipInvalidRecords(ctx, err); err != nil {
+		return nil, err
+	}
+
+	stale := make([]video.Video, 0)
+	for _, v := range candidates {
+		if len(opts.ChannelIDs) > 0 && !slices.Contains(opts.ChannelIDs, v.ChannelID()) {
+			continue
+		}
+		if isStale(v, now, s.config.Stale.GracePeriod) {
+			stale = append(stale, v)
+		}
+	}
+
+	report = &StaleReport{Checked: len(stale), Changes: make([]StaleChange, 0), DryRun: opts.DryRun}
+	if len(stale) == 0 {
+		logging.FromContext(ctx).Info("No stale upcoming video")
+		return report, nil
+	}
+
+	ids := make([]string, 0, len(stale))
+	for _, v := range stale {
+		ids = append(ids, v.SourceID())
+		run.FromContext(ctx).AddFetched(v.ChannelID(), 1)
+	}
+	// a video the API returned but that could not be extracted is left as it is, rather than taken for deleted
+	details, err := s.apiRepo.FetchVideoDetailsByVideoIDs(ctx, ids)
+	var failed *api.ExtractionError
+	if err != nil && !errors.As(err, &failed) {
+		return nil, err
+	}
+	unresolved := make(map[string]struct{})
+	if failed != nil {
+		for _, id := range failed.SourceIDs {
+			unresolved[id] = struct{}{}
+		}
+	}
+	byID := make(map[string]dto.DetailResponse, len(details))
+	for _, d := range details {
+		byID[d.Id] = d
+	}
+
+	updated := make([]video.Video, 0, len(stale))
+	for _, v := range stale {
+		if _, ok := unresolved[v.SourceID()]; ok {
+			continue
+		}
+		d, found := byID[v.SourceID()]
+		u, reason, err := resolveStale(v, d, found, now, s.config.Stale.GracePeriod)
+		if err != nil {
+			logging.FromContext(ctx).Error(
+				"Failed to resolve a stale video",
+				"sourceID", v.SourceID(),
+				"error", err,
+			)
+			continue
+		}
+		// a missed video that still did not start stays as it was marked, so that its grace period is not extended
+		if v.Status() == status.Missed && u.Status() == status.Missed {
+			continue
+		}
+
+		report.Changes = append(report.Changes, StaleChange{
+			SourceID:    u.SourceID(),
+			ChannelID:   u.ChannelID(),
+			ScheduledAt: u.ScheduledAt().StdTime(),
+			Status:      u.Status(),
+			Reason:      reason,
+		})
+		updated = append(updated, *u)
+	}
+
+	for _, c := range report.Changes {
+		logging.FromContext(ctx).Info(
+			"Resolved a stale upcoming video",
+			slog.Group("Stale", "sourceID", c.SourceID, "channelID", c.ChannelID, "status", c.Status, "reason", c.Reason),
+		)
+	}
+	logging.FromContext(ctx).Info("Stale upcoming report", "checked", report.Checked, "changes", len(report.Changes), "dryRun", opts.DryRun)
+
+	if opts.DryRun || len(updated) == 0 {
+		return report, nil
+	}
+
+	if err := s.rtdRepo.UpdateRecords(ctx, updated); err != nil {
+		return nil, err
+	}
+	for _, v := range updated {
+		run.FromContext(ctx).AddUpserted(v.ChannelID(), 1)
+	}
+
+	return report, nil
+}
+
+// skipInvalidRecords logs the rows a read could not rebuild and drops the error, so that the valid videos read with it are still handled.
+// Any other error is returned as is.
+func skipInvalidRecords(ctx context.Context, err error) error {
+	var invalid *realtime.InvalidRecordsError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	logging.FromContext(ctx).Warn(
+		"Skipped invalid video records",
+		slog.Group("Realtime", "sourceIDs", invalid.SourceIDs, "error", err),
+	)
+	return nil
+}
+
+// isStale reports whether v is an upcoming broadcast whose schedule passed more than grace ago,
+// or a broadcast marked missed less than grace ago.
+// A free-chat room is scheduled far ahead and never broadcast, so it is never stale.
+func isStale(v video.Video, now synchro.Time[tz.AsiaTokyo], grace time.Duration) bool {
+	if v.Kind() == kind.FreeChat || v.ScheduledAt().IsZero() {
+		return false
+	}
+
+	switch v.Status() {
+	case status.Upcoming:
+		return v.ScheduledAt().Add(grace).Before(now)
+	case status.Missed:
+		return now.Before(v.UpdatedAt().Add(grace))
+	default:
+		return false
+	}
+}
+
+// resolveStale decides the state of the stale video v from its details d, which found tells are returned by the API.
+//
+//	not returned                    cancelled, because it was deleted or made private
+//	live                            live
+//	ended after starting            archived
+//	ended without starting          cancelled
+//	upcoming, rescheduled           upcoming with the new schedule
+//	upcoming, still past the grace  missed
+func resolveStale(v video.Video, d dto.DetailResponse, found bool, now synchro.Time[tz.AsiaTokyo], grace time.Duration) (*video.Video, string, error) {
+	if !found {
+		u, err := v.WithStatus(status.Cancelled, now)
+		return u, "not returned by the API", err
+	}
+
+	st, reason := d.Status, ""
+	switch d.Status {
+	case status.Live:
+		reason = "started late"
+	case status.Archived:
+		reason = "ended"
+		if d.ActualStartAt.IsZero() {
+			st, reason = status.Cancelled, "ended without a broadcast"
+		}
+	case status.Upcoming:
+		reason = "rescheduled"
+		if d.ScheduledAt.IsZero() || d.ScheduledAt.Add(grace).Before(now) {
+			st, reason = status.Missed, "never started"
+		}
+	default:
+		u, err := v.WithStatus(status.Cancelled, now)
+		return u, "unknown state in the API", err
+	}
+
+	u, err := video.NewVideo(
+		v.ChannelID(),
+		v.SourceID(),
+		v.Title(),
+		v.Description(),
+		d.ChatId,
+		st,
+		v.PublishedAt(),
+		d.ScheduledAt,
+		now,
+		video.Live{ChatStatus: d.ChatStatus, ActualStartAt: d.ActualStartAt, ActualEndAt: d.ActualEndAt},
+		v.Kind(),
+	)
+
+	return u, reason, err
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"github.com/Code-Hex/synchro"
+	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"testing"
+	"time"
+)
+
+const grace = 6 * time.Hour
+
+var (
+	publishedAt = synchro.New[tz.AsiaTokyo](2024, 1, 1, 0, 0, 0, 0)
+	scheduledAt = synchro.New[tz.AsiaTokyo](2024, 1, 2, 20, 0, 0, 0)
+	now         = synchro.New[tz.AsiaTokyo](2024, 1, 3, 12, 0, 0, 0)
+)
+
+func upcomingVideo(t *testing.T, k kind.Kind, scheduledAt synchro.Time[tz.AsiaTokyo]) video.Video {
+	t.Helper()
+	v, err := video.NewVideo("channel", "source", "title", "", "chat", status.Upcoming, publishedAt, scheduledAt, publishedAt, video.Live{ChatStatus: status.ChatEnabled}, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *v
+}
+
+func missedVideo(t *testing.T, sourceID string, markedAt synchro.Time[tz.AsiaTokyo]) video.Video {
+	t.Helper()
+	v, err := video.NewVideo("channel", sourceID, "title", "", "chat", status.Missed, publishedAt, scheduledAt, markedAt, video.Live{ChatStatus: status.ChatEnabled}, kind.Live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *v
+}
+
+func TestIsStale(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		video video.Video
+		want  bool
+	}{
+		"past the grace":     {video: upcomingVideo(t, kind.Live, scheduledAt), want: true},
+		"within the grace":   {video: upcomingVideo(t, kind.Live, now.Add(-time.Hour)), want: false},
+		"scheduled ahead":    {video: upcomingVideo(t, kind.Live, now.Add(time.Hour)), want: false},
+		"free chat":          {video: upcomingVideo(t, kind.FreeChat, scheduledAt), want: false},
+		"without a schedule": {video: upcomingVideo(t, kind.Live, synchro.Time[tz.AsiaTokyo]{}), want: false},
+		"missed recently":    {video: missedVideo(t, "source", now.Add(-time.Hour)), want: true},
+		"missed long ago":    {video: missedVideo(t, "source", now.Add(-grace-time.Hour)), want: false},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, isStale(tt.video, now, grace))
+		})
+	}
+}
+
+func TestResolveStale(t *testing.T) {
+	t.Parallel()
+
+	startedAt := scheduledAt.Add(time.Hour)
+	endedAt := scheduledAt.Add(3 * time.Hour)
+	tests := map[string]struct {
+		detail     dto.DetailResponse
+		found      bool
+		wantStatus status.Status
+		wantReason string
+		wantAt     synchro.Time[tz.AsiaTokyo]
+	}{
+		"deleted": {
+			wantStatus: status.Cancelled,
+			wantReason: "not returned by the API",
+			wantAt:     scheduledAt,
+		},
+		"started late": {
+			detail:     dto.DetailResponse{Status: status.Live, ScheduledAt: scheduledAt, ActualStartAt: now.Add(-time.Hour)},
+			found:      true,
+			wantStatus: status.Live,
+			wantReason: "started late",
+			wantAt:     scheduledAt,
+		},
+		"ended": {
+			detail:     dto.DetailResponse{Status: status.Archived, ScheduledAt: scheduledAt, ActualStartAt: startedAt, ActualEndAt: endedAt},
+			found:      true,
+			wantStatus: status.Archived,
+			wantReason: "ended",
+			wantAt:     scheduledAt,
+		},
+		"ended without a broadcast": {
+			detail:     dto.DetailResponse{Status: status.Archived, ScheduledAt: scheduledAt},
+			found:      true,
+			wantStatus: status.Cancelled,
+			wantReason: "ended without a broadcast",
+			wantAt:     scheduledAt,
+		},
+		"rescheduled": {
+			detail:     dto.DetailResponse{Status: status.Upcoming, ScheduledAt: now.Add(24 * time.Hour)},
+			found:      true,
+			wantStatus: status.Upcoming,
+			wantReason: "rescheduled",
+			wantAt:     now.Add(24 * time.Hour),
+		},
+		"never started": {
+			detail:     dto.DetailResponse{Status: status.Upcoming, ScheduledAt: scheduledAt},
+			found:      true,
+			wantStatus: status.Missed,
+			wantReason: "never started",
+			wantAt:     scheduledAt,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, reason, err := resolveStale(upcomingVideo(t, kind.Live, scheduledAt), tt.detail, tt.found, now, grace)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantStatus, got.Status())
+			assert.Equal(t, tt.wantReason, reason)
+			assert.True(t, got.ScheduledAt().Equal(tt.wantAt), "scheduledAt = %v, want %v", got.ScheduledAt(), tt.wantAt)
+			assert.Equal(t, now, got.UpdatedAt())
+			assert.Equal(t, kind.Live, got.Kind())
+		})
+	}
+}
+
+// brokenRows reads the videos of the backend along with rows that failed validation
+type brokenRows struct {
+	*realtime.Memory
+	sourceIDs []string
+}
+
+func (b brokenRows) GetVideosByStatus(ctx context.Context, statuses ...status.Status) ([]video.Video, error) {
+	videos, err := b.Memory.GetVideosByStatus(ctx, statuses...)
+	if err != nil {
+		return nil, err
+	}
+	return videos, &realtime.InvalidRecordsError{SourceIDs: b.sourceIDs}
+}
+
+func TestStaleService_DetectStaleUpcoming_InvalidRecords(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := realtime.NewMemory()
+	if err := backend.UpsertRecords(ctx, []video.Video{upcomingVideo(t, kind.Live, scheduledAt)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the broken row is skipped, and the valid video is still resolved
+	s := NewStaleService(config.Config{Stale: config.Stale{GracePeriod: grace}}, &fakeDetailAPI{}, brokenRows{Memory: backend, sourceIDs: []string{"broken"}})
+	report, err := s.DetectStaleUpcoming(ctx, StaleOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, report.Checked)
+	if assert.Len(t, report.Changes, 1) {
+		assert.Equal(t, "source", report.Changes[0].SourceID)
+		assert.Equal(t, status.Cancelled, report.Changes[0].Status)
+	}
+}
+
+func TestStaleService_DetectStaleUpcoming(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := realtime.NewMemory()
+	clock := synchro.Now[tz.AsiaTokyo]()
+	stale := func(sourceID string) video.Video {
+		v, err := video.NewVideo("channel", sourceID, "title", "", "chat", status.Upcoming, publishedAt, scheduledAt, publishedAt, video.Live{ChatStatus: status.ChatEnabled}, kind.Live)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *v
+	}
+	if err := backend.UpsertRecords(ctx, []video.Video{
+		stale("deleted"),
+		stale("broken"),
+		missedVideo(t, "started", clock.Add(-time.Hour)),
+		missedVideo(t, "still_missed", clock.Add(-time.Hour)),
+		missedVideo(t, "missed_long_ago", clock.Add(-grace-time.Hour)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	detail := func(sourceID string, st status.Status, live video.Live) dto.DetailResponse {
+		return dto.DetailResponse{Id: sourceID, ChannelId: "channel", Title: "title", Status: st, Kind: kind.Live, PublishedAt: publishedAt, ScheduledAt: scheduledAt,
+			ChatId: "chat", ChatStatus: live.ChatStatus, ActualStartAt: live.ActualStartAt, ActualEndAt: live.ActualEndAt}
+	}
+	apiRepo := &fakeDetailAPI{
+		details: map[string]dto.DetailResponse{
+			"started":      detail("started", status.Live, video.Live{ChatStatus: status.ChatEnabled, ActualStartAt: clock.Add(-time.Minute)}),
+			"still_missed": detail("still_missed", status.Upcoming, video.Live{ChatStatus: status.ChatEnabled}),
+		},
+		failed: []string{"broken"},
+	}
+	s := NewStaleService(config.Config{Stale: config.Stale{GracePeriod: grace}}, apiRepo, backend)
+
+	report, err := s.DetectStaleUpcoming(ctx, StaleOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the video that failed extraction is not cancelled, and the missed video long past its grace is not asked again
+	slices.Sort(apiRepo.requested)
+	assert.Equal(t, []string{"broken", "deleted", "started", "still_missed"}, apiRepo.requested)
+	assert.Equal(t, 4, report.Checked)
+	changes := make(map[string]status.Status)
+	for _, c := range report.Changes {
+		changes[c.SourceID] = c.Status
+	}
+	assert.Equal(t, map[string]status.Status{"deleted": status.Cancelled, "started": status.Live}, changes)
+}
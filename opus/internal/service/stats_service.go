@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/stats"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"slices"
+	"time"
+)
+
+// StatsService samples the statistics of the tracked videos into snapshots.
+// It is meant to be called at the live interval, and samples the other videos only once their interval has passed.
+type StatsService struct {
+	schedule  stats.Schedule
+	apiRepo   api.ApiRepository
+	rtdRepo   realtime.RealtimeRepository
+	statsRepo realtime.StatsRepository
+	tracer    trace.Tracer
+	now       func() time.Time
+}
+
+func NewStatsService(c config.Config, a api.ApiRepository, rt realtime.RealtimeRepository, sr realtime.StatsRepository) *StatsService {
+	return &StatsService{
+		schedule:  stats.Schedule{LiveInterval: c.Stats.LiveInterval, Interval: c.Stats.Interval, Window: c.Stats.Window},
+		apiRepo:   a,
+		rtdRepo:   rt,
+		statsRepo: sr,
+		tracer:    otel.Tracer(instrumentationName),
+		now:       time.Now,
+	}
+}
+
+// StatsOptions narrows a sampling. The zero value samples every channel.
+type StatsOptions struct {
+	ChannelIDs []string
+}
+
+func (s *StatsService) SnapshotStats(ctx context.Context, opts StatsOptions) (err error) {
+	ctx, span := s.tracer.Start(ctx, "StatsService.SnapshotStats")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to snapshot stats")
+		}
+		span.End()
+	}()
+
+	now := s.now()
+	videos, err := s.rtdRepo.GetVideosByStatus(ctx, status.Upcoming, status.Live, status.Archived)
+	if err := skipInvalidRecords(ctx, err); err != nil {
+		return err
+	}
+
+	tracked := make([]video.Video, 0)
+	for _, v := range videos {
+		if len(opts.ChannelIDs) > 0 && !slices.Contains(opts.ChannelIDs, v.ChannelID()) {
+			continue
+		}
+		// a free-chat room is never broadcast, so it has nothing to chart
+		if v.Kind() == kind.FreeChat {
+			continue
+		}
+		if s.schedule.Tracked(v.Status(), endedAt(v), now) {
+			tracked = append(tracked, v)
+		}
+	}
+	if len(tracked) == 0 {
+		logging.FromContext(ctx).Info("No video to snapshot stats of")
+		return nil
+	}
+
+	ids := make([]string, 0, len(tracked))
+	for _, v := range tracked {
+		ids = append(ids, v.SourceID())
+	}
+	last, err := s.statsRepo.GetLastStatsSnapshotTimes(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	channels := make(map[string]string)
+	due := make([]string, 0, len(tracked))
+	for _, v := range tracked {
+		if s.schedule.Due(v.Status(), last[v.SourceID()], now) {
+			due = append(due, v.SourceID())
+			channels[v.SourceID()] = v.ChannelID()
+		}
+	}
+	if len(due) == 0 {
+		logging.FromContext(ctx).Info("No video is due for a stats snapshot", "tracked", len(tracked))
+		return nil
+	}
+
+	resp, err := s.apiRepo.FetchStatisticsByVideoIDs(ctx, due)
+	if err != nil {
+		return err
+	}
+
+	snapshots := make([]stats.Snapshot, 0, len(resp))
+	for _, r := range resp {
+		sn, err := stats.NewSnapshot(r.Id, r.ViewCount, r.LikeCount, r.CommentCount, r.ConcurrentViewers, now)
+		if err != nil {
+			logging.FromContext(ctx).Error(
+				"Failed to create a stats snapshot",
+				"sourceID", r.Id,
+				"error", err,
+			)
+			continue
+		}
+		snapshots = append(snapshots, *sn)
+		run.FromContext(ctx).AddFetched(channels[r.Id], 1)
+	}
+
+	if err := s.statsRepo.InsertStatsSnapshots(ctx, snapshots); err != nil {
+		return err
+	}
+	for _, sn := range snapshots {
+		run.FromContext(ctx).AddUpserted(channels[sn.SourceID()], 1)
+	}
+	logging.FromContext(ctx).Info("Took stats snapshots", "tracked", len(tracked), "due", len(due), "snapshots", len(snapshots))
+
+	return nil
+}
+
+// endedAt is when an archived broadcast ended, or when an upload was published.
+func endedAt(v video.Video) time.Time {
+	if !v.ActualEndAt().IsZero() {
+		return v.ActualEndAt().StdTime()
+	}
+	return v.PublishedAt().StdTime()
+}
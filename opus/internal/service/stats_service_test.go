@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/stats"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"testing"
+	"time"
+)
+
+type fakeStatsAPI struct {
+	requested []string
+}
+
+func (f *fakeStatsAPI) FetchVideoDetailsByVideoIDs(_ context.Context, _ []string) ([]dto.DetailResponse, error) {
+	return nil, nil
+}
+
+func (f *fakeStatsAPI) FetchScheduledAtByVideoIDs(_ context.Context, _ []string) ([]dto.ScheduleResponse, error) {
+	return nil, nil
+}
+
+func (f *fakeStatsAPI) FetchStatisticsByVideoIDs(_ context.Context, videoIDs []string) ([]dto.StatsResponse, error) {
+	f.requested = append(f.requested, videoIDs...)
+	res := make([]dto.StatsResponse, 0, len(videoIDs))
+	for _, id := range videoIDs {
+		res = append(res, dto.StatsResponse{Id: id, ViewCount: 100})
+	}
+	return res, nil
+}
+
+func (f *fakeStatsAPI) FetchChannelsByIDs(_ context.Context, _ []string) ([]dto.ChannelResponse, error) {
+	return nil, nil
+}
+
+func TestStatsService_SnapshotStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clock := now.StdTime()
+	backend := realtime.NewMemory()
+
+	newVideo := func(sourceID string, st status.Status, k kind.Kind, live video.Live) video.Video {
+		v, err := video.NewVideo("channel", sourceID, "title", "", "", st, publishedAt, scheduledAt, publishedAt, live, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *v
+	}
+	recentEnd := video.Live{ChatStatus: status.ChatEnded, ActualStartAt: now.Add(-3 * time.Hour), ActualEndAt: now.Add(-2 * time.Hour)}
+	if err := backend.UpsertRecords(ctx, []video.Video{
+		newVideo("live", status.Live, kind.Live, video.Live{ChatStatus: status.ChatEnabled, ActualStartAt: now.Add(-time.Hour)}),
+		newVideo("upcoming", status.Upcoming, kind.Live, video.Live{ChatStatus: status.ChatEnabled}),
+		newVideo("recent", status.Archived, kind.Live, recentEnd),
+		newVideo("old", status.Archived, kind.Upload, video.Live{}),
+		newVideo("free_chat", status.Upcoming, kind.FreeChat, video.Live{ChatStatus: status.ChatEnabled}),
+		newVideo("missed", status.Missed, kind.Live, video.Live{}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// every video was sampled 10 minutes ago, so only the live one is due again
+	previous := make([]stats.Snapshot, 0)
+	for _, id := range []string{"live", "upcoming", "recent"} {
+		s, err := stats.NewSnapshot(id, 50, 0, 0, nil, clock.Add(-10*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous = append(previous, *s)
+	}
+	if err := backend.InsertStatsSnapshots(ctx, previous); err != nil {
+		t.Fatal(err)
+	}
+
+	api := &fakeStatsAPI{}
+	s := NewStatsService(config.Config{Stats: config.Stats{LiveInterval: 5 * time.Minute, Interval: time.Hour, Window: 24 * time.Hour}}, api, backend, backend)
+	s.now = func() time.Time { return clock }
+
+	assert.NoError(t, s.SnapshotStats(ctx, StatsOptions{}))
+	assert.Equal(t, []string{"live"}, api.requested)
+
+	// an hour later, every tracked video is due
+	clock = clock.Add(time.Hour)
+	api.requested = nil
+	assert.NoError(t, s.SnapshotStats(ctx, StatsOptions{}))
+	slices.Sort(api.requested)
+	assert.Equal(t, []string{"live", "recent", "upcoming"}, api.requested)
+
+	series, err := backend.GetStatsSeries(ctx, "live", nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, series, 3)
+}
+
+func TestStatsService_SnapshotStats_InvalidRecords(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := realtime.NewMemory()
+	v, err := video.NewVideo("channel", "live", "title", "", "", status.Live, publishedAt, scheduledAt, publishedAt, video.Live{ChatStatus: status.ChatEnabled, ActualStartAt: now.Add(-time.Hour)}, kind.Live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.UpsertRecords(ctx, []video.Video{*v}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the broken row is skipped, and the valid video is still sampled
+	api := &fakeStatsAPI{}
+	s := NewStatsService(config.Config{Stats: config.Stats{LiveInterval: 5 * time.Minute, Interval: time.Hour, Window: 24 * time.Hour}}, api, brokenRows{Memory: backend, sourceIDs: []string{"broken"}}, backend)
+	s.now = func() time.Time { return now.StdTime() }
+
+	assert.NoError(t, s.SnapshotStats(ctx, StatsOptions{}))
+	assert.Equal(t, []string{"live"}, api.requested)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"github.com/Code-Hex/synchro"
 	"github.com/Code-Hex/synchro/tz"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
@@ -9,50 +10,122 @@ import (
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/rss"
 	rssDto "github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/rss/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
 	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
-	"log/slog"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"sort"
+	"time"
 )
 
 var ytRssURL = "https://www.youtube.com/feeds/videos.xml?channel_id="
 
+const instrumentationName = "github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/service"
+
 type SyncService struct {
 	config  config.Config
 	rssRepo rss.RSSRepository
 	apiRepo api.ApiRepository
 	rtdRepo realtime.RealtimeRepository
+	stRepo  realtime.SyncStateRepository
+	fcRepo  realtime.FreeChatRepository
+	fvRepo  realtime.FilteredVideoRepository
+	tracer  trace.Tracer
+	synced  metric.Int64Counter
 }
 
-func NewSyncService(c config.Config, r rss.RSSRepository, a api.ApiRepository, rt realtime.RealtimeRepository) *SyncService {
+func NewSyncService(c config.Config, r rss.RSSRepository, a api.ApiRepository, rt realtime.RealtimeRepository, st realtime.SyncStateRepository, fc realtime.FreeChatRepository, fv realtime.FilteredVideoRepository) *SyncService {
+	synced, err := otel.Meter(instrumentationName).Int64Counter(
+		"opus.videos.synced",
+		metric.WithDescription("Videos upserted into the videos table"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
 	return &SyncService{
 		config:  c,
 		rssRepo: r,
 		apiRepo: a,
 		rtdRepo: rt,
+		stRepo:  st,
+		fcRepo:  fc,
+		fvRepo:  fv,
+		tracer:  otel.Tracer(instrumentationName),
+		synced:  synced,
 	}
 }
 
-func (s *SyncService) SyncVideosWithRSS(ctx context.Context) error {
-	// Get the latest update time of the video info in the database(RealtimeDB)
-	// To eliminate RSS that has already been confirmed
-	luu, err := s.rtdRepo.GetLastUpdatedUnixOfVideo(ctx)
+// SyncOptions narrows a sync. The zero value syncs every target channel.
+type SyncOptions struct {
+	// ChannelIDs limits the sync to these target channels
+	ChannelIDs []string
+	// DryRun fetches and builds the videos without writing anything
+	DryRun bool
+	// Limit caps the RSS items taken from each channel, 0 means no cap. The items cut are taken by the next sync
+	Limit int
+}
+
+func (s *SyncService) SyncVideosWithRSS(ctx context.Context, opts SyncOptions) (err error) {
+	ctx, span := s.tracer.Start(ctx, "SyncService.SyncVideosWithRSS")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to sync videos with RSS")
+		}
+		span.End()
+	}()
+
+	// Each channel only takes the feed entries updated after its watermark,
+	// which only the RSS sync moves, so the writes of the other jobs cannot hide an entry
+	states, err := s.stRepo.GetSyncStates(ctx)
 	if err != nil {
 		return err
 	}
+	luu := make(map[string]int64, len(states))
+	for _, st := range states {
+		if st.RSSWatermark != nil {
+			luu[st.ChannelID] = st.RSSWatermark.Unix()
+		}
+	}
 
 	// Get updated videos from RSS
 	rssItemList := make([]rssDto.Item, 0, 5)
-	for _, c := range s.config.ChannelIDs() {
+	channelIDs := s.config.ChannelIDs()
+	if len(opts.ChannelIDs) > 0 {
+		channelIDs = opts.ChannelIDs
+	}
+	watermarks := make(map[string]time.Time)
+	for _, c := range channelIDs {
 		// generate rss url
 		url := ytRssURL + c
 		// fetch rss items
-		items, err := s.rssRepo.FetchRssItems(ctx, url, luu)
+		items, err := s.rssRepo.FetchRssItems(ctx, url, luu[c])
 		if err != nil {
 			return err
 		}
+		var cut []rssDto.Item
+		if opts.Limit > 0 && len(items) > opts.Limit {
+			items, cut = items[:opts.Limit], items[opts.Limit:]
+		}
+		if w, ok := advancedWatermark(items, cut); ok {
+			watermarks[c] = w
+		}
+		run.FromContext(ctx).AddFetched(c, len(items))
 		rssItemList = append(rssItemList, items...)
 	}
 
+	// Drop the videos that cost no quota to tell apart, before fetching their details
+	rssItemList, err = s.skipFiltered(ctx, rssItemList)
+	if err != nil {
+		return err
+	}
+
 	// Extract source IDs from updated rssItemList
 	sidList := make([]string, 0, len(rssItemList))
 	for _, r := range rssItemList {
@@ -60,14 +133,16 @@ func (s *SyncService) SyncVideosWithRSS(ctx context.Context) error {
 	}
 
 	// Get video details of updated videos from YouTube Data API
+	// the videos that could not be extracted were logged by the API, and are fetched again when their feed entries change
 	vdList, err := s.apiRepo.FetchVideoDetailsByVideoIDs(ctx, sidList)
-	if err != nil {
+	var failed *api.ExtractionError
+	if err != nil && !errors.As(err, &failed) {
 		return err
 	}
 
 	// if the difference between len(rssItemList) and len(vdList) is not 0, log it as a warning
 	if len(rssItemList) != len(vdList) {
-		slog.Warn(
+		logging.FromContext(ctx).Warn(
 			"Failed to get video details for all updated videos",
 			"rssItemList", len(rssItemList),
 			"videoDetails", len(vdList),
@@ -76,8 +151,31 @@ func (s *SyncService) SyncVideosWithRSS(ctx context.Context) error {
 
 	// Update the video details in the database(RealtimeDB)
 	videos := make([]video.Video, 0, len(vdList))
+	freeChats := make(map[string]video.Video)
+	freeChatRule := video.FreeChatRule{Horizon: s.config.FreeChat.Horizon, Keywords: s.config.FreeChat.Keywords}
+	now := synchro.Now[tz.AsiaTokyo]()
+
+	filtered := make([]*realtime.FilteredVideo, 0)
 
 	for _, vd := range vdList {
+		k := vd.Kind
+		if freeChatRule.Matches(vd.Title, vd.Status, vd.Kind, vd.ScheduledAt, now) {
+			k = kind.FreeChat
+		}
+
+		target, _ := s.config.TargetChannel(vd.ChannelId)
+		if keep, rule := target.Filters.Keep(config.Candidate{SourceID: vd.Id, Title: vd.Title, Kind: k, Duration: vd.Duration}); !keep {
+			logging.FromContext(ctx).Info("Filtered out a video", "channelID", vd.ChannelId, "sourceID", vd.Id, "rule", rule)
+			filtered = append(filtered, &realtime.FilteredVideo{
+				SourceID:    vd.Id,
+				ChannelID:   vd.ChannelId,
+				Rule:        rule,
+				Fingerprint: target.Filters.Fingerprint(),
+				FilteredAt:  now.StdTime(),
+			})
+			continue
+		}
+
 		// merge video info and rss info
 		m, err := video.NewVideo(
 			vd.ChannelId,
@@ -88,10 +186,12 @@ func (s *SyncService) SyncVideosWithRSS(ctx context.Context) error {
 			vd.Status,
 			vd.PublishedAt,
 			vd.ScheduledAt,
-			synchro.Now[tz.AsiaTokyo](),
+			now,
+			video.Live{ChatStatus: vd.ChatStatus, ActualStartAt: vd.ActualStartAt, ActualEndAt: vd.ActualEndAt},
+			k,
 		)
 		if err != nil {
-			slog.Error(
+			logging.FromContext(ctx).Error(
 				"Failed to create a video",
 				"sourceID", vd.Id,
 				"error", err,
@@ -100,13 +200,29 @@ func (s *SyncService) SyncVideosWithRSS(ctx context.Context) error {
 		}
 
 		videos = append(videos, *m)
+		// the latest room of a channel is the active one
+		if k == kind.FreeChat {
+			if fc, ok := freeChats[m.ChannelID()]; !ok || m.PublishedAt().After(fc.PublishedAt()) {
+				freeChats[m.ChannelID()] = *m
+			}
+		}
 	}
 
-	if len(videos) == 0 {
-		slog.Info("No new videos found")
+	if opts.DryRun {
+		logging.FromContext(ctx).Info("Dry run: skipped writing videos", "videos", len(videos), "filtered", len(filtered))
 		return nil
 	}
 
+	// Remembering is only to save quota, so a failure does not fail the sync
+	if err := s.fvRepo.RememberFilteredVideos(ctx, filtered); err != nil {
+		logging.FromContext(ctx).Warn("Failed to remember filtered videos", "filtered", len(filtered), "error", err)
+	}
+
+	if len(videos) == 0 {
+		logging.FromContext(ctx).Info("No new videos found")
+		return s.markSynced(ctx, channelIDs, watermarks)
+	}
+
 	// Sort the merged video info by published time
 	sort.Slice(videos, func(i, j int) bool {
 		return videos[i].PublishedAt().After(videos[j].PublishedAt())
@@ -116,6 +232,83 @@ func (s *SyncService) SyncVideosWithRSS(ctx context.Context) error {
 	if err := s.rtdRepo.UpsertRecords(ctx, videos); err != nil {
 		return err
 	}
+	s.synced.Add(ctx, int64(len(videos)))
+	for _, v := range videos {
+		run.FromContext(ctx).AddUpserted(v.ChannelID(), 1)
+	}
+	span.SetAttributes(attribute.Int("opus.videos.synced", len(videos)))
+
+	if len(freeChats) > 0 {
+		fcs := make([]video.Video, 0, len(freeChats))
+		for _, v := range freeChats {
+			fcs = append(fcs, v)
+		}
+		if err := s.fcRepo.SetFreeChats(ctx, fcs); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("Updated active free chats", "channels", len(fcs))
+	}
+
+	return s.markSynced(ctx, channelIDs, watermarks)
+}
+
+// advancedWatermark returns the update time of the newest item taken, kept below every item cut by the limit
+// so that the next sync takes them. It reports false when no item is taken.
+func advancedWatermark(taken, cut []rssDto.Item) (time.Time, bool) {
+	var w time.Time
+	for _, it := range taken {
+		if it.UpdatedAt.StdTime().After(w) {
+			w = it.UpdatedAt.StdTime()
+		}
+	}
+	if w.IsZero() {
+		return w, false
+	}
+	// the feed is compared in whole seconds
+	for _, it := range cut {
+		if below := it.UpdatedAt.StdTime().Truncate(time.Second).Add(-time.Second); below.Before(w) {
+			w = below
+		}
+	}
+	return w, true
+}
+
+// markSynced records the time of the last successful sync of the synced channels
+// so that a stalled sync can be detected from outside, and moves the RSS watermarks of those that took entries.
+func (s *SyncService) markSynced(ctx context.Context, channelIDs []string, watermarks map[string]time.Time) error {
+	if err := s.stRepo.MarkChannelsSynced(ctx, channelIDs, synchro.Now[tz.AsiaTokyo]().StdTime()); err != nil {
+		return err
+	}
+	if len(watermarks) == 0 {
+		return nil
+	}
+	return s.stRepo.SetRSSWatermarks(ctx, watermarks)
+}
+
+// skipFiltered drops the items that the filters of their channels ignore, and those that the current filters dropped before.
+func (s *SyncService) skipFiltered(ctx context.Context, items []rssDto.Item) ([]rssDto.Item, error) {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.SourceID)
+	}
+	fps, err := s.fvRepo.GetFilterFingerprints(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]rssDto.Item, 0, len(items))
+	for _, item := range items {
+		target, _ := s.config.TargetChannel(item.ChannelID)
+		if target.Filters.Ignores(item.SourceID) {
+			logging.FromContext(ctx).Info("Filtered out a video", "channelID", item.ChannelID, "sourceID", item.SourceID, "rule", "ignoreSourceIds")
+			continue
+		}
+		// filters that changed since may keep the video, so it is evaluated again
+		if fp, ok := fps[item.SourceID]; ok && fp == target.Filters.Fingerprint() {
+			continue
+		}
+		kept = append(kept, item)
+	}
 
-	return nil
+	return kept, nil
 }
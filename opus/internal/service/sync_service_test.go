@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"github.com/Code-Hex/synchro"
+	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/config"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/api/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	rssDto "github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/rss/dto"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRSS returns the items of the channel of the feed URL
+type fakeRSS map[string][]rssDto.Item
+
+func (f fakeRSS) FetchRssItems(_ context.Context, url string, _ int64) ([]rssDto.Item, error) {
+	return f[strings.TrimPrefix(url, ytRssURL)], nil
+}
+
+// fakeDetailAPI returns the details of the requested videos that it knows, and reports those it fails to extract
+type fakeDetailAPI struct {
+	fakeStatsAPI
+	details   map[string]dto.DetailResponse
+	failed    []string
+	requested []string
+}
+
+func (f *fakeDetailAPI) FetchVideoDetailsByVideoIDs(_ context.Context, videoIDs []string) ([]dto.DetailResponse, error) {
+	f.requested = append(f.requested, videoIDs...)
+	res := make([]dto.DetailResponse, 0, len(videoIDs))
+	failed := make([]string, 0)
+	for _, id := range videoIDs {
+		if d, ok := f.details[id]; ok {
+			res = append(res, d)
+		}
+		if slices.Contains(f.failed, id) {
+			failed = append(failed, id)
+		}
+	}
+	if len(failed) > 0 {
+		return res, &api.ExtractionError{SourceIDs: failed}
+	}
+	return res, nil
+}
+
+// withTarget is a config of the targets t alone
+func withTarget(t config.Target) config.Config {
+	c := config.Config{}
+	c.SetTarget(t)
+	return c
+}
+
+const (
+	mainChannelID = "UCmain000000000000000000"
+	subChannelID  = "UCsub0000000000000000000"
+)
+
+func TestSyncService_Filters(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := realtime.NewMemory()
+
+	item := func(channelID, sourceID string) rssDto.Item {
+		return rssDto.Item{ChannelID: channelID, SourceID: sourceID, Title: sourceID}
+	}
+	feeds := fakeRSS{
+		subChannelID:  {item(subChannelID, "stream"), item(subChannelID, "clip"), item(subChannelID, "short"), item(subChannelID, "ignored")},
+		mainChannelID: {item(mainChannelID, "upload")},
+	}
+	detail := func(channelID, sourceID string, k kind.Kind, d time.Duration) dto.DetailResponse {
+		vd := dto.DetailResponse{
+			Id:          sourceID,
+			ChannelId:   channelID,
+			Title:       sourceID,
+			Status:      status.Archived,
+			Kind:        k,
+			PublishedAt: synchro.New[tz.AsiaTokyo](2024, 1, 1, 0, 0, 0, 0),
+			Duration:    d,
+		}
+		if k == kind.Live {
+			vd.ChatStatus = status.ChatEnded
+			vd.ActualStartAt = synchro.New[tz.AsiaTokyo](2024, 1, 1, 1, 0, 0, 0)
+			vd.ActualEndAt = synchro.New[tz.AsiaTokyo](2024, 1, 1, 3, 0, 0, 0)
+		}
+		return vd
+	}
+	details := map[string]dto.DetailResponse{
+		"stream":  detail(subChannelID, "stream", kind.Live, 2*time.Hour),
+		"clip":    detail(subChannelID, "clip", kind.Upload, 5*time.Minute),
+		"short":   detail(subChannelID, "short", kind.Short, 30*time.Second),
+		"ignored": detail(subChannelID, "ignored", kind.Live, 2*time.Hour),
+		"upload":  detail(mainChannelID, "upload", kind.Upload, 5*time.Minute),
+	}
+
+	target, err := config.ParseTarget([]byte(`
+channel:
+  - display: main
+    channelId: `+mainChannelID+`
+  - display: sub
+    channelId: `+subChannelID+`
+    filters:
+      includeKind: [live, premiere]
+      minDuration: 10m
+      ignoreSourceIds: [ignored]
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runSync := func(c config.Config) []string {
+		t.Helper()
+		api := &fakeDetailAPI{details: details}
+		svc := NewSyncService(c, feeds, api, backend, backend, backend, backend)
+		if err := svc.SyncVideosWithRSS(ctx, SyncOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(api.requested)
+		return api.requested
+	}
+
+	// the ignored video is dropped without fetching its details, and the clip and the short after it
+	assert.Equal(t, []string{"clip", "short", "stream", "upload"}, runSync(withTarget(*target)))
+	stored, err := backend.GetVideosBySourceIDs(ctx, []string{"stream", "clip", "short", "ignored", "upload"})
+	assert.NoError(t, err)
+	ids := make([]string, 0, len(stored))
+	for _, v := range stored {
+		ids = append(ids, v.SourceID())
+	}
+	slices.Sort(ids)
+	assert.Equal(t, []string{"stream", "upload"}, ids)
+
+	// the filtered videos are remembered, so they are not fetched again
+	assert.Equal(t, []string{"stream", "upload"}, runSync(withTarget(*target)))
+
+	// once the filters change, they are evaluated again
+	unfiltered := withTarget(config.Target{Channel: []config.Channel{{ChannelId: mainChannelID}, {ChannelId: subChannelID}}})
+	assert.Equal(t, []string{"clip", "ignored", "short", "stream", "upload"}, runSync(unfiltered))
+}
+
+// fakeFeed returns the items of the channel of the feed URL updated after limitUnix, as the RSS extractor does
+type fakeFeed map[string][]rssDto.Item
+
+func (f fakeFeed) FetchRssItems(_ context.Context, url string, limitUnix int64) ([]rssDto.Item, error) {
+	res := make([]rssDto.Item, 0)
+	for _, it := range f[strings.TrimPrefix(url, ytRssURL)] {
+		if it.UpdatedAt.Unix() > limitUnix {
+			res = append(res, it)
+		}
+	}
+	return res, nil
+}
+
+func TestSyncService_Watermark(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := realtime.NewMemory()
+	t0 := synchro.New[tz.AsiaTokyo](2024, 1, 1, 0, 0, 0, 0)
+
+	item := func(channelID, sourceID string, updatedAt synchro.Time[tz.AsiaTokyo]) rssDto.Item {
+		return rssDto.Item{ChannelID: channelID, SourceID: sourceID, Title: sourceID, PublishedAt: t0, UpdatedAt: updatedAt}
+	}
+	feeds := fakeFeed{
+		mainChannelID: {item(mainChannelID, "first", t0)},
+		subChannelID:  {item(subChannelID, "other", t0)},
+	}
+	details := make(map[string]dto.DetailResponse)
+	for _, id := range []string{"first", "second", "third", "fourth", "other"} {
+		c := mainChannelID
+		if id == "other" {
+			c = subChannelID
+		}
+		details[id] = dto.DetailResponse{Id: id, ChannelId: c, Title: id, Status: status.Archived, Kind: kind.Upload, PublishedAt: t0}
+	}
+	c := withTarget(config.Target{Channel: []config.Channel{{ChannelId: mainChannelID}, {ChannelId: subChannelID}}})
+	runSync := func(opts SyncOptions) []string {
+		t.Helper()
+		api := &fakeDetailAPI{details: details}
+		svc := NewSyncService(c, feeds, api, backend, backend, backend, backend)
+		if err := svc.SyncVideosWithRSS(ctx, opts); err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(api.requested)
+		return api.requested
+	}
+
+	assert.Equal(t, []string{"first"}, runSync(SyncOptions{ChannelIDs: []string{mainChannelID}}))
+
+	// another job writing a video later does not move the watermark
+	v, err := video.NewVideo(mainChannelID, "first", "first", "", "", status.Archived, t0, synchro.Time[tz.AsiaTokyo]{}, synchro.Now[tz.AsiaTokyo](), video.Live{}, kind.Upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, backend.UpdateRecords(ctx, []video.Video{*v}))
+	feeds[mainChannelID] = append(feeds[mainChannelID], item(mainChannelID, "second", t0.Add(time.Hour)))
+
+	// the channel left out of the first sync still takes its entries
+	assert.Equal(t, []string{"other", "second"}, runSync(SyncOptions{}))
+	assert.Empty(t, runSync(SyncOptions{}))
+
+	// the entries cut by the limit are taken by the next sync, even when they are older than those taken
+	feeds[mainChannelID] = append([]rssDto.Item{item(mainChannelID, "fourth", t0.Add(3*time.Hour)), item(mainChannelID, "third", t0.Add(2*time.Hour))}, feeds[mainChannelID]...)
+	assert.Equal(t, []string{"fourth"}, runSync(SyncOptions{Limit: 1}))
+	assert.Equal(t, []string{"fourth", "third"}, runSync(SyncOptions{}))
+	assert.Empty(t, runSync(SyncOptions{}))
+}
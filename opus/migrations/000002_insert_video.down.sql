@@ -1 +0,0 @@
-delete from videos;
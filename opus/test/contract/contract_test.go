@@ -0,0 +1,211 @@
+// Package contract runs the repositories of Opus and Animus against one database
+// migrated with the shared schema, so that a drift between the two functions fails here.
+package contract
+
+import (
+	"context"
+	"github.com/Code-Hex/synchro"
+	"github.com/Code-Hex/synchro/tz"
+	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/infra"
+	"github.com/KasumiMercury/patotta-stone-functions-go/animus/pkg/model"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/adapters/db/realtime"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/run"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/internal/domain/video"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/test/migrate"
+	"github.com/KasumiMercury/patotta-stone-functions-go/opus/test/testcontainers"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/schema"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/google/go-cmp/cmp"
+	"github.com/uptrace/bun"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+var (
+	opus   *realtime.Realtime
+	animus *infra.SupabaseRepository
+	db     *bun.DB
+)
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+
+	container, err := testcontainers.SetUpRealtimeContainer(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	connStr, err := container.ConnectionString(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	connStr += "sslmode=disable"
+
+	if err := migrate.Migrate(connStr); err != nil {
+		log.Fatalf("failed to migrate: %v", err)
+	}
+
+	opus, err = realtime.NewRealtimeClient(connStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err = infra.NewSupabaseClient(connStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	animus = infra.NewSupabaseRepository(db)
+
+	code := m.Run()
+
+	if err := container.Terminate(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	os.Exit(code)
+}
+
+// TestModelsMatchTables selects every column of every model, so that a column missing from the migrations fails.
+func TestModelsMatchTables(t *testing.T) {
+	t.Parallel()
+
+	models := map[string]any{
+		"videos":                &[]schema.Video{},
+		"chats":                 &[]schema.Chat{},
+		"fetch_chat_history":    &[]schema.FetchChatHistory{},
+		"free_chats":            &[]schema.FreeChat{},
+		"channel_sync_states":   &[]realtime.SyncState{},
+		"sync_runs":             &[]realtime.SyncRun{},
+		"video_stats_snapshots": &[]realtime.StatsSnapshot{},
+		"channels":              &[]realtime.Channel{},
+		"targets":               &[]realtime.Target{},
+		"filtered_videos":       &[]realtime.FilteredVideo{},
+	}
+
+	for name, m := range models {
+		name, m := name, m
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if err := db.NewSelect().Model(m).Limit(1).Scan(context.Background()); err != nil {
+				t.Errorf("error: %v", err)
+			}
+		})
+	}
+}
+
+func TestVideoWrittenByOpusIsReadByAnimus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	upsert(t, "contract_upcoming", status.Upcoming)
+	upsert(t, "contract_live", status.Live)
+
+	records, err := animus.GetVideoInfoByStatus(ctx, []status.Status{status.Upcoming, status.Live})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	got := make(map[string]status.Status)
+	for _, r := range records {
+		got[r.SourceID] = r.Status
+	}
+	for id, want := range map[string]status.Status{"contract_upcoming": status.Upcoming, "contract_live": status.Live} {
+		if got[id] != want {
+			t.Errorf("%s: want: %v, got: %v", id, want, got[id])
+		}
+	}
+}
+
+func TestStatusWrittenByAnimusIsReadByOpus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	upsert(t, "contract_archive", status.Upcoming)
+
+	if err := animus.UpdateStatusBySourceID(ctx, "contract_archive", status.Archived); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	videos, err := opus.GetVideosBySourceIDs(ctx, []string{"contract_archive"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(videos) != 1 || videos[0].Status() != status.Archived {
+		t.Errorf("want an archived video, got: %v", videos)
+	}
+}
+
+func TestChatsAndFetchHistory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	publishedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	chats := []model.ChatRecord{
+		{SourceID: "contract_chat", Message: "hello", PublishedAt: publishedAt.Add(-time.Minute)},
+		{SourceID: "contract_chat", Message: "hello", IsNegative: true, PublishedAt: publishedAt},
+	}
+	if err := animus.InsertChatRecord(ctx, chats); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	last, err := animus.GetPublishedAtOfLastRecordedChatBySource(ctx, "contract_chat")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if last != publishedAt.Unix() {
+		t.Errorf("want: %v, got: %v", publishedAt.Unix(), last)
+	}
+
+	for _, id := range []string{"contract_history_1", "contract_history_2", "contract_history_1"} {
+		if err := animus.InsertFetchedHistory(ctx, id); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+
+	histories, err := animus.GetFetchedHistory(ctx, []string{"contract_history_1", "contract_history_2"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	ids := make([]string, 0, len(histories))
+	for _, h := range histories {
+		ids = append(ids, h.SourceID)
+	}
+	// the latest history of each video, oldest first
+	if diff := cmp.Diff([]string{"contract_history_2", "contract_history_1"}, ids); diff != "" {
+		t.Errorf("GetFetchedHistory() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSyncRunLedger(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rec := run.NewRecorder(run.OperationRSSSync, run.TriggerManual, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	id, err := opus.StartRun(ctx, rec.Stats())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	got, err := opus.GetRun(ctx, id)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if got == nil || got.Outcome != string(run.OutcomeRunning) {
+		t.Errorf("want a running run, got: %v", got)
+	}
+}
+
+func upsert(t *testing.T, sourceID string, st status.Status) {
+	t.Helper()
+
+	at := synchro.In[tz.AsiaTokyo](time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	v, err := video.NewVideo("contract_channel", sourceID, "title", "description", "chat_"+sourceID, st, at, at.Add(time.Hour), at, video.Live{}, kind.Live)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := opus.UpsertRecords(context.Background(), []video.Video{*v}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+}
@@ -1,45 +1,45 @@
 package migrate
 
 import (
+	"context"
 	"database/sql"
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/golang-migrate/migrate/v4/source/github"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/migration"
 	"github.com/uptrace/bun/driver/pgdriver"
-	"log"
 )
 
-func Migrate(dsn string, migrationPath string) error {
+//go:embed testdata/seed.sql
+var seed string
+
+// Migrate applies the shared migration set embedded in the schema package.
+func Migrate(dsn string) (err error) {
 	db := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
 	defer func() {
-		if err := db.Close(); err != nil {
-			log.Fatalf("failed to close db: %v", err)
-		}
+		err = errors.Join(err, db.Close())
 	}()
 
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	r, err := migration.NewPostgresRunner(context.Background(), db)
 	if err != nil {
-		log.Fatalf("failed to create driver: %v", err)
 		return err
 	}
+	defer func() {
+		err = errors.Join(err, r.Close())
+	}()
 
-	log.Println("migrate start")
-	log.Println("migrationPath: ", migrationPath)
+	return r.Up()
+}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+migrationPath,
-		"postgres",
-		driver,
-	)
-	if err != nil {
-		return err
-	}
+// Seed inserts the videos the repository tests read.
+func Seed(dsn string) (err error) {
+	db := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
 
-	if err = m.Up(); err != nil {
-		log.Fatalf("failed to migrate: %v", err)
-		return err
+	if _, err := db.Exec(seed); err != nil {
+		return fmt.Errorf("failed to seed: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,201 @@
+// Package auth authenticates requests by the OIDC ID token in the Authorization header,
+// such as the token Cloud Scheduler attaches for a service account.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/golang-jwt/jwt/v5"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	googleIssuer = "https://accounts.google.com"
+	googleJWKS   = "https://www.googleapis.com/oauth2/v3/certs"
+	// clockSkew is tolerated between the issuer and the function when checking expiry
+	clockSkew = 30 * time.Second
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is a valid token of a caller that is not allowed
+	ErrForbidden = errors.New("caller is not allowed")
+)
+
+type Config struct {
+	// Audience is the expected aud claim, usually the URL of the function. Authentication is disabled without it.
+	Audience string
+	Issuer   string
+	// AllowedEmails are the service accounts allowed to call. Any caller with a valid token is allowed if empty.
+	AllowedEmails []string
+	JWKSURL       string
+	// JWKSFile is a JWK set to verify against instead of JWKSURL, for an environment without network access
+	JWKSFile string
+	// PublicPaths are served without a token, such as the health checks
+	PublicPaths []string
+}
+
+// ConfigFromEnv reads AUTH_AUDIENCE, AUTH_ISSUER, AUTH_ALLOWED_EMAILS, AUTH_JWKS_URL, AUTH_JWKS_FILE and AUTH_PUBLIC_PATHS.
+// The issuer and JWKS endpoint default to Google, and the public paths to the health checks.
+func ConfigFromEnv() Config {
+	c := Config{
+		Audience:      os.Getenv("AUTH_AUDIENCE"),
+		Issuer:        os.Getenv("AUTH_ISSUER"),
+		AllowedEmails: splitList(os.Getenv("AUTH_ALLOWED_EMAILS")),
+		JWKSURL:       os.Getenv("AUTH_JWKS_URL"),
+		JWKSFile:      os.Getenv("AUTH_JWKS_FILE"),
+		PublicPaths:   []string{"/healthz", "/readyz"},
+	}
+	if c.Issuer == "" {
+		c.Issuer = googleIssuer
+	}
+	if c.JWKSURL == "" {
+		c.JWKSURL = googleJWKS
+	}
+	if p, ok := os.LookupEnv("AUTH_PUBLIC_PATHS"); ok {
+		c.PublicPaths = splitList(p)
+	}
+
+	return c
+}
+
+func (c Config) Enabled() bool {
+	return c.Audience != ""
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// Claims are the claims of a Google-signed ID token the verifier reads.
+type Claims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	jwt.RegisteredClaims
+}
+
+type Verifier struct {
+	config Config
+	keys   KeySet
+	now    func() time.Time
+}
+
+// NewVerifier returns the verifier of c. The JWKS file is read here, and the JWKS endpoint on the first request.
+func NewVerifier(c Config) (*Verifier, error) {
+	if !c.Enabled() {
+		return nil, errors.New("audience is not set")
+	}
+	if c.Issuer == "" {
+		return nil, errors.New("issuer is not set")
+	}
+
+	var keys KeySet
+	switch {
+	case c.JWKSFile != "":
+		ks, err := NewFileKeySet(c.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = ks
+	case c.JWKSURL != "":
+		keys = NewRemoteKeySet(c.JWKSURL, nil)
+	default:
+		return nil, errors.New("neither JWKS URL nor file is set")
+	}
+
+	return newVerifier(c, keys, time.Now), nil
+}
+
+func newVerifier(c Config, keys KeySet, now func() time.Time) *Verifier {
+	return &Verifier{config: c, keys: keys, now: now}
+}
+
+// Verify checks the signature, issuer, audience and expiry of the raw token, and then the caller.
+// The error wraps ErrUnauthenticated or ErrForbidden.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(v.config.Issuer),
+		jwt.WithAudience(v.config.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(v.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	if len(v.config.AllowedEmails) > 0 {
+		if !claims.EmailVerified || !slices.Contains(v.config.AllowedEmails, claims.Email) {
+			return nil, fmt.Errorf("%w: %q", ErrForbidden, claims.Email)
+		}
+	}
+
+	return claims, nil
+}
+
+// Middleware rejects a request without a valid bearer token before it reaches next.
+// A nil verifier disables authentication, so that the functions run without it when AUTH_AUDIENCE is not set.
+func Middleware(v *Verifier, next http.HandlerFunc) http.HandlerFunc {
+	if v == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(v.config.PublicPaths, r.URL.Path) {
+			next(w, r)
+			return
+		}
+
+		raw, ok := bearerToken(r)
+		if !ok {
+			unauthorized(w, r, fmt.Errorf("%w: no bearer token", ErrUnauthenticated))
+			return
+		}
+		claims, err := v.Verify(r.Context(), raw)
+		if errors.Is(err, ErrForbidden) {
+			logging.FromContext(r.Context()).Warn("Rejected request of a caller that is not allowed", slog.Group("Auth", "error", err))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			unauthorized(w, r, err)
+			return
+		}
+
+		l := logging.FromContext(r.Context()).With(slog.String("caller", claims.Email))
+		next(w, r.WithContext(logging.WithLogger(r.Context(), l)))
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	logging.FromContext(r.Context()).Warn("Rejected unauthenticated request", slog.Group("Auth", "error", err))
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	http.Error(w, "unauthenticated", http.StatusUnauthorized)
+}
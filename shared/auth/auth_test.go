@@ -0,0 +1,333 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://accounts.google.com"
+	testAudience = "https://opus.example.com"
+	testCaller   = "scheduler@project.iam.gserviceaccount.com"
+)
+
+var testNow = time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func jwksOf(t *testing.T, keys map[string]*rsa.PrivateKey) []byte {
+	t.Helper()
+
+	var set jwks
+	for kid, k := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func sign(t *testing.T, k *rsa.PrivateKey, kid string, c Claims) string {
+	t.Helper()
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() Claims {
+	return Claims{
+		Email:         testCaller,
+		EmailVerified: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(testNow.Add(-time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(testNow.Add(time.Hour)),
+		},
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	key := newKey(t)
+	other := newKey(t)
+	keys, err := parseJWKS(jwksOf(t, map[string]*rsa.PrivateKey{"key-1": key}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		token   func(t *testing.T) string
+		allowed []string
+		wantErr error
+	}{
+		"valid": {
+			token: func(t *testing.T) string { return sign(t, key, "key-1", validClaims()) },
+		},
+		"allowed caller": {
+			token:   func(t *testing.T) string { return sign(t, key, "key-1", validClaims()) },
+			allowed: []string{testCaller},
+		},
+		"caller not allowed": {
+			token:   func(t *testing.T) string { return sign(t, key, "key-1", validClaims()) },
+			allowed: []string{"deployer@project.iam.gserviceaccount.com"},
+			wantErr: ErrForbidden,
+		},
+		"unverified email": {
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.EmailVerified = false
+				return sign(t, key, "key-1", c)
+			},
+			allowed: []string{testCaller},
+			wantErr: ErrForbidden,
+		},
+		"expired": {
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.ExpiresAt = jwt.NewNumericDate(testNow.Add(-time.Minute))
+				return sign(t, key, "key-1", c)
+			},
+			wantErr: ErrUnauthenticated,
+		},
+		"expired within the clock skew": {
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.ExpiresAt = jwt.NewNumericDate(testNow.Add(-10 * time.Second))
+				return sign(t, key, "key-1", c)
+			},
+		},
+		"without expiry": {
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.ExpiresAt = nil
+				return sign(t, key, "key-1", c)
+			},
+			wantErr: ErrUnauthenticated,
+		},
+		"other audience": {
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Audience = jwt.ClaimStrings{"https://animus.example.com"}
+				return sign(t, key, "key-1", c)
+			},
+			wantErr: ErrUnauthenticated,
+		},
+		"other issuer": {
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Issuer = "https://issuer.example.com"
+				return sign(t, key, "key-1", c)
+			},
+			wantErr: ErrUnauthenticated,
+		},
+		"signed by another key": {
+			token:   func(t *testing.T) string { return sign(t, other, "key-1", validClaims()) },
+			wantErr: ErrUnauthenticated,
+		},
+		"unknown key ID": {
+			token:   func(t *testing.T) string { return sign(t, key, "key-2", validClaims()) },
+			wantErr: ErrUnauthenticated,
+		},
+		"unsigned": {
+			token: func(t *testing.T) string {
+				s, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+			wantErr: ErrUnauthenticated,
+		},
+		"malformed": {
+			token:   func(_ *testing.T) string { return "not.a.token" },
+			wantErr: ErrUnauthenticated,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			v := newVerifier(Config{Issuer: testIssuer, Audience: testAudience, AllowedEmails: tt.allowed}, staticKeySet(keys), func() time.Time { return testNow })
+
+			claims, err := v.Verify(context.Background(), tt.token(t))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, testCaller, claims.Email)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	key := newKey(t)
+	keys, err := parseJWKS(jwksOf(t, map[string]*rsa.PrivateKey{"key-1": key}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := newVerifier(Config{
+		Issuer:        testIssuer,
+		Audience:      testAudience,
+		AllowedEmails: []string{testCaller},
+		PublicPaths:   []string{"/healthz"},
+	}, staticKeySet(keys), time.Now)
+
+	valid := validClaims()
+	valid.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	valid.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	notAllowed := valid
+	notAllowed.Email = "other@project.iam.gserviceaccount.com"
+
+	tests := map[string]struct {
+		path          string
+		authorization string
+		wantCode      int
+	}{
+		"valid token":        {path: "/", authorization: "Bearer " + sign(t, key, "key-1", valid), wantCode: http.StatusOK},
+		"lowercase scheme":   {path: "/", authorization: "bearer " + sign(t, key, "key-1", valid), wantCode: http.StatusOK},
+		"no token":           {path: "/", wantCode: http.StatusUnauthorized},
+		"basic auth":         {path: "/", authorization: "Basic dXNlcjpwYXNz", wantCode: http.StatusUnauthorized},
+		"invalid token":      {path: "/", authorization: "Bearer not.a.token", wantCode: http.StatusUnauthorized},
+		"caller not allowed": {path: "/", authorization: "Bearer " + sign(t, key, "key-1", notAllowed), wantCode: http.StatusForbidden},
+		"public path":        {path: "/healthz", wantCode: http.StatusOK},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			called := false
+			h := Middleware(v, func(w http.ResponseWriter, _ *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantCode == http.StatusOK, called)
+			if tt.wantCode == http.StatusUnauthorized {
+				assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestMiddleware_Disabled(t *testing.T) {
+	t.Parallel()
+
+	h := Middleware(nil, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestNewVerifier_JWKSFile(t *testing.T) {
+	t.Parallel()
+
+	key := newKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksOf(t, map[string]*rsa.PrivateKey{"key-1": key}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewVerifier(Config{Issuer: testIssuer, Audience: testAudience, JWKSFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := validClaims()
+	c.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	_, err = v.Verify(context.Background(), sign(t, key, "key-1", c))
+	assert.NoError(t, err)
+
+	_, err = NewVerifier(Config{Issuer: testIssuer, Audience: testAudience, JWKSFile: filepath.Join(t.TempDir(), "missing.json")})
+	assert.Error(t, err)
+}
+
+func TestRemoteKeySet_Key(t *testing.T) {
+	t.Parallel()
+
+	first := newKey(t)
+	second := newKey(t)
+	var fetches atomic.Int32
+	var body atomic.Value
+	body.Store(jwksOf(t, map[string]*rsa.PrivateKey{"key-1": first}))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(body.Load().([]byte))
+	}))
+	t.Cleanup(srv.Close)
+
+	now := testNow
+	ks := NewRemoteKeySet(srv.URL, srv.Client())
+	ks.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	k, err := ks.Key(ctx, "key-1")
+	assert.NoError(t, err)
+	assert.True(t, first.PublicKey.Equal(k))
+	_, err = ks.Key(ctx, "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load(), "cached keys are used")
+
+	// a rotated key is not refetched more often than the refetch interval
+	body.Store(jwksOf(t, map[string]*rsa.PrivateKey{"key-1": first, "key-2": second}))
+	_, err = ks.Key(ctx, "key-2")
+	assert.ErrorIs(t, err, errUnknownKey)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	now = now.Add(remoteRefetchInterval)
+	k, err = ks.Key(ctx, "key-2")
+	assert.NoError(t, err)
+	assert.True(t, second.PublicKey.Equal(k))
+	assert.Equal(t, int32(2), fetches.Load())
+}
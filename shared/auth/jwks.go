@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// KeySet resolves the public key that signed a token by its key ID.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// parseJWKS returns the RSA signing keys of a JWK set by key ID. Keys of other types are ignored.
+func parseJWKS(b []byte) (map[string]*rsa.PublicKey, error) {
+	var set jwks
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("malformed JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("malformed modulus of key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("malformed exponent of key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no RSA signing key")
+	}
+
+	return keys, nil
+}
+
+// staticKeySet is a JWK set read once, such as from a file for an offline setup.
+type staticKeySet map[string]*rsa.PublicKey
+
+// NewFileKeySet reads the JWK set in the file at path.
+func NewFileKeySet(path string) (KeySet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return staticKeySet(keys), nil
+}
+
+func (s staticKeySet) Key(_ context.Context, kid string) (*rsa.PublicKey, error) {
+	k, ok := s[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+	}
+	return k, nil
+}
+
+const (
+	// remoteKeysTTL is how long fetched keys are used before they are fetched again
+	remoteKeysTTL = time.Hour
+	// remoteRefetchInterval limits the fetches for a key ID that is not in the set
+	remoteRefetchInterval = time.Minute
+)
+
+// RemoteKeySet fetches the JWK set from a JWKS endpoint on first use and caches it.
+// An unknown key ID triggers a refetch, so that rotated keys are picked up before the cache expires.
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteKeySet{url: url, client: client, now: time.Now}
+}
+
+func (s *RemoteKeySet) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	age := s.now().Sub(s.fetchedAt)
+	k, ok := s.keys[kid]
+	if ok && age < remoteKeysTTL {
+		return k, nil
+	}
+	if s.keys == nil || age >= remoteKeysTTL || (!ok && age >= remoteRefetchInterval) {
+		keys, err := s.fetch(ctx)
+		if err != nil {
+			// keep using the cached keys while the endpoint is unavailable
+			if ok {
+				return k, nil
+			}
+			return nil, err
+		}
+		s.keys, s.fetchedAt = keys, s.now()
+		k, ok = keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+	}
+
+	return k, nil
+}
+
+func (s *RemoteKeySet) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", res.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	return parseJWKS(b)
+}
@@ -0,0 +1,38 @@
+// Package kind classifies what a video is, independently of its status.
+package kind
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknown is returned when a value is not one of the defined kinds.
+var ErrUnknown = errors.New("unknown kind")
+
+// Kind is stored as is in the kind column.
+type Kind string
+
+const (
+	// Unknown is a video written before the kind was classified
+	Unknown     Kind = ""
+	Premiere    Kind = "premiere"
+	Live        Kind = "live"
+	Upload      Kind = "upload"
+	Short       Kind = "short"
+	MembersOnly Kind = "members_only"
+	// FreeChat is a waiting room that is kept open as a chat, scheduled far ahead and never broadcast
+	FreeChat Kind = "free_chat"
+)
+
+var kinds = []Kind{Premiere, Live, Upload, Short, MembersOnly, FreeChat}
+
+// Parse returns the kind named s. Unknown is never returned without an error.
+func Parse(s string) (Kind, error) {
+	for _, k := range kinds {
+		if string(k) == s {
+			return k, nil
+		}
+	}
+
+	return Unknown, fmt.Errorf("%w: %q", ErrUnknown, s)
+}
@@ -0,0 +1,39 @@
+package kind
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in      string
+		want    Kind
+		wantErr bool
+	}{
+		"premiere":     {in: "premiere", want: Premiere},
+		"live":         {in: "live", want: Live},
+		"upload":       {in: "upload", want: Upload},
+		"short":        {in: "short", want: Short},
+		"members only": {in: "members_only", want: MembersOnly},
+		"free chat":    {in: "free_chat", want: FreeChat},
+		"empty":        {in: "", wantErr: true},
+		"unknown":      {in: "podcast", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Parse(tt.in)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnknown)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+)
+
+// ErrHeld is returned by TryLock when another run already holds the lock.
+var ErrHeld = errors.New("lock is already held")
+
+// Locker guards an operation so that only one run of it executes at a time.
+type Locker interface {
+	// TryLock acquires the lock for key without waiting.
+	// It returns ErrHeld if the lock is held by another run.
+	TryLock(ctx context.Context, key string) (Lock, error)
+}
+
+type Lock interface {
+	Unlock(ctx context.Context) error
+}
+
+// Key converts a lock name into a Postgres advisory lock key.
+func Key(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,43 @@
+package lock
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryLocker is a process-local Locker for tests and local runs.
+type MemoryLocker struct {
+	mu   sync.Mutex
+	held map[string]struct{}
+}
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{held: make(map[string]struct{})}
+}
+
+type memoryLock struct {
+	locker *MemoryLocker
+	key    string
+	once   sync.Once
+}
+
+func (l *MemoryLocker) TryLock(_ context.Context, key string) (Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.held[key]; ok {
+		return nil, ErrHeld
+	}
+	l.held[key] = struct{}{}
+
+	return &memoryLock{locker: l, key: key}, nil
+}
+
+func (l *memoryLock) Unlock(_ context.Context) error {
+	l.once.Do(func() {
+		l.locker.mu.Lock()
+		defer l.locker.mu.Unlock()
+		delete(l.locker.held, l.key)
+	})
+	return nil
+}
@@ -0,0 +1,67 @@
+package lock
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMemoryLocker_TryLock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := NewMemoryLocker()
+
+	first, err := l.TryLock(ctx, "sync")
+	assert.NoError(t, err)
+
+	_, err = l.TryLock(ctx, "sync")
+	assert.ErrorIs(t, err, ErrHeld)
+
+	other, err := l.TryLock(ctx, "collect")
+	assert.NoError(t, err)
+	assert.NoError(t, other.Unlock(ctx))
+
+	assert.NoError(t, first.Unlock(ctx))
+	// unlocking twice must not release a lock acquired by another run
+	again, err := l.TryLock(ctx, "sync")
+	assert.NoError(t, err)
+	assert.NoError(t, first.Unlock(ctx))
+	_, err = l.TryLock(ctx, "sync")
+	assert.ErrorIs(t, err, ErrHeld)
+	assert.NoError(t, again.Unlock(ctx))
+}
+
+func TestMemoryLocker_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := NewMemoryLocker()
+
+	var acquired atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := l.TryLock(ctx, "sync"); err == nil {
+				acquired.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), acquired.Load())
+}
+
+func TestKey(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, Key("opus:sync"), Key("opus:sync"))
+	assert.NotEqual(t, Key("opus:sync"), Key("animus:collect-chats"))
+}
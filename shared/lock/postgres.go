@@ -0,0 +1,63 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// PostgresLocker uses session-level advisory locks.
+// Each lock holds a dedicated connection until it is released,
+// because the advisory lock belongs to the session that acquired it.
+type PostgresLocker struct {
+	db *sql.DB
+}
+
+func NewPostgresLocker(db *sql.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+type postgresLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+func (l *PostgresLocker) TryLock(ctx context.Context, key string) (Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	k := Key(key)
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", k).Scan(&ok); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	if !ok {
+		_ = conn.Close()
+		return nil, ErrHeld
+	}
+
+	return &postgresLock{conn: conn, key: k}, nil
+}
+
+func (l *postgresLock) Unlock(ctx context.Context) error {
+	var ok bool
+	if err := l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.key).Scan(&ok); err != nil {
+		// closing the connection would return the session to the pool still holding the lock,
+		// so the connection is discarded, which ends the session and releases the lock
+		_ = l.conn.Raw(func(any) error { return driver.ErrBadConn })
+		_ = l.conn.Close()
+		return fmt.Errorf("failed to release advisory lock: %w", err)
+	}
+	if err := l.conn.Close(); err != nil {
+		return fmt.Errorf("failed to return connection: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("advisory lock %d was not held", l.key)
+	}
+
+	return nil
+}
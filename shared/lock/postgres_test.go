@@ -0,0 +1,99 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"testing"
+	"time"
+)
+
+func openPostgres(t *testing.T) string {
+	t.Helper()
+
+	ctx := context.Background()
+	container, err := postgres.Run(ctx,
+		"docker.io/postgres:16-alpine",
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("password"),
+		postgres.WithDatabase("test"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").WithOccurrence(2).WithStartupTimeout(2*time.Minute),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dsn
+}
+
+func newPostgresLocker(t *testing.T, dsn string) *PostgresLocker {
+	t.Helper()
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a single connection makes the pool reuse the session of a released lock
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return NewPostgresLocker(db)
+}
+
+func TestPostgresLocker(t *testing.T) {
+	dsn := openPostgres(t)
+	ctx := context.Background()
+	l := newPostgresLocker(t, dsn)
+	other := newPostgresLocker(t, dsn)
+
+	t.Run("held by another session", func(t *testing.T) {
+		first, err := l.TryLock(ctx, "sync")
+		assert.NoError(t, err)
+
+		_, err = other.TryLock(ctx, "sync")
+		assert.ErrorIs(t, err, ErrHeld)
+
+		collect, err := other.TryLock(ctx, "collect")
+		assert.NoError(t, err)
+		assert.NoError(t, collect.Unlock(ctx))
+
+		assert.NoError(t, first.Unlock(ctx))
+		again, err := other.TryLock(ctx, "sync")
+		assert.NoError(t, err)
+		assert.NoError(t, again.Unlock(ctx))
+	})
+
+	t.Run("released when the unlock fails", func(t *testing.T) {
+		first, err := l.TryLock(ctx, "sync")
+		assert.NoError(t, err)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.Error(t, first.Unlock(canceled))
+
+		// the session is ended rather than returned to the pool still holding the lock
+		assert.Eventually(t, func() bool {
+			again, err := other.TryLock(ctx, "sync")
+			if err != nil {
+				return false
+			}
+			return again.Unlock(ctx) == nil
+		}, 10*time.Second, 100*time.Millisecond)
+
+		// and the pool opens a new connection
+		next, err := l.TryLock(ctx, "collect")
+		assert.NoError(t, err)
+		assert.NoError(t, next.Unlock(ctx))
+	})
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"context"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/telemetry"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type CustomHandler struct {
+	slog.Handler
+	projectID string
+	// hasTrace is true when the trace fields are already attached by WithAttrs
+	hasTrace bool
+}
+
+// Handle adds the Cloud Logging trace fields of the active span
+// unless the logger already carries the trace of the request.
+func (h *CustomHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.hasTrace {
+		r.AddAttrs(telemetry.TraceAttrs(ctx, h.projectID)...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *CustomHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hasTrace := h.hasTrace
+	for _, a := range attrs {
+		if a.Key == telemetry.TraceKey {
+			hasTrace = true
+		}
+	}
+	return &CustomHandler{Handler: h.Handler.WithAttrs(attrs), projectID: h.projectID, hasTrace: hasTrace}
+}
+
+func (h *CustomHandler) WithGroup(name string) slog.Handler {
+	return &CustomHandler{Handler: h.Handler.WithGroup(name), projectID: h.projectID, hasTrace: h.hasTrace}
+}
+
+// NewCustomLogger creates a logger writing the structured JSON of Cloud Logging to stdout.
+// SERVICE_NAME is required unless LOCAL_ONLY is true, in which case localServiceName is used.
+// The minimum level is read from LOG_LEVEL (debug, info, warn or error) and defaults to info.
+func NewCustomLogger(localServiceName string) *slog.Logger {
+	svcName := os.Getenv("SERVICE_NAME")
+	if svcName == "" {
+		if os.Getenv("LOCAL_ONLY") != "true" {
+			slog.Error(
+				"SERVICE_NAME is not set",
+				slog.String("error", "SERVICE_NAME must be set"),
+			)
+			panic("SERVICE_NAME must be set")
+		} else {
+			svcName = localServiceName
+		}
+	}
+
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	logger := newLogger(os.Stdout, level, os.Getenv("GOOGLE_CLOUD_PROJECT"), svcName)
+	if err != nil {
+		logger.Warn("LOG_LEVEL is invalid, falling back to info", "error", err)
+	}
+
+	return logger
+}
+
+// ParseLevel parses a case-insensitive level name. An empty string means info.
+func ParseLevel(s string) (slog.Level, error) {
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return slog.LevelInfo, err
+	}
+
+	return l, nil
+}
+
+func newLogger(w io.Writer, level slog.Level, projectID, svcName string) *slog.Logger {
+	handler := CustomHandler{
+		Handler: slog.NewJSONHandler(
+			w,
+			&slog.HandlerOptions{
+				AddSource: true,
+				Level:     level,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					switch a.Key {
+					case slog.MessageKey:
+						a = slog.Attr{
+							Key:   "message",
+							Value: a.Value,
+						}
+					case slog.LevelKey:
+						a = slog.Attr{
+							Key:   "severity",
+							Value: a.Value,
+						}
+					case slog.SourceKey:
+						a = slog.Attr{
+							Key:   "logging.googleapis.com/sourceLocation",
+							Value: a.Value,
+						}
+					}
+					return a
+				},
+			}),
+		projectID: projectID,
+	}
+
+	logger := slog.New(&handler).With(
+		slog.Group("logging.googleapis.com/labels",
+			slog.String("service", svcName),
+		))
+
+	return logger
+}
@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/telemetry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		"empty":       {in: "", want: slog.LevelInfo},
+		"debug":       {in: "debug", want: slog.LevelDebug},
+		"upper case":  {in: "WARN", want: slog.LevelWarn},
+		"error":       {in: "error", want: slog.LevelError},
+		"with offset": {in: "info+2", want: slog.LevelInfo + 2},
+		"invalid":     {in: "verbose", want: slog.LevelInfo, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseLevel(tt.in)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestRequestTraceAttrs(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		headers map[string]string
+		want    []slog.Attr
+	}{
+		"no header": {
+			headers: map[string]string{},
+			want:    nil,
+		},
+		"traceparent": {
+			headers: map[string]string{
+				"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			},
+			want: []slog.Attr{
+				slog.String(telemetry.TraceKey, "projects/project/traces/0af7651916cd43dd8448eb211c80319c"),
+				slog.String(telemetry.SpanIDKey, "b7ad6b7169203331"),
+				slog.Bool(telemetry.TraceSampledKey, true),
+			},
+		},
+		"x cloud trace context": {
+			headers: map[string]string{
+				"X-Cloud-Trace-Context": "105445aa7843bc8bf206b12000100000/255;o=0",
+			},
+			want: []slog.Attr{
+				slog.String(telemetry.TraceKey, "projects/project/traces/105445aa7843bc8bf206b12000100000"),
+				slog.String(telemetry.SpanIDKey, "00000000000000ff"),
+				slog.Bool(telemetry.TraceSampledKey, false),
+			},
+		},
+		"x cloud trace context without span": {
+			headers: map[string]string{
+				"X-Cloud-Trace-Context": "105445aa7843bc8bf206b12000100000",
+			},
+			want: []slog.Attr{
+				slog.String(telemetry.TraceKey, "projects/project/traces/105445aa7843bc8bf206b12000100000"),
+				slog.Bool(telemetry.TraceSampledKey, false),
+			},
+		},
+		"traceparent takes precedence": {
+			headers: map[string]string{
+				"traceparent":           "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00",
+				"X-Cloud-Trace-Context": "105445aa7843bc8bf206b12000100000/1;o=1",
+			},
+			want: []slog.Attr{
+				slog.String(telemetry.TraceKey, "projects/project/traces/0af7651916cd43dd8448eb211c80319c"),
+				slog.String(telemetry.SpanIDKey, "b7ad6b7169203331"),
+				slog.Bool(telemetry.TraceSampledKey, false),
+			},
+		},
+		"malformed": {
+			headers: map[string]string{
+				"traceparent":           "garbage",
+				"X-Cloud-Trace-Context": "not-a-trace",
+			},
+			want: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, RequestTraceAttrs(r, "project"))
+		})
+	}
+}
+
+func TestCustomHandler_TraceFields(t *testing.T) {
+	t.Parallel()
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	tests := map[string]struct {
+		log       func(l *slog.Logger)
+		wantTrace string
+		wantRunID string
+	}{
+		"span in context": {
+			log: func(l *slog.Logger) {
+				l.InfoContext(ctx, "message")
+			},
+			wantTrace: "projects/project/traces/0102030405060708090a0b0c0d0e0f10",
+		},
+		"request-scoped trace is not overwritten": {
+			log: func(l *slog.Logger) {
+				l.With(slog.String(telemetry.TraceKey, "request-trace"), slog.String(RunIDKey, "run")).InfoContext(ctx, "message")
+			},
+			wantTrace: "request-trace",
+			wantRunID: "run",
+		},
+		"no trace": {
+			log: func(l *slog.Logger) {
+				l.Info("message")
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			buf := &bytes.Buffer{}
+			tt.log(newLogger(buf, slog.LevelInfo, "project", "service"))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "message", got["message"])
+			assert.Equal(t, "INFO", got["severity"])
+			if tt.wantTrace == "" {
+				assert.NotContains(t, got, telemetry.TraceKey)
+			} else {
+				assert.Equal(t, tt.wantTrace, got[telemetry.TraceKey])
+			}
+			if tt.wantRunID != "" {
+				assert.Equal(t, tt.wantRunID, got[RunIDKey])
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var got *slog.Logger
+	h := Middleware(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Function-Execution-Id", "execution")
+	h(httptest.NewRecorder(), r)
+
+	assert.NotNil(t, got)
+	assert.NotSame(t, slog.Default(), got)
+}
+
+func TestFromContext_Default(t *testing.T) {
+	t.Parallel()
+	assert.Same(t, slog.Default(), FromContext(context.Background()))
+}
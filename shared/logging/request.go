@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/telemetry"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+const RunIDKey = "runId"
+
+type loggerKey struct{}
+
+var (
+	// TRACE_ID/SPAN_ID;o=OPTIONS, where SPAN_ID is decimal
+	xCloudTraceContext = regexp.MustCompile(`^([a-fA-F0-9]{32})(?:/([0-9]+))?(?:;o=([01]))?$`)
+	// VERSION-TRACE_ID-PARENT_ID-FLAGS
+	traceparent = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+)
+
+// WithLogger returns a copy of ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the request-scoped logger in ctx, or the default logger if there is none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// Middleware attaches a request-scoped logger to the request context.
+// The logger carries the Cloud Logging trace fields and a run ID,
+// so that every line of one invocation can be grouped together.
+func Middleware(next http.HandlerFunc) http.HandlerFunc {
+	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		runID := r.Header.Get("Function-Execution-Id")
+		if runID == "" {
+			runID = NewRunID()
+		}
+
+		attrs := RequestTraceAttrs(r, projectID)
+		args := make([]any, 0, len(attrs)+1)
+		for _, a := range attrs {
+			args = append(args, a)
+		}
+		args = append(args, slog.String(RunIDKey, runID))
+
+		l := slog.Default().With(args...)
+		next(w, r.WithContext(WithLogger(r.Context(), l)))
+	}
+}
+
+// RequestTraceAttrs returns the Cloud Logging trace fields of the request.
+// The active span takes precedence over the X-Cloud-Trace-Context and traceparent headers.
+func RequestTraceAttrs(r *http.Request, projectID string) []slog.Attr {
+	if attrs := telemetry.TraceAttrs(r.Context(), projectID); attrs != nil {
+		return attrs
+	}
+
+	traceID, spanID, sampled, ok := parseTraceparent(r.Header.Get("traceparent"))
+	if !ok {
+		traceID, spanID, sampled, ok = parseXCloudTraceContext(r.Header.Get("X-Cloud-Trace-Context"))
+	}
+	if !ok {
+		return nil
+	}
+
+	if projectID != "" {
+		traceID = fmt.Sprintf("projects/%s/traces/%s", projectID, traceID)
+	}
+
+	attrs := []slog.Attr{slog.String(telemetry.TraceKey, traceID)}
+	if spanID != "" {
+		attrs = append(attrs, slog.String(telemetry.SpanIDKey, spanID))
+	}
+	attrs = append(attrs, slog.Bool(telemetry.TraceSampledKey, sampled))
+
+	return attrs
+}
+
+func parseTraceparent(h string) (string, string, bool, bool) {
+	m := traceparent.FindStringSubmatch(h)
+	if m == nil {
+		return "", "", false, false
+	}
+
+	flags, err := strconv.ParseUint(m[3], 16, 8)
+	if err != nil {
+		return "", "", false, false
+	}
+
+	return m[1], m[2], flags&uint64(trace.FlagsSampled) != 0, true
+}
+
+// parseXCloudTraceContext converts the decimal span ID into the 16 hex digits Cloud Logging expects.
+func parseXCloudTraceContext(h string) (string, string, bool, bool) {
+	m := xCloudTraceContext.FindStringSubmatch(h)
+	if m == nil {
+		return "", "", false, false
+	}
+
+	spanID := ""
+	if m[2] != "" {
+		id, err := strconv.ParseUint(m[2], 10, 64)
+		if err != nil {
+			return "", "", false, false
+		}
+		spanID = fmt.Sprintf("%016x", id)
+	}
+
+	return m[1], spanID, m[3] == "1", true
+}
+
+// NewRunID returns a random identifier of one invocation.
+func NewRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
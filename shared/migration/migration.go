@@ -0,0 +1,173 @@
+// Package migration applies the migrations embedded in the schema package.
+// It is used both by the migrate command and by the functions that migrate on a cold start.
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/schema"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+	"log/slog"
+	"strings"
+)
+
+var (
+	// ErrDirty is returned when a previous migration failed halfway and the version has to be forced
+	ErrDirty = errors.New("database is dirty")
+	// ErrInvalidSteps is returned when down is asked for a non-positive number of migrations
+	ErrInvalidSteps = errors.New("steps must be positive")
+)
+
+type Runner struct {
+	m        *migrate.Migrate
+	versions []uint
+}
+
+// Status is the state of the database against the embedded migrations.
+// Version is 0 when no migration has been applied yet.
+type Status struct {
+	Version uint   `json:"version"`
+	Dirty   bool   `json:"dirty"`
+	Latest  uint   `json:"latest"`
+	Pending []uint `json:"pending"`
+}
+
+// NewPostgresRunner runs the schema migrations against db.
+// The runner holds a dedicated connection of db and Close releases only that connection, so db can be shared.
+func NewPostgresRunner(ctx context.Context, db *sql.DB) (*Runner, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection: %w", err)
+	}
+
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to create the postgres driver: %w", err)
+	}
+
+	return newRunner(schema.Migrations, "migrations", "postgres", driver)
+}
+
+func newRunner(fsys fs.FS, path string, dbName string, driver database.Driver) (*Runner, error) {
+	src, err := iofs.New(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	versions, err := listVersions(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, dbName, driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the migrator: %w", err)
+	}
+	m.Log = logger{}
+
+	return &Runner{m: m, versions: versions}, nil
+}
+
+// Up applies every pending migration. A database that is already up to date is not an error.
+func (r *Runner) Up() error {
+	if err := r.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return wrap("up", err)
+	}
+
+	return nil
+}
+
+// Down rolls back the last n applied migrations.
+func (r *Runner) Down(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("down %d: %w", n, ErrInvalidSteps)
+	}
+	if err := r.m.Steps(-n); err != nil {
+		return wrap(fmt.Sprintf("down %d", n), err)
+	}
+
+	return nil
+}
+
+// Force sets the version without running any migration, and clears the dirty flag.
+// It is the way out after a migration failed halfway and was fixed by hand.
+func (r *Runner) Force(version int) error {
+	if err := r.m.Force(version); err != nil {
+		return wrap(fmt.Sprintf("force %d", version), err)
+	}
+
+	return nil
+}
+
+func (r *Runner) Status() (Status, error) {
+	s := Status{Pending: make([]uint, 0)}
+	if len(r.versions) > 0 {
+		s.Latest = r.versions[len(r.versions)-1]
+	}
+
+	v, dirty, err := r.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return Status{}, wrap("status", err)
+	}
+	s.Version, s.Dirty = v, dirty
+
+	for _, pv := range r.versions {
+		if pv > s.Version {
+			s.Pending = append(s.Pending, pv)
+		}
+	}
+
+	return s, nil
+}
+
+func (r *Runner) Close() error {
+	srcErr, dbErr := r.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+func listVersions(fsys fs.FS, path string) ([]uint, error) {
+	src, err := iofs.New(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer src.Close()
+
+	versions := make([]uint, 0)
+	v, err := src.First()
+	for err == nil {
+		versions = append(versions, v)
+		v, err = src.Next(v)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	return versions, nil
+}
+
+func wrap(op string, err error) error {
+	var dirty migrate.ErrDirty
+	if errors.As(err, &dirty) {
+		return fmt.Errorf("%s: %w at version %d, fix it and force a version", op, ErrDirty, dirty.Version)
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// logger forwards the progress of golang-migrate to slog
+type logger struct{}
+
+func (logger) Printf(format string, v ...interface{}) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, v...)), slog.String("component", "migration"))
+}
+
+func (logger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,102 @@
+package migration
+
+import (
+	"errors"
+	"github.com/golang-migrate/migrate/v4/database/stub"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+)
+
+func newStubRunner(t *testing.T) (*Runner, *stub.Stub) {
+	t.Helper()
+
+	fsys := fstest.MapFS{
+		"migrations/000001_create.up.sql":   {Data: []byte("create")},
+		"migrations/000001_create.down.sql": {Data: []byte("drop")},
+		"migrations/000002_alter.up.sql":    {Data: []byte("alter")},
+		"migrations/000002_alter.down.sql":  {Data: []byte("unalter")},
+		"migrations/000003_index.up.sql":    {Data: []byte("index")},
+		"migrations/000003_index.down.sql":  {Data: []byte("unindex")},
+	}
+	driver, err := stub.WithInstance(nil, &stub.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := newRunner(fsys, "migrations", "stub", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+
+	return r, driver.(*stub.Stub)
+}
+
+func TestRunner_Status(t *testing.T) {
+	t.Parallel()
+
+	r, _ := newStubRunner(t)
+
+	got, err := r.Status()
+	assert.NoError(t, err)
+	if diff := cmp.Diff(Status{Version: 0, Latest: 3, Pending: []uint{1, 2, 3}}, got); diff != "" {
+		t.Errorf("Status() before up mismatch (-want +got):\n%s", diff)
+	}
+
+	assert.NoError(t, r.Up())
+	got, err = r.Status()
+	assert.NoError(t, err)
+	if diff := cmp.Diff(Status{Version: 3, Latest: 3, Pending: []uint{}}, got); diff != "" {
+		t.Errorf("Status() after up mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunner_UpAndDown(t *testing.T) {
+	t.Parallel()
+
+	r, db := newStubRunner(t)
+
+	assert.NoError(t, r.Up())
+	// running up on an up-to-date database is not an error
+	assert.NoError(t, r.Up())
+	assert.NoError(t, r.Down(2))
+
+	assert.Equal(t, []string{"create", "alter", "index", "unindex", "unalter"}, db.MigrationSequence)
+	assert.Equal(t, 1, db.CurrentVersion)
+}
+
+func TestRunner_DownInvalid(t *testing.T) {
+	t.Parallel()
+
+	r, _ := newStubRunner(t)
+	assert.NoError(t, r.Up())
+
+	assert.ErrorIs(t, r.Down(0), ErrInvalidSteps)
+	// rolling back more migrations than were applied fails instead of silently stopping at zero
+	assert.Error(t, r.Down(4))
+}
+
+func TestRunner_Force(t *testing.T) {
+	t.Parallel()
+
+	r, db := newStubRunner(t)
+	assert.NoError(t, r.Up())
+
+	// a migration that failed halfway leaves the database dirty
+	db.CurrentVersion, db.IsDirty = 2, true
+
+	err := r.Up()
+	if !errors.Is(err, ErrDirty) {
+		t.Fatalf("want ErrDirty, got: %v", err)
+	}
+	s, err := r.Status()
+	assert.NoError(t, err)
+	assert.True(t, s.Dirty)
+
+	assert.NoError(t, r.Force(2))
+	assert.False(t, db.IsDirty)
+	assert.NoError(t, r.Up())
+	assert.Equal(t, 3, db.CurrentVersion)
+}
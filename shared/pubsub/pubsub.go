@@ -0,0 +1,112 @@
+// Package pubsub runs a function from the CloudEvent of a Pub/Sub trigger.
+// Pub/Sub redelivers a message as long as the function returns an error, so a failure that no retry can fix
+// is wrapped with Permanent to acknowledge the message instead of retrying it until it is dead-lettered.
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/logging"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"log/slog"
+	"time"
+)
+
+// Message is the Pub/Sub message carried by the event.
+type Message struct {
+	ID          string            `json:"messageId"`
+	Data        []byte            `json:"data"`
+	Attributes  map[string]string `json:"attributes"`
+	PublishTime time.Time         `json:"publishTime"`
+}
+
+// messagePublishedData is the data of a google.cloud.pubsub.topic.v1.messagePublished event
+type messagePublishedData struct {
+	Message      Message `json:"message"`
+	Subscription string  `json:"subscription"`
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as a failure that a retry of the same message cannot fix.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Decode extracts the Pub/Sub message from e. An event that cannot be decoded is permanent.
+func Decode(e event.Event) (Message, error) {
+	var d messagePublishedData
+	if err := e.DataAs(&d); err != nil {
+		return Message{}, Permanent(fmt.Errorf("malformed Pub/Sub event: %w", err))
+	}
+
+	return d.Message, nil
+}
+
+// Unmarshal decodes the data of the message as JSON into v, rejecting unknown fields.
+// A message without data leaves v untouched. A malformed payload is permanent.
+func (m Message) Unmarshal(v any) error {
+	if len(bytes.TrimSpace(m.Data)) == 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(m.Data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return Permanent(fmt.Errorf("malformed message payload: %w", err))
+	}
+
+	return nil
+}
+
+// Handler adapts fn to functions.CloudEvent. The context passed to fn carries a logger labelled with the event ID.
+// A permanent error is logged and acknowledged, and any other error is returned so that Pub/Sub retries the message.
+func Handler(fn func(ctx context.Context, m Message) error) func(context.Context, event.Event) error {
+	return func(ctx context.Context, e event.Event) error {
+		l := slog.Default().With(slog.String(logging.RunIDKey, e.ID()))
+		ctx = logging.WithLogger(ctx, l)
+
+		m, err := Decode(e)
+		if err == nil {
+			err = fn(ctx, m)
+		}
+
+		switch {
+		case err == nil:
+			return nil
+		case IsPermanent(err):
+			l.Error(
+				"Dropped message after a permanent failure",
+				slog.Group("PubSub", "error", err, "messageId", m.ID),
+			)
+			return nil
+		default:
+			l.Warn(
+				"Message failed and will be retried",
+				slog.Group("PubSub", "error", err, "messageId", m.ID),
+			)
+			return err
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newEvent(t *testing.T, data any) event.Event {
+	t.Helper()
+
+	e := event.New()
+	e.SetID("event-1")
+	e.SetSource("//pubsub.googleapis.com/projects/p/topics/t")
+	e.SetType("google.cloud.pubsub.topic.v1.messagePublished")
+	if err := e.SetData(event.ApplicationJSON, data); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	type payload struct {
+		Operation string `json:"operation"`
+	}
+	published := map[string]any{
+		"message": map[string]any{
+			"messageId": "message-1",
+			// base64 of {"operation":"rss_sync"}
+			"data": "eyJvcGVyYXRpb24iOiJyc3Nfc3luYyJ9",
+		},
+		"subscription": "projects/p/subscriptions/s",
+	}
+	retryable := errors.New("connection reset")
+
+	tests := map[string]struct {
+		data    any
+		fnErr   error
+		wantErr error
+		wantOp  string
+	}{
+		"succeeded": {
+			data:   published,
+			wantOp: "rss_sync",
+		},
+		"retryable failure is returned": {
+			data:    published,
+			fnErr:   retryable,
+			wantErr: retryable,
+			wantOp:  "rss_sync",
+		},
+		"permanent failure is acknowledged": {
+			data:   published,
+			fnErr:  Permanent(errors.New("unknown operation")),
+			wantOp: "rss_sync",
+		},
+		"malformed event is acknowledged": {
+			data: "not a message",
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got payload
+			h := Handler(func(_ context.Context, m Message) error {
+				if err := m.Unmarshal(&got); err != nil {
+					return err
+				}
+				return tt.fnErr
+			})
+
+			err := h(context.Background(), newEvent(t, tt.data))
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantOp, got.Operation)
+		})
+	}
+}
+
+func TestMessage_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	type payload struct {
+		Limit int `json:"limit"`
+	}
+
+	tests := map[string]struct {
+		data          string
+		want          payload
+		wantPermanent bool
+	}{
+		"empty":         {data: "", want: payload{}},
+		"payload":       {data: `{"limit": 3}`, want: payload{Limit: 3}},
+		"unknown field": {data: `{"force": true}`, wantPermanent: true},
+		"not json":      {data: "rss_sync", wantPermanent: true},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got payload
+			err := Message{Data: []byte(tt.data)}.Unmarshal(&got)
+			assert.Equal(t, tt.wantPermanent, IsPermanent(err))
+			if !tt.wantPermanent {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, Permanent(nil))
+	base := errors.New("invalid")
+	err := fmt.Errorf("run: %w", Permanent(base))
+	assert.True(t, IsPermanent(err))
+	assert.ErrorIs(t, err, base)
+	assert.False(t, IsPermanent(base))
+}
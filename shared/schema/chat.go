@@ -0,0 +1,24 @@
+package schema
+
+import (
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// Chat is a row of the chats table written by Animus.
+type Chat struct {
+	bun.BaseModel `bun:"table:chats"`
+
+	SourceID    string    `bun:",type:varchar(255)"`
+	Message     string    `bun:",type:text"`
+	IsNegative  bool      `bun:",type:boolean"`
+	PublishedAt time.Time `bun:",type:timestamptz"`
+}
+
+// FetchChatHistory records when Animus last fetched the chats of a video.
+type FetchChatHistory struct {
+	bun.BaseModel `bun:"table:fetch_chat_history"`
+
+	SourceID  string    `bun:",type:varchar(255)"`
+	CreatedAt time.Time `bun:",type:timestamptz"`
+}
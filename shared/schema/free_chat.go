@@ -0,0 +1,17 @@
+package schema
+
+import (
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// FreeChat is a row of the free_chats table, the free-chat room currently open on each channel.
+// Opus writes it when it classifies a video as a free chat, and Animus reads it as its static targets.
+type FreeChat struct {
+	bun.BaseModel `bun:"table:free_chats"`
+
+	ChannelID string    `bun:",pk,type:varchar(255)"`
+	SourceID  string    `bun:",type:varchar(255)"`
+	ChatID    string    `bun:",type:varchar(255)"`
+	UpdatedAt time.Time `bun:",type:timestamptz"`
+}
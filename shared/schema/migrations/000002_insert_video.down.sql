@@ -0,0 +1,2 @@
+-- Nothing to roll back, see the up migration.
+SELECT 1;
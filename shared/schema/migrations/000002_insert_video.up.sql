@@ -0,0 +1,3 @@
+-- The seed data that used to be inserted here is test data, and lives in opus/test/migrate/testdata now.
+-- The version is kept so that databases which applied it stay in sequence.
+SELECT 1;
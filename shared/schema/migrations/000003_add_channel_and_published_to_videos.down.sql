@@ -0,0 +1,3 @@
+ALTER TABLE videos
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS channel_id;
@@ -0,0 +1,3 @@
+ALTER TABLE videos
+    ADD COLUMN channel_id VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN published_at TIMESTAMP;
@@ -0,0 +1,4 @@
+CREATE TABLE channel_sync_states (
+    channel_id VARCHAR(255) NOT NULL PRIMARY KEY,
+    last_synced_at TIMESTAMP NOT NULL
+);
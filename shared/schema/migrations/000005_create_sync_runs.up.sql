@@ -0,0 +1,14 @@
+CREATE TABLE sync_runs (
+    id BIGSERIAL PRIMARY KEY,
+    operation VARCHAR(64) NOT NULL,
+    trigger VARCHAR(64) NOT NULL,
+    outcome VARCHAR(32) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP,
+    channel_counts JSONB NOT NULL DEFAULT '{}',
+    api_calls INTEGER NOT NULL DEFAULT 0,
+    quota_units INTEGER NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX sync_runs_started_at_idx ON sync_runs (started_at DESC);
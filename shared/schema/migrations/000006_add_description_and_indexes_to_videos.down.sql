@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS videos_scheduled_at_idx;
+DROP INDEX IF EXISTS videos_status_idx;
+DROP INDEX IF EXISTS videos_channel_id_idx;
+
+ALTER TABLE videos
+    DROP COLUMN IF EXISTS description;
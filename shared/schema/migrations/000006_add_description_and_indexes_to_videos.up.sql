@@ -0,0 +1,6 @@
+ALTER TABLE videos
+    ADD COLUMN description TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX videos_channel_id_idx ON videos (channel_id);
+CREATE INDEX videos_status_idx ON videos (status);
+CREATE INDEX videos_scheduled_at_idx ON videos (scheduled_at);
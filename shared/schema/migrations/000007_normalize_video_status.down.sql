@@ -0,0 +1 @@
+UPDATE videos SET status = initcap(status) WHERE status <> initcap(status);
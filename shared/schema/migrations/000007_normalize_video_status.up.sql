@@ -0,0 +1 @@
+UPDATE videos SET status = lower(status) WHERE status <> lower(status);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS fetch_chat_history;
+DROP TABLE IF EXISTS chats;
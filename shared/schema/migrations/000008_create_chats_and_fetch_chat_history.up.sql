@@ -0,0 +1,18 @@
+-- These tables were created by hand for Animus, so keep the existing ones as they are
+CREATE TABLE IF NOT EXISTS chats (
+    id BIGSERIAL PRIMARY KEY,
+    source_id VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    is_negative BOOLEAN NOT NULL DEFAULT FALSE,
+    published_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS chats_source_id_published_at_idx ON chats (source_id, published_at DESC);
+
+CREATE TABLE IF NOT EXISTS fetch_chat_history (
+    id BIGSERIAL PRIMARY KEY,
+    source_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS fetch_chat_history_source_id_created_at_idx ON fetch_chat_history (source_id, created_at DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE videos
+    DROP COLUMN IF EXISTS actual_end_at,
+    DROP COLUMN IF EXISTS actual_start_at,
+    DROP COLUMN IF EXISTS chat_status;
@@ -0,0 +1,4 @@
+ALTER TABLE videos
+    ADD COLUMN chat_status VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN actual_start_at TIMESTAMPTZ,
+    ADD COLUMN actual_end_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS videos_kind_idx;
+
+ALTER TABLE videos
+    DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,4 @@
+ALTER TABLE videos
+    ADD COLUMN kind VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX videos_kind_idx ON videos (kind);
@@ -0,0 +1,6 @@
+CREATE TABLE free_chats (
+    channel_id VARCHAR(255) NOT NULL PRIMARY KEY,
+    source_id VARCHAR(255) NOT NULL,
+    chat_id VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL
+);
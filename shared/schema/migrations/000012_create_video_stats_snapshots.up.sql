@@ -0,0 +1,11 @@
+CREATE TABLE video_stats_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    source_id VARCHAR(255) NOT NULL,
+    view_count BIGINT NOT NULL,
+    like_count BIGINT NOT NULL,
+    comment_count BIGINT NOT NULL,
+    concurrent_viewers BIGINT,
+    taken_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX video_stats_snapshots_source_id_taken_at_idx ON video_stats_snapshots (source_id, taken_at);
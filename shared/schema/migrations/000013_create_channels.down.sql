@@ -0,0 +1,10 @@
+ALTER TABLE videos
+    DROP CONSTRAINT IF EXISTS videos_channel_id_fkey;
+
+UPDATE videos SET channel_id = '' WHERE channel_id IS NULL;
+
+ALTER TABLE videos
+    ALTER COLUMN channel_id SET DEFAULT '',
+    ALTER COLUMN channel_id SET NOT NULL;
+
+DROP TABLE IF EXISTS channels;
@@ -0,0 +1,23 @@
+CREATE TABLE channels (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    handle VARCHAR(255) NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    subscriber_count BIGINT,
+    uploads_playlist_id VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ
+);
+
+-- the channels already referenced get placeholder rows, filled by the next channel sync
+INSERT INTO channels (id)
+SELECT DISTINCT channel_id FROM videos WHERE channel_id <> '';
+
+-- videos stored before channel_id existed have no channel, which the foreign key can only express as NULL
+ALTER TABLE videos
+    ALTER COLUMN channel_id DROP DEFAULT,
+    ALTER COLUMN channel_id DROP NOT NULL;
+
+UPDATE videos SET channel_id = NULL WHERE channel_id = '';
+
+ALTER TABLE videos
+    ADD CONSTRAINT videos_channel_id_fkey FOREIGN KEY (channel_id) REFERENCES channels (id);
@@ -0,0 +1,10 @@
+CREATE TABLE targets (
+    id BIGSERIAL PRIMARY KEY,
+    display VARCHAR(255) NOT NULL DEFAULT '',
+    channel_id VARCHAR(255) NOT NULL DEFAULT '',
+    handle VARCHAR(255) NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    discovery VARCHAR(255) NOT NULL DEFAULT '',
+    filters JSONB
+);
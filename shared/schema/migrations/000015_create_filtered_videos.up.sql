@@ -0,0 +1,7 @@
+CREATE TABLE filtered_videos (
+    source_id VARCHAR(255) PRIMARY KEY,
+    channel_id VARCHAR(255) NOT NULL,
+    rule TEXT NOT NULL,
+    fingerprint VARCHAR(255) NOT NULL,
+    filtered_at TIMESTAMPTZ NOT NULL
+);
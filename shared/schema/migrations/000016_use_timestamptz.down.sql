@@ -0,0 +1,13 @@
+-- only the columns the up migration converted are reverted
+DO $$
+DECLARE
+    c RECORD;
+BEGIN
+    FOR c IN SELECT table_name, column_name FROM timestamptz_conversions
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMP USING %I AT TIME ZONE ''UTC''', c.table_name, c.column_name, c.column_name);
+    END LOOP;
+END
+$$;
+
+DROP TABLE timestamptz_conversions;
@@ -0,0 +1,35 @@
+-- The tables created before TIMESTAMPTZ was used everywhere hold the UTC wall clock the application wrote,
+-- so their values are read as UTC. Columns already created as TIMESTAMPTZ, like those of the chat tables
+-- created by hand for Animus, are left as they are.
+-- The converted columns are recorded so that the down migration only reverts them.
+CREATE TABLE timestamptz_conversions (
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    PRIMARY KEY (table_name, column_name)
+);
+
+DO $$
+DECLARE
+    c RECORD;
+BEGIN
+    FOR c IN
+        SELECT table_name, column_name
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND data_type = 'timestamp without time zone'
+          AND (table_name, column_name) IN (
+              ('videos', 'scheduled_at'),
+              ('videos', 'updated_at'),
+              ('videos', 'published_at'),
+              ('channel_sync_states', 'last_synced_at'),
+              ('sync_runs', 'started_at'),
+              ('sync_runs', 'finished_at'),
+              ('chats', 'published_at'),
+              ('fetch_chat_history', 'created_at')
+          )
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE ''UTC''', c.table_name, c.column_name, c.column_name);
+        INSERT INTO timestamptz_conversions (table_name, column_name) VALUES (c.table_name, c.column_name);
+    END LOOP;
+END
+$$;
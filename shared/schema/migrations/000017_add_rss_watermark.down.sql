@@ -0,0 +1,2 @@
+ALTER TABLE channel_sync_states
+    DROP COLUMN rss_watermark;
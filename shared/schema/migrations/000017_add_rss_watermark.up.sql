@@ -0,0 +1,3 @@
+-- a channel without a watermark takes every entry of its feed once
+ALTER TABLE channel_sync_states
+    ADD COLUMN rss_watermark TIMESTAMPTZ;
@@ -0,0 +1,12 @@
+// Package schema owns the tables Opus and Animus share, and the migrations of every table in the database.
+// Tables used by only one function keep their models in that function.
+package schema
+
+import (
+	"embed"
+)
+
+// Migrations holds the golang-migrate files under migrations/.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
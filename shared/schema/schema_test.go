@@ -0,0 +1,34 @@
+package schema
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"regexp"
+	"testing"
+)
+
+var migrationName = regexp.MustCompile(`^(\d{6})_[a-z0-9_]+\.(up|down)\.sql$`)
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+
+	entries, err := fs.ReadDir(Migrations, "migrations")
+	assert.NoError(t, err)
+
+	// every version needs both directions, and versions must not be skipped
+	directions := make(map[string][]string)
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if m == nil {
+			t.Errorf("unexpected file name: %s", e.Name())
+			continue
+		}
+		directions[m[1]] = append(directions[m[1]], m[2])
+	}
+
+	for i := 1; i <= len(directions); i++ {
+		v := fmt.Sprintf("%06d", i)
+		assert.ElementsMatch(t, []string{"up", "down"}, directions[v], "version %s", v)
+	}
+}
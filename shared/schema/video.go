@@ -0,0 +1,29 @@
+package schema
+
+import (
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/kind"
+	"github.com/KasumiMercury/patotta-stone-functions-go/shared/status"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// Video is a row of the videos table. Opus writes it from the YouTube Data API,
+// and Animus reads it to find the chats to collect and marks it archived when the chat ends.
+type Video struct {
+	bun.BaseModel `bun:"table:videos"`
+
+	SourceID    string        `bun:",type:varchar(255),unique"`
+	ChannelID   string        `bun:",type:varchar(255)"`
+	Title       string        `bun:",type:varchar(255)"`
+	Description string        `bun:",type:text"`
+	Status      status.Status `bun:",type:varchar(255)"`
+	Kind        kind.Kind     `bun:",type:varchar(255)"`
+	ChatID      string        `bun:",type:varchar(255)"`
+	ChatStatus  status.Chat   `bun:",type:varchar(255)"`
+	PublishedAt *time.Time    `bun:",type:timestamptz"`
+	ScheduledAt *time.Time    `bun:",type:timestamptz"`
+	// ActualStartAt and ActualEndAt are set once the broadcast has started and ended
+	ActualStartAt *time.Time `bun:",type:timestamptz"`
+	ActualEndAt   *time.Time `bun:",type:timestamptz"`
+	UpdatedAt     time.Time  `bun:",type:timestamptz"`
+}
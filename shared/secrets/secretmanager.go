@@ -0,0 +1,146 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	secretManagerURL = "https://secretmanager.googleapis.com"
+	// metadataTokenURL issues the access tokens of the service account that the function runs as
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	// tokenExpiryMargin renews a token before it expires during a request
+	tokenExpiryMargin = time.Minute
+)
+
+// SecretManager reads the latest versions of secrets from Secret Manager over its REST API,
+// authenticated as the service account of the instance.
+type SecretManager struct {
+	project  string
+	baseURL  string
+	tokenURL string
+	client   *http.Client
+	now      func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewSecretManager(project string, client *http.Client) *SecretManager {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &SecretManager{
+		project:  project,
+		baseURL:  secretManagerURL,
+		tokenURL: metadataTokenURL,
+		client:   client,
+		now:      time.Now,
+	}
+}
+
+type accessResponse struct {
+	Payload struct {
+		Data string `json:"data"`
+		// DataCrc32c is an int64 encoded as a string, which is empty for secrets added without one
+		DataCrc32c string `json:"dataCrc32c"`
+	} `json:"payload"`
+}
+
+func (s *SecretManager) Secret(ctx context.Context, name string) (string, error) {
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u := fmt.Sprintf("%s/v1/projects/%s/secrets/%s/versions/latest:access", s.baseURL, url.PathEscape(s.project), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create secret request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to access secret %s: %w", name, err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	default:
+		return "", fmt.Errorf("failed to access secret %s: status %d", name, res.StatusCode)
+	}
+
+	var ar accessResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&ar); err != nil {
+		return "", fmt.Errorf("malformed secret %s: %w", name, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(ar.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("malformed payload of secret %s: %w", name, err)
+	}
+	if ar.Payload.DataCrc32c != "" {
+		want, err := strconv.ParseInt(ar.Payload.DataCrc32c, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("malformed checksum of secret %s: %w", name, err)
+		}
+		if int64(crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))) != want {
+			return "", fmt.Errorf("corrupted payload of secret %s", name)
+		}
+	}
+
+	return string(data), nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// accessToken returns the cached token, or fetches one from the metadata server once it is about to expire.
+func (s *SecretManager) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Add(tokenExpiryMargin).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.tokenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch access token: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch access token: status %d", res.StatusCode)
+	}
+	var tr tokenResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&tr); err != nil {
+		return "", fmt.Errorf("malformed access token: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return "", errors.New("malformed access token: empty")
+	}
+	s.token, s.expiresAt = tr.AccessToken, s.now().Add(time.Duration(tr.ExpiresIn)*time.Second)
+
+	return s.token, nil
+}
@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testToken = "test-token"
+
+// secretManagerStub serves the metadata token endpoint and the access endpoint of Secret Manager.
+type secretManagerStub struct {
+	secrets map[string]string
+	// corrupt sends a checksum that does not match the payload
+	corrupt bool
+	status  int
+	tokens  atomic.Int32
+}
+
+func (s *secretManagerStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		s.tokens.Add(1)
+		_ = json.NewEncoder(w).Encode(tokenResponse{AccessToken: testToken, ExpiresIn: 3600})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	var name string
+	for n := range s.secrets {
+		if r.URL.Path == "/v1/projects/test-project/secrets/"+n+"/versions/latest:access" {
+			name = n
+		}
+	}
+	if name == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	data := []byte(s.secrets[name])
+	sum := int64(crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+	if s.corrupt {
+		sum++
+	}
+	var ar accessResponse
+	ar.Payload.Data = base64.StdEncoding.EncodeToString(data)
+	ar.Payload.DataCrc32c = strconv.FormatInt(sum, 10)
+	_ = json.NewEncoder(w).Encode(ar)
+}
+
+func newTestSecretManager(t *testing.T, stub *secretManagerStub) *SecretManager {
+	t.Helper()
+
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+
+	sm := NewSecretManager("test-project", srv.Client())
+	sm.baseURL, sm.tokenURL = srv.URL, srv.URL+"/token"
+	return sm
+}
+
+func TestSecretManager(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		stub    *secretManagerStub
+		name    string
+		want    string
+		wantErr error
+		err     bool
+	}{
+		"found": {
+			stub: &secretManagerStub{secrets: map[string]string{"API_KEY": "key"}},
+			name: "API_KEY",
+			want: "key",
+		},
+		"not found": {
+			stub:    &secretManagerStub{secrets: map[string]string{"API_KEY": "key"}},
+			name:    "REALTIME_DSN",
+			wantErr: ErrNotFound,
+		},
+		"corrupted": {
+			stub: &secretManagerStub{secrets: map[string]string{"API_KEY": "key"}, corrupt: true},
+			name: "API_KEY",
+			err:  true,
+		},
+		"denied": {
+			stub: &secretManagerStub{secrets: map[string]string{"API_KEY": "key"}, status: http.StatusForbidden},
+			name: "API_KEY",
+			err:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newTestSecretManager(t, tt.stub).Secret(context.Background(), tt.name)
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.err:
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, ErrNotFound)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSecretManager_CachesToken(t *testing.T) {
+	t.Parallel()
+
+	stub := &secretManagerStub{secrets: map[string]string{"API_KEY": "key", "REALTIME_DSN": "dsn"}}
+	sm := newTestSecretManager(t, stub)
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	sm.now = func() time.Time { return now }
+
+	for _, n := range []string{"API_KEY", "REALTIME_DSN"} {
+		_, err := sm.Secret(context.Background(), n)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), stub.tokens.Load())
+
+	// a token about to expire is renewed
+	now = now.Add(time.Hour - 30*time.Second)
+	_, err := sm.Secret(context.Background(), "API_KEY")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), stub.tokens.Load())
+}
+
+func TestSecretManager_InChain(t *testing.T) {
+	t.Setenv("REALTIME_DSN", "from env")
+
+	stub := &secretManagerStub{secrets: map[string]string{"API_KEY": "from store"}}
+	c := Chain{newTestSecretManager(t, stub), Env{}}
+
+	v, err := c.Secret(context.Background(), "API_KEY")
+	assert.NoError(t, err)
+	assert.Equal(t, "from store", v)
+
+	v, err = c.Secret(context.Background(), "REALTIME_DSN")
+	assert.NoError(t, err)
+	assert.Equal(t, "from env", v)
+}
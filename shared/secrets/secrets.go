@@ -0,0 +1,113 @@
+// Package secrets reads API keys and DSNs from where the deployment keeps them:
+// the environment, a mounted secret volume or Secret Manager.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when a provider has no secret of the name.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider reads secrets by name, such as API_KEY.
+type Provider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// The providers that SECRETS_PROVIDER selects
+const (
+	ProviderEnv           = "env"
+	ProviderFile          = "file"
+	ProviderSecretManager = "secretmanager"
+)
+
+const defaultSecretsDir = "/secrets"
+
+// Env reads secrets from the environment variables of their names.
+type Env struct{}
+
+func (Env) Secret(_ context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return v, nil
+}
+
+// File reads secrets from the files of their names in Dir, which is how a secret volume is mounted.
+type File struct {
+	Dir string
+}
+
+func (f File) Secret(_ context.Context, name string) (string, error) {
+	if name == "" || name != filepath.Base(name) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	b, err := os.ReadFile(filepath.Join(f.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+
+	// editors and kubectl leave a trailing newline, which no key or DSN has
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// Chain reads a secret from the first provider that has it.
+type Chain []Provider
+
+func (c Chain) Secret(ctx context.Context, name string) (string, error) {
+	for _, p := range c {
+		v, err := p.Secret(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return v, err
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Lookup reads a secret that may be unset, which is reported as an empty value.
+func Lookup(ctx context.Context, p Provider, name string) (string, error) {
+	v, err := p.Secret(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	return v, err
+}
+
+// FromEnv returns the provider selected by SECRETS_PROVIDER, which is env by default.
+// The environment is read last by every provider, so that a local run needs no secret store.
+//
+//	SECRETS_PROVIDER=file           SECRETS_DIR (default /secrets)
+//	SECRETS_PROVIDER=secretmanager  SECRETS_PROJECT (default GOOGLE_CLOUD_PROJECT)
+func FromEnv() (Provider, error) {
+	switch p := os.Getenv("SECRETS_PROVIDER"); p {
+	case "", ProviderEnv:
+		return Env{}, nil
+	case ProviderFile:
+		dir := os.Getenv("SECRETS_DIR")
+		if dir == "" {
+			dir = defaultSecretsDir
+		}
+		return Chain{File{Dir: dir}, Env{}}, nil
+	case ProviderSecretManager:
+		project := os.Getenv("SECRETS_PROJECT")
+		if project == "" {
+			project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+		}
+		if project == "" {
+			return nil, errors.New("SECRETS_PROJECT is not set")
+		}
+		return Chain{NewSecretManager(project, nil), Env{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown SECRETS_PROVIDER %q", p)
+	}
+}
@@ -0,0 +1,134 @@
+package secrets
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type mapProvider map[string]string
+
+func (m mapProvider) Secret(_ context.Context, name string) (string, error) {
+	v, ok := m[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("SECRETS_TEST_KEY", "key")
+	t.Setenv("SECRETS_TEST_EMPTY", "")
+
+	v, err := Env{}.Secret(context.Background(), "SECRETS_TEST_KEY")
+	assert.NoError(t, err)
+	assert.Equal(t, "key", v)
+
+	_, err = Env{}.Secret(context.Background(), "SECRETS_TEST_EMPTY")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = Env{}.Secret(context.Background(), "SECRETS_TEST_UNSET")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "API_KEY"), []byte("key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "REALTIME_DSN"), []byte("postgres://db"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		"trailing newline": {name: "API_KEY", want: "key"},
+		"no newline":       {name: "REALTIME_DSN", want: "postgres://db"},
+		"missing":          {name: "SUPABASE_DSN", wantErr: ErrNotFound},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := File{Dir: dir}.Secret(context.Background(), tt.name)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFile_RejectsPaths(t *testing.T) {
+	t.Parallel()
+
+	_, err := File{Dir: t.TempDir()}.Secret(context.Background(), "../API_KEY")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	c := Chain{mapProvider{"API_KEY": "from store"}, mapProvider{"API_KEY": "from env", "REALTIME_DSN": "dsn"}}
+
+	v, err := c.Secret(context.Background(), "API_KEY")
+	assert.NoError(t, err)
+	assert.Equal(t, "from store", v)
+
+	v, err = c.Secret(context.Background(), "REALTIME_DSN")
+	assert.NoError(t, err)
+	assert.Equal(t, "dsn", v)
+
+	_, err = c.Secret(context.Background(), "SUPABASE_DSN")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	v, err = Lookup(context.Background(), c, "SUPABASE_DSN")
+	assert.NoError(t, err)
+	assert.Empty(t, v)
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := map[string]struct {
+		env     map[string]string
+		want    Provider
+		wantErr bool
+	}{
+		"default": {want: Env{}},
+		"env":     {env: map[string]string{"SECRETS_PROVIDER": "env"}, want: Env{}},
+		"file": {
+			env:  map[string]string{"SECRETS_PROVIDER": "file", "SECRETS_DIR": "/run/secrets"},
+			want: Chain{File{Dir: "/run/secrets"}, Env{}},
+		},
+		"file in default dir": {
+			env:  map[string]string{"SECRETS_PROVIDER": "file"},
+			want: Chain{File{Dir: "/secrets"}, Env{}},
+		},
+		"secret manager without project": {env: map[string]string{"SECRETS_PROVIDER": "secretmanager"}, wantErr: true},
+		"unknown":                        {env: map[string]string{"SECRETS_PROVIDER": "vault"}, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, k := range []string{"SECRETS_PROVIDER", "SECRETS_DIR", "SECRETS_PROJECT", "GOOGLE_CLOUD_PROJECT"} {
+				t.Setenv(k, tt.env[k])
+			}
+			got, err := FromEnv()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
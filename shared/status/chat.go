@@ -0,0 +1,12 @@
+package status
+
+// Chat is the availability of the live chat of a video, stored as is in the chat_status column.
+type Chat string
+
+const (
+	// ChatUnknown is a video that is not a broadcast, or whose chat has not been seen yet
+	ChatUnknown  Chat = ""
+	ChatEnabled  Chat = "enabled"
+	ChatDisabled Chat = "disabled"
+	ChatEnded    Chat = "ended"
+)
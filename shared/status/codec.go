@@ -0,0 +1,86 @@
+package status
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknown is returned when a value is not one of the defined statuses.
+var ErrUnknown = errors.New("unknown status")
+
+// wireNames is the canonical lower case form used in JSON and in the database.
+var wireNames = map[Status]string{
+	Upcoming:  "upcoming",
+	Live:      "live",
+	Archived:  "archived",
+	Missed:    "missed",
+	Cancelled: "cancelled",
+}
+
+// Parse returns the status named s in any case. Undefined is never returned without an error.
+func Parse(s string) (Status, error) {
+	for st, name := range wireNames {
+		if strings.EqualFold(s, name) {
+			return st, nil
+		}
+	}
+
+	return Undefined, fmt.Errorf("%w: %q", ErrUnknown, s)
+}
+
+func (s Status) MarshalText() ([]byte, error) {
+	name, ok := wireNames[s]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknown, s)
+	}
+	return []byte(name), nil
+}
+
+func (s *Status) UnmarshalText(b []byte) error {
+	st, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*s = st
+	return nil
+}
+
+func (s Status) MarshalJSON() ([]byte, error) {
+	b, err := s.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(b))
+}
+
+func (s *Status) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return fmt.Errorf("status must be a string: %w", err)
+	}
+	return s.UnmarshalText([]byte(name))
+}
+
+// Scan implements sql.Scanner.
+func (s *Status) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return s.UnmarshalText([]byte(v))
+	case []byte:
+		return s.UnmarshalText(v)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrUnknown, src)
+	}
+}
+
+// Value implements driver.Valuer.
+func (s Status) Value() (driver.Value, error) {
+	b, err := s.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,87 @@
+package status
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in      string
+		want    Status
+		wantErr bool
+	}{
+		"lower case":  {in: "upcoming", want: Upcoming},
+		"title case":  {in: "Live", want: Live},
+		"upper case":  {in: "ARCHIVED", want: Archived},
+		"missed":      {in: "missed", want: Missed},
+		"cancelled":   {in: "cancelled", want: Cancelled},
+		"undefined":   {in: "undefined", want: Undefined, wantErr: true},
+		"empty":       {in: "", want: Undefined, wantErr: true},
+		"unknown":     {in: "deleted", want: Undefined, wantErr: true},
+		"with spaces": {in: " live ", want: Undefined, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Parse(tt.in)
+			assert.Equal(t, tt.want, got)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnknown)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestStatus_JSON(t *testing.T) {
+	t.Parallel()
+
+	type payload struct {
+		Status Status `json:"status"`
+	}
+
+	b, err := json.Marshal(payload{Status: Upcoming})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"status":"upcoming"}`, string(b))
+
+	var got payload
+	assert.NoError(t, json.Unmarshal([]byte(`{"status":"Live"}`), &got))
+	assert.Equal(t, Live, got.Status)
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"status":"deleted"}`), &got), ErrUnknown)
+	assert.Error(t, json.Unmarshal([]byte(`{"status":1}`), &got))
+
+	_, err = json.Marshal(payload{Status: Undefined})
+	assert.Error(t, err)
+}
+
+func TestStatus_SQL(t *testing.T) {
+	t.Parallel()
+
+	for _, st := range []Status{Upcoming, Live, Archived, Missed, Cancelled} {
+		v, err := st.Value()
+		assert.NoError(t, err)
+
+		var got Status
+		assert.NoError(t, got.Scan(v))
+		assert.Equal(t, st, got)
+	}
+
+	var got Status
+	assert.NoError(t, got.Scan([]byte("Archived")))
+	assert.Equal(t, Archived, got)
+	assert.ErrorIs(t, got.Scan(nil), ErrUnknown)
+	assert.ErrorIs(t, got.Scan(int64(1)), ErrUnknown)
+
+	_, err := Undefined.Value()
+	assert.ErrorIs(t, err, ErrUnknown)
+	_, err = Status(99).Value()
+	assert.ErrorIs(t, err, ErrUnknown)
+}
@@ -0,0 +1,16 @@
+package status
+
+//go:generate stringer -type Status
+
+type Status int
+
+const (
+	Undefined Status = iota
+	Upcoming
+	Live
+	Archived
+	// Missed is an upcoming broadcast that was never started long after its schedule
+	Missed
+	// Cancelled is an upcoming broadcast that was deleted or made private before it started
+	Cancelled
+)
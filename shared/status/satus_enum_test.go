@@ -26,6 +26,16 @@ func Test_StatusString_Archived_ReturnsArchived(t *testing.T) {
 	assert.Equal(t, "Archived", Archived.String())
 }
 
+func Test_StatusString_Missed_ReturnsMissed(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "Missed", Missed.String())
+}
+
+func Test_StatusString_Cancelled_ReturnsCancelled(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "Cancelled", Cancelled.String())
+}
+
 func Test_StatusString_InvalidStatus_ReturnsStatusWithNumber(t *testing.T) {
 	t.Parallel()
 	invalidStatus := Status(99)
@@ -12,11 +12,13 @@ func _() {
 	_ = x[Upcoming-1]
 	_ = x[Live-2]
 	_ = x[Archived-3]
+	_ = x[Missed-4]
+	_ = x[Cancelled-5]
 }
 
-const _Status_name = "UndefinedUpcomingLiveArchived"
+const _Status_name = "UndefinedUpcomingLiveArchivedMissedCancelled"
 
-var _Status_index = [...]uint8{0, 9, 17, 21, 29}
+var _Status_index = [...]uint8{0, 9, 17, 21, 29, 35, 44}
 
 func (i Status) String() string {
 	if i < 0 || i >= Status(len(_Status_index)-1) {
@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/api/googleapi"
+	"strconv"
+	"time"
+)
+
+// APIMetrics records the latency and the errors of calls to external APIs
+// such as the YouTube Data API and the Natural Language API.
+type APIMetrics struct {
+	latency metric.Float64Histogram
+	errors  metric.Int64Counter
+}
+
+func NewAPIMetrics(meter metric.Meter) (*APIMetrics, error) {
+	latency, err := meter.Float64Histogram(
+		"api.call.duration",
+		metric.WithDescription("Latency of external API calls"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	errs, err := meter.Int64Counter(
+		"api.call.errors",
+		metric.WithDescription("Failed external API calls by reason"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &APIMetrics{latency: latency, errors: errs}, nil
+}
+
+// Record records the latency of the call started at start and counts err by its reason if it is not nil.
+func (m *APIMetrics) Record(ctx context.Context, method string, start time.Time, err error) {
+	attrs := attribute.NewSet(attribute.String("api.method", method))
+	m.latency.Record(ctx, time.Since(start).Seconds(), metric.WithAttributeSet(attrs))
+
+	if err != nil {
+		m.errors.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("api.method", method),
+			attribute.String("error.reason", ErrorReason(err)),
+		))
+	}
+}
+
+// ErrorReason returns a low-cardinality reason of err suitable for a metric attribute.
+// For Google API errors it is the reason of the first error item (e.g. quotaExceeded, liveChatEnded).
+func ErrorReason(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		if len(gErr.Errors) > 0 && gErr.Errors[0].Reason != "" {
+			return gErr.Errors[0].Reason
+		}
+		return "http_" + strconv.Itoa(gErr.Code)
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,21 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"net/http"
+)
+
+// WrapHTTP starts a server span for each request and flushes the telemetry before returning,
+// because the CPU of a Cloud Functions instance is throttled once the response is written.
+func WrapHTTP(name string, fn http.HandlerFunc) http.HandlerFunc {
+	h := otelhttp.NewHandler(fn, name)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+
+		if err := Flush(r.Context()); err != nil {
+			otel.Handle(err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+)
+
+// Keys of the special fields recognized by Cloud Logging
+const (
+	TraceKey        = "logging.googleapis.com/trace"
+	SpanIDKey       = "logging.googleapis.com/spanId"
+	TraceSampledKey = "logging.googleapis.com/trace_sampled"
+)
+
+// TraceAttrs returns the Cloud Logging trace fields of the active span in ctx.
+// It returns nil if there is no valid span.
+func TraceAttrs(ctx context.Context, projectID string) []slog.Attr {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	traceID := sc.TraceID().String()
+	if projectID != "" {
+		traceID = fmt.Sprintf("projects/%s/traces/%s", projectID, traceID)
+	}
+
+	return []slog.Attr{
+		slog.String(TraceKey, traceID),
+		slog.String(SpanIDKey, sc.SpanID().String()),
+		slog.Bool(TraceSampledKey, sc.IsSampled()),
+	}
+}
@@ -0,0 +1,131 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"os"
+	"strings"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+var (
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+)
+
+type Config struct {
+	// Exporter is one of ExporterNone, ExporterStdout and ExporterOTLP
+	Exporter    string
+	ServiceName string
+}
+
+// ConfigFromEnv reads OTEL_EXPORTER and SERVICE_NAME.
+// The OTLP endpoint is read by the exporter itself from the standard OTEL_EXPORTER_OTLP_* variables.
+func ConfigFromEnv() Config {
+	exp := strings.ToLower(os.Getenv("OTEL_EXPORTER"))
+	if exp == "" {
+		exp = ExporterNone
+	}
+
+	return Config{
+		Exporter:    exp,
+		ServiceName: os.Getenv("SERVICE_NAME"),
+	}
+}
+
+// Setup installs the global tracer and meter providers.
+// The returned function flushes and stops the exporters.
+// With ExporterNone the global no-op providers are left untouched.
+func Setup(ctx context.Context, c Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if c.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	spanExp, metricExp, err := newExporters(ctx, c.Exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(c.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExp),
+		sdktrace.WithResource(res),
+	)
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExp)),
+		sdkmetric.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+	tracerProvider, meterProvider = tp, mp
+
+	return func(ctx context.Context) error {
+		return errors.Join(tp.Shutdown(ctx), mp.Shutdown(ctx))
+	}, nil
+}
+
+// Flush exports the buffered spans and metrics.
+// It does nothing if Setup has not installed any exporter.
+func Flush(ctx context.Context) error {
+	if tracerProvider == nil || meterProvider == nil {
+		return nil
+	}
+
+	return errors.Join(tracerProvider.ForceFlush(ctx), meterProvider.ForceFlush(ctx))
+}
+
+func newExporters(ctx context.Context, exporter string) (sdktrace.SpanExporter, sdkmetric.Exporter, error) {
+	switch exporter {
+	case ExporterStdout:
+		se, err := stdouttrace.New()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+		me, err := stdoutmetric.New()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout metric exporter: %w", err)
+		}
+		return se, me, nil
+	case ExporterOTLP:
+		se, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		me, err := otlpmetrichttp.New(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+		}
+		return se, me, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown exporter: %q", exporter)
+	}
+}
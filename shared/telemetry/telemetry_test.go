@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/googleapi"
+	"log/slog"
+	"testing"
+)
+
+func TestErrorReason(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"nil": {
+			err:  nil,
+			want: "",
+		},
+		"google api error with reason": {
+			err: fmt.Errorf("wrapped: %w", &googleapi.Error{
+				Code:   403,
+				Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}},
+			}),
+			want: "quotaExceeded",
+		},
+		"google api error without reason": {
+			err:  &googleapi.Error{Code: 503},
+			want: "http_503",
+		},
+		"deadline exceeded": {
+			err:  context.DeadlineExceeded,
+			want: "timeout",
+		},
+		"other": {
+			err:  errors.New("boom"),
+			want: "unknown",
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, ErrorReason(tt.err))
+		})
+	}
+}
+
+func TestTraceAttrs(t *testing.T) {
+	t.Parallel()
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	tests := map[string]struct {
+		ctx       context.Context
+		projectID string
+		want      []slog.Attr
+	}{
+		"without span": {
+			ctx:  context.Background(),
+			want: nil,
+		},
+		"with project": {
+			ctx:       ctx,
+			projectID: "project",
+			want: []slog.Attr{
+				slog.String(TraceKey, "projects/project/traces/0102030405060708090a0b0c0d0e0f10"),
+				slog.String(SpanIDKey, "0102030405060708"),
+				slog.Bool(TraceSampledKey, true),
+			},
+		},
+		"without project": {
+			ctx: ctx,
+			want: []slog.Attr{
+				slog.String(TraceKey, "0102030405060708090a0b0c0d0e0f10"),
+				slog.String(SpanIDKey, "0102030405060708"),
+				slog.Bool(TraceSampledKey, true),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, TraceAttrs(tt.ctx, tt.projectID))
+		})
+	}
+}
+
+func TestSetup(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		exporter string
+		wantErr  bool
+	}{
+		"none": {
+			exporter: ExporterNone,
+		},
+		"unknown": {
+			exporter: "zipkin",
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			shutdown, err := Setup(context.Background(), Config{Exporter: tt.exporter, ServiceName: "test"})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}